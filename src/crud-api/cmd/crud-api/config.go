@@ -0,0 +1,122 @@
+package main
+import (
+    "os"
+    "fmt"
+    "time"
+    "strconv"
+    "encoding/hex"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+
+// Server settings read from environment, DB settings are handled by shareddb
+type Config struct {
+    Addr            string
+    ReadTimeout     time.Duration
+    WriteTimeout    time.Duration
+    IdleTimeout     time.Duration
+    ShutdownTimeout time.Duration
+    DBTimeout       time.Duration
+    CursorKey       string  // hex, signs list cursors, empty = random per process
+    UserRetention   time.Duration   // soft deleted users are purged after this long
+    PurgeInterval   time.Duration   // how often purge job runs
+    MaxBodyBytes        int64   // request body cap, bigger body is 413
+    MaxBatchBodyBytes   int64   // same for /batch/* routes
+    StrictJSON      bool    // unknown keys in body are 422 instead of ignored
+}
+
+
+func defaultConfigFn() Config {
+    return Config{
+        Addr:               ":8080",
+        ReadTimeout:        10 * time.Second,
+        WriteTimeout:       10 * time.Second,
+        IdleTimeout:        60 * time.Second,
+        ShutdownTimeout:    15 * time.Second,
+        DBTimeout:          5 * time.Second,
+        UserRetention:      30 * 24 * time.Hour,
+        PurgeInterval:      time.Hour,
+        MaxBodyBytes:       256 << 10,  // entry ciphertext alone can be 64 KiB of hex
+        MaxBatchBodyBytes:  1 << 20,
+        StrictJSON:         true,
+    }
+}
+
+
+func loadConfigFromEnvFn() (Config, error) {
+    const fn = "loadConfigFromEnvFn"
+    cfg := defaultConfigFn()
+
+    // Address, optional
+    if val := os.Getenv("CRUD_API_ADDR"); val != "" {
+        cfg.Addr = val
+    }
+
+    // Durations, optional, must be parsable by time.ParseDuration and positive
+    durations := []struct {
+        key     string
+        target  *time.Duration
+    }{
+        {"CRUD_API_READ_TIMEOUT",       &cfg.ReadTimeout},
+        {"CRUD_API_WRITE_TIMEOUT",      &cfg.WriteTimeout},
+        {"CRUD_API_IDLE_TIMEOUT",       &cfg.IdleTimeout},
+        {"CRUD_API_SHUTDOWN_TIMEOUT",   &cfg.ShutdownTimeout},
+        {"CRUD_API_DB_TIMEOUT",         &cfg.DBTimeout},
+        {"CRUD_API_USER_RETENTION",     &cfg.UserRetention},
+        {"CRUD_API_PURGE_INTERVAL",     &cfg.PurgeInterval},
+    }
+    for _, d := range durations {
+        val := os.Getenv(d.key)
+        if val == "" {
+            continue // Keep default
+        }
+        parsed, err := time.ParseDuration(val)
+        if err != nil || parsed <= 0 {
+            return Config{}, fmt.Errorf("%s: invalid duration for %s: %q", fn, d.key, val)
+        }
+        *d.target = parsed
+    }
+
+    // Body caps, optional, positive number of bytes
+    sizes := []struct {
+        key     string
+        target  *int64
+    }{
+        {"CRUD_API_MAX_BODY_BYTES",         &cfg.MaxBodyBytes},
+        {"CRUD_API_MAX_BATCH_BODY_BYTES",   &cfg.MaxBatchBodyBytes},
+    }
+    for _, s := range sizes {
+        val := os.Getenv(s.key)
+        if val == "" {
+            continue // Keep default
+        }
+        parsed, err := strconv.ParseInt(val, 10, 64)
+        if err != nil || parsed <= 0 {
+            return Config{}, fmt.Errorf("%s: invalid size for %s: %q", fn, s.key, val)
+        }
+        *s.target = parsed
+    }
+
+    // Strict JSON, optional, anything strconv.ParseBool accepts
+    if val := os.Getenv("CRUD_API_STRICT_JSON"); val != "" {
+        parsed, err := strconv.ParseBool(val)
+        if err != nil {
+            return Config{}, fmt.Errorf("%s: invalid bool for CRUD_API_STRICT_JSON: %q", fn, val)
+        }
+        cfg.StrictJSON = parsed
+    }
+
+    // Cursor key, optional, hex of at least sapi.CursorKeyLen bytes so every
+    //  replica accepts cursors issued by others
+    if val := os.Getenv("CRUD_API_CURSOR_KEY"); val != "" {
+        key, err := hex.DecodeString(val)
+        if err != nil || len(key) < sapi.CursorKeyLen {
+            return Config{}, fmt.Errorf("%s: CRUD_API_CURSOR_KEY must be hex of at least %d bytes", fn, sapi.CursorKeyLen)
+        }
+        cfg.CursorKey = val
+    }
+
+    return cfg, nil
+}
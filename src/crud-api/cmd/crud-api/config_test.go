@@ -0,0 +1,132 @@
+package main
+import (
+    "os"
+    "testing"
+    "strings"
+    "time"
+)
+
+
+//{{{ loadConfigFromEnvFn
+func Test_LoadConfigFromEnvFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        env                 map[string]string
+        expectedConfig      Config
+        expectedErrSubStr   string
+    }{
+        {
+            name:               "Defaults",
+            env:                map[string]string{},
+            expectedConfig:     defaultConfigFn(),
+            expectedErrSubStr:  "",
+        }, {
+            name:               "Override",
+            env:                map[string]string{
+                "CRUD_API_ADDR":                "127.0.0.1:9000",
+                "CRUD_API_SHUTDOWN_TIMEOUT":    "3s",
+                "CRUD_API_USER_RETENTION":      "168h",
+                "CRUD_API_MAX_BODY_BYTES":      "4096",
+                "CRUD_API_STRICT_JSON":         "false",
+            },
+            expectedConfig:     Config{
+                Addr:               "127.0.0.1:9000",
+                ReadTimeout:        10 * time.Second,
+                WriteTimeout:       10 * time.Second,
+                IdleTimeout:        60 * time.Second,
+                ShutdownTimeout:    3 * time.Second,
+                DBTimeout:          5 * time.Second,
+                UserRetention:      168 * time.Hour,
+                PurgeInterval:      time.Hour,
+                MaxBodyBytes:       4096,
+                MaxBatchBodyBytes:  1 << 20,
+                StrictJSON:         false,
+            },
+            expectedErrSubStr:  "",
+        }, {
+            name:               "InvalidDuration",
+            env:                map[string]string{
+                "CRUD_API_READ_TIMEOUT": "ten seconds",
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "invalid duration for CRUD_API_READ_TIMEOUT",
+        }, {
+            name:               "CursorKey",
+            env:                map[string]string{
+                "CRUD_API_CURSOR_KEY": strings.Repeat("ab", 32),
+            },
+            expectedConfig:     Config{
+                Addr:               ":8080",
+                ReadTimeout:        10 * time.Second,
+                WriteTimeout:       10 * time.Second,
+                IdleTimeout:        60 * time.Second,
+                ShutdownTimeout:    15 * time.Second,
+                DBTimeout:          5 * time.Second,
+                UserRetention:      30 * 24 * time.Hour,
+                PurgeInterval:      time.Hour,
+                MaxBodyBytes:       256 << 10,
+                MaxBatchBodyBytes:  1 << 20,
+                StrictJSON:         true,
+                CursorKey:          strings.Repeat("ab", 32),
+            },
+            expectedErrSubStr:  "",
+        }, {
+            name:               "ShortCursorKey",
+            env:                map[string]string{
+                "CRUD_API_CURSOR_KEY": "abcd",
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "CRUD_API_CURSOR_KEY must be hex of at least 32 bytes",
+        }, {
+            name:               "NotHexCursorKey",
+            env:                map[string]string{
+                "CRUD_API_CURSOR_KEY": strings.Repeat("zz", 32),
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "CRUD_API_CURSOR_KEY must be hex",
+        }, {
+            name:               "NegativeDuration",
+            env:                map[string]string{
+                "CRUD_API_IDLE_TIMEOUT": "-1s",
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "invalid duration for CRUD_API_IDLE_TIMEOUT",
+        }, {
+            name:               "InvalidSize",
+            env:                map[string]string{
+                "CRUD_API_MAX_BATCH_BODY_BYTES": "1MiB",
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "invalid size for CRUD_API_MAX_BATCH_BODY_BYTES",
+        }, {
+            name:               "InvalidBool",
+            env:                map[string]string{
+                "CRUD_API_STRICT_JSON": "sometimes",
+            },
+            expectedConfig:     Config{},
+            expectedErrSubStr:  "invalid bool for CRUD_API_STRICT_JSON",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            os.Clearenv()
+            for k, v := range tc.env {
+                os.Setenv(k, v)
+            }
+            cfg, err := loadConfigFromEnvFn()
+            // Check error
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrSubStr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErrSubStr, err)
+            }
+            // Check config
+            if cfg != tc.expectedConfig {
+                t.Errorf("Wrong config:\nExpected:\t%+v\nGot:\t\t%+v", tc.expectedConfig, cfg)
+            }
+        })
+    }
+}
+//}}} loadConfigFromEnvFn
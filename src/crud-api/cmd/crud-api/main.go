@@ -0,0 +1,90 @@
+package main
+import (
+    "os"
+    "log"
+    "errors"
+    "context"
+    "net/http"
+    "os/signal"
+    "syscall"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+func main() {
+    if err := run(); err != nil {
+        log.Printf("crud-api: %v", err)
+        os.Exit(1)
+    }
+}
+
+
+func run() error {
+    // Load config
+    cfg, err := loadConfigFromEnvFn()
+    if err != nil {
+        return err
+    }
+
+    // SIGINT/SIGTERM aborts DB startup wait as well as serving
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    // Open DB pool, closed last so in-flight requests can finish
+    db, err := sdb.GetConnContext(ctx)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    srv := &http.Server{
+        Addr:           cfg.Addr,
+        Handler:        newRouterFn(db, cfg),
+        ReadTimeout:    cfg.ReadTimeout,
+        WriteTimeout:   cfg.WriteTimeout,
+        IdleTimeout:    cfg.IdleTimeout,
+    }
+
+    // Purge soft deleted users past retention, stops with ctx or when run returns.
+    //  Deferred after db.Close so job is stopped and waited on before DB closes
+    purgeCtx, stopPurge := context.WithCancel(ctx)
+    purgeDone := make(chan struct{})
+    go func() {
+        runUserPurgeFn(purgeCtx, cruduser.NewPgUserStore(db), cfg.UserRetention, cfg.PurgeInterval, cfg.DBTimeout)
+        close(purgeDone)
+    }()
+    defer func() {
+        stopPurge()
+        <-purgeDone
+    }()
+
+    // Serve in background, ErrServerClosed is expected after Shutdown
+    serveErr := make(chan error, 1)
+    go func() {
+        log.Printf("crud-api: listening on %s", cfg.Addr)
+        if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            serveErr <- err
+        }
+        close(serveErr)
+    }()
+
+    // Wait for SIGINT/SIGTERM or server failure
+    select {
+    case err := <-serveErr:
+        return err
+    case <-ctx.Done():
+        log.Printf("crud-api: shutdown signal received, draining requests")
+    }
+
+    // Drain in-flight requests
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+    defer cancel()
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        return err
+    }
+    log.Printf("crud-api: shutdown complete")
+    return nil
+}
@@ -0,0 +1,35 @@
+package main
+import (
+    "log"
+    "time"
+    "context"
+)
+import (
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// Hard deletes users soft deleted more than retention ago, every interval until ctx is done.
+//  First run is right away so restarts don't postpone purge. Runs on every replica,
+//  purge is idempotent so overlapping runs are harmless
+func runUserPurgeFn(ctx context.Context, store cruduser.UserStore, retention time.Duration, interval time.Duration, opTimeout time.Duration) {
+    const wrap = "UserPurgeJob"
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        opCtx, cancel := context.WithTimeout(ctx, opTimeout)
+        before := time.Now().Add(-retention)
+        purged, err := store.PurgeDeleted(opCtx, before)
+        cancel()
+        if err != nil && ctx.Err() == nil {
+            log.Printf("%s: %v", wrap, err)
+        } else if purged > 0 {
+            log.Printf("%s: purged %d users deleted before %s", wrap, purged, before.UTC().Format(time.RFC3339))
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,49 @@
+package main
+import (
+    "testing"
+    "context"
+    "time"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+func Test_RunUserPurgeFn(t *testing.T) {
+    store := cruduser.NewMemUserStore()
+    user := smodels.User{
+        Username:   "test_purge_user1",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    if err := store.Insert(context.Background(), user); err != nil {
+        t.Fatalf("Failed to create user that will be purged: %v", err)
+    }
+    if err := store.Delete(context.Background(), user.Username); err != nil {
+        t.Fatalf("Failed to delete user that will be purged: %v", err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        runUserPurgeFn(ctx, store, time.Nanosecond, 10 * time.Millisecond, time.Second)
+        close(done)
+    }()
+    // Soft deleted username stays taken until job purges it
+    deadline := time.Now().Add(2 * time.Second)
+    for store.Insert(context.Background(), user) != nil {
+        if time.Now().After(deadline) {
+            t.Fatalf("Deleted user was not purged in time")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+    // Stops with context
+    cancel()
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Errorf("Purge job didn't stop after context was cancelled")
+    }
+}
@@ -0,0 +1,99 @@
+package main
+import (
+    "net/http"
+    "database/sql"
+    "encoding/hex"
+)
+import (
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudevent "github.com/FAH2S/diar4/src/crud-api/event"
+    crudreview "github.com/FAH2S/diar4/src/crud-api/review"
+    crudentry "github.com/FAH2S/diar4/src/crud-api/entry"
+    crudmiddleware "github.com/FAH2S/diar4/src/crud-api/middleware"
+)
+
+
+func newRouterFn(db *sql.DB, cfg Config) http.Handler {
+    userHandler := cruduser.NewUserHandler(cruduser.NewPgUserStore(db))
+    userHandler.OpTimeout = cfg.DBTimeout
+    userHandler.Body.DisallowUnknown = cfg.StrictJSON
+    if cfg.CursorKey != "" {
+        // Validated by loadConfigFromEnvFn
+        userHandler.CursorKey, _ = hex.DecodeString(cfg.CursorKey)
+    }
+    eventHandler := crudevent.NewEventHandler(crudevent.NewPgEventStore(db))
+    eventHandler.OpTimeout = cfg.DBTimeout
+    eventHandler.Body.DisallowUnknown = cfg.StrictJSON
+    reviewHandler := crudreview.NewReviewHandler(crudreview.NewPgReviewStore(db))
+    reviewHandler.OpTimeout = cfg.DBTimeout
+    reviewHandler.Body.DisallowUnknown = cfg.StrictJSON
+    entryHandler := crudentry.NewEntryHandler(crudentry.NewPgEntryStore(db))
+    entryHandler.OpTimeout = cfg.DBTimeout
+    entryHandler.Body.DisallowUnknown = cfg.StrictJSON
+    routes := map[string]http.HandlerFunc{
+        "/create/user": userHandler.CreateUserEndpoint,
+        "/read/user":   userHandler.ReadUserEndpoint,
+        "/update/user": userHandler.UpdateUserEndpoint,
+        "/delete/user": userHandler.DeleteUserEndpoint,
+        "/restore/user":    userHandler.RestoreUserEndpoint,
+        "/purge/user":      userHandler.PurgeUserEndpoint,
+        "/verify/user": userHandler.VerifyUserEndpoint,
+        "/rename/user": userHandler.RenameUserEndpoint,
+        "/rotate/user": userHandler.RotateUserCredentialsEndpoint,
+        "/list/users":  userHandler.ListUsersEndpoint,
+        "/batch/create/user":   userHandler.BatchCreateUserEndpoint,
+        "/batch/update/user":   userHandler.BatchUpdateUserEndpoint,
+        "/batch/delete/user":   userHandler.BatchDeleteUserEndpoint,
+        "/create/event": eventHandler.CreateEventEndpoint,
+        "/read/event":   eventHandler.ReadEventEndpoint,
+        "/update/event": eventHandler.UpdateEventEndpoint,
+        "/delete/event": eventHandler.DeleteEventEndpoint,
+        "/create/review": reviewHandler.CreateReviewEndpoint,
+        "/read/review":   reviewHandler.ReadReviewEndpoint,
+        "/update/review": reviewHandler.UpdateReviewEndpoint,
+        "/delete/review": reviewHandler.DeleteReviewEndpoint,
+        "/create/entry": entryHandler.CreateEntryEndpoint,
+        "/read/entry":   entryHandler.ReadEntryEndpoint,
+        "/list/entry":   entryHandler.ListEntryEndpoint,
+        "/update/entry": entryHandler.UpdateEntryEndpoint,
+        "/delete/entry": entryHandler.DeleteEntryEndpoint,
+    }
+
+    // REST resources (Go 1.22 patterns), method picks endpoint, rest is 405
+    resources := map[string]map[string]http.HandlerFunc{
+        "/users": {
+            http.MethodPost:   userHandler.CreateUserEndpoint,
+        },
+        "/users/{username}": {
+            http.MethodGet:    userHandler.GetUserResourceEndpoint,
+            http.MethodPatch:  userHandler.PatchUserResourceEndpoint,
+            http.MethodDelete: userHandler.DeleteUserResourceEndpoint,
+        },
+    }
+
+    // Body cap per route, routes not listed get cfg.MaxBodyBytes
+    bodyLimits := map[string]int64{
+        "/batch/create/user":   cfg.MaxBatchBodyBytes,
+        "/batch/update/user":   cfg.MaxBatchBodyBytes,
+        "/batch/delete/user":   cfg.MaxBatchBodyBytes,
+    }
+
+    mux := http.NewServeMux()
+    for path, endpoint := range routes {
+        limit, ok := bodyLimits[path]
+        if !ok {
+            limit = cfg.MaxBodyBytes
+        }
+        mux.Handle(path, crudmiddleware.ValidateMethodAndTypeEndpoint(crudmiddleware.MaxBodyEndpoint(limit, endpoint)))
+    }
+    for pattern, handlers := range resources {
+        for method, endpoint := range handlers {
+            handlers[method] = crudmiddleware.MaxBodyEndpoint(cfg.MaxBodyBytes, endpoint).ServeHTTP
+        }
+        mux.Handle(pattern, crudmiddleware.MethodsEndpoint(handlers))
+    }
+    // Operational, plain GET without JSON body
+    mux.Handle("/stats/db", dbStatsEndpointFn(db))
+    // Outermost, so errors of every route/middleware can be problem+json
+    return crudmiddleware.ProblemEndpoint(mux)
+}
@@ -0,0 +1,136 @@
+package main
+import (
+    "testing"
+    "strings"
+    "net/http/httptest"
+    "database/sql"
+    "encoding/json"
+)
+
+
+// Only method routing, requests that pass it would reach db
+func Test_NewRouterFn_Methods(t *testing.T) {
+    // sql.Open doesn't connect
+    db, err := sql.Open("postgres", "postgres://u:p@localhost:1/db?sslmode=disable")
+    if err != nil {
+        t.Fatalf("Failed to open pool: %v", err)
+    }
+    defer db.Close()
+    router := newRouterFn(db, defaultConfigFn())
+
+    tests := []struct {
+        Name                string
+        Method              string
+        Path                string
+        ContentType         string
+        ExpectedStatusCode  int
+        ExpectedAllow       string
+    }{
+        {"VerbRouteGET",        "GET",      "/read/user",       "",                 405,    "POST"},
+        {"VerbRouteNoCT",       "POST",     "/read/user",       "",                 400,    ""},
+        {"UsersGET",            "GET",      "/users",           "",                 405,    "POST"},
+        {"UsersNoCT",           "POST",     "/users",           "text/plain",       400,    ""},
+        {"UserPUT",             "PUT",      "/users/test_user", "application/json", 405,    "DELETE, GET, PATCH"},
+        {"UserPOST",            "POST",     "/users/test_user", "application/json", 405,    "DELETE, GET, PATCH"},
+        {"UserPatchNoCT",       "PATCH",    "/users/test_user", "",                 400,    ""},
+        {"UserNested",          "GET",      "/users/a/b",       "",                 404,    ""},
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader("{}"))
+            if tc.ContentType != "" {
+                req.Header.Set("Content-Type", tc.ContentType)
+            }
+            resp := httptest.NewRecorder()
+            router.ServeHTTP(resp, req)
+            allow := resp.Header().Get("Allow")
+            if resp.Code != tc.ExpectedStatusCode || allow != tc.ExpectedAllow {
+                t.Errorf("Unexpeted response:\nGot:\t%d (Allow: %q)\nWant:\t%d (Allow: %q)", resp.Code, allow, tc.ExpectedStatusCode, tc.ExpectedAllow)
+            }
+        })
+    }
+}
+
+
+// Per route body caps, batch routes get bigger one
+func Test_NewRouterFn_BodyLimit(t *testing.T) {
+    db, err := sql.Open("postgres", "postgres://u:p@localhost:1/db?sslmode=disable")
+    if err != nil {
+        t.Fatalf("Failed to open pool: %v", err)
+    }
+    defer db.Close()
+    cfg := defaultConfigFn()
+    cfg.MaxBodyBytes = 32
+    router := newRouterFn(db, cfg)
+    // Invalid mode is rejected before any DB call
+    body := `{"mode":"best_effort","users":[{"username":"test_user"}]}`
+
+    tests := []struct {
+        Name                string
+        Method              string
+        Path                string
+        ExpectedStatusCode  int
+    }{
+        {"VerbRoute",       "POST",     "/delete/user",         413},
+        {"ResourceRoute",   "PATCH",    "/users/test_user",     413},
+        {"BatchRoute",      "POST",     "/batch/delete/user",   422},
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(body))
+            req.Header.Set("Content-Type", "application/json")
+            resp := httptest.NewRecorder()
+            router.ServeHTTP(resp, req)
+            if resp.Code != tc.ExpectedStatusCode {
+                t.Errorf("Unexpeted status code:\nGot:\t%d\nWant:\t%d", resp.Code, tc.ExpectedStatusCode)
+            }
+        })
+    }
+}
+
+
+// Middleware errors follow Accept too, router is wrapped by ProblemEndpoint
+func Test_NewRouterFn_Problem(t *testing.T) {
+    db, err := sql.Open("postgres", "postgres://u:p@localhost:1/db?sslmode=disable")
+    if err != nil {
+        t.Fatalf("Failed to open pool: %v", err)
+    }
+    defer db.Close()
+    router := newRouterFn(db, defaultConfigFn())
+
+    tests := []struct {
+        Name                string
+        Accept              string
+        ExpectedCT          string
+        ExpectedCode        string
+    }{
+        {"Envelope",    "",                         "application/json",         "request.method_not_allowed"},
+        {"Problem",     "application/problem+json", "application/problem+json", "request.method_not_allowed"},
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest("PUT", "/users/test_user", strings.NewReader("{}"))
+            req.Header.Set("Accept", tc.Accept)
+            resp := httptest.NewRecorder()
+            router.ServeHTTP(resp, req)
+            var body struct {
+                Code        string  `json:"code"`
+                Type        string  `json:"type"`
+                Instance    string  `json:"instance"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatalf("Failed to decode response body: %v", err)
+            }
+            ct := resp.Header().Get("Content-Type")
+            if resp.Code != 405 || ct != tc.ExpectedCT || body.Code != tc.ExpectedCode {
+                t.Errorf("Unexpeted response:\nGot:\t%d %s %q\nWant:\t%d %s %q", resp.Code, ct, body.Code, 405, tc.ExpectedCT, tc.ExpectedCode)
+            }
+            if tc.ExpectedCT == "application/problem+json" && (body.Type != "urn:diar4:problem:request.method_not_allowed" || body.Instance != "/users/test_user") {
+                t.Errorf("Unexpeted problem:\nGot:\t%+v", body)
+            }
+            if vary := resp.Header().Get("Vary"); vary != "Accept" {
+                t.Errorf("Unexpeted Vary:\nGot:\t%q\nWant:\t%q", vary, "Accept")
+            }
+        })
+    }
+}
@@ -0,0 +1,21 @@
+package main
+import (
+    "net/http"
+    "database/sql"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    crudmiddleware "github.com/FAH2S/diar4/src/crud-api/middleware"
+)
+
+
+// Pool statistics, growing wait_count/wait_duration_ms means pool is saturated.
+//  GET only, other methods get 405
+func dbStatsEndpointFn(db *sql.DB) http.Handler {
+    return crudmiddleware.MethodsEndpoint(map[string]http.HandlerFunc{
+        http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+            sapi.WriteJSONResponseFn(w, 200, "Success: read db stats", "", sdb.PoolStatsFn(db))
+        },
+    })
+}
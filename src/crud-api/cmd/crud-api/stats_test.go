@@ -0,0 +1,41 @@
+package main
+import (
+    "testing"
+    "encoding/json"
+    "net/http/httptest"
+    "database/sql"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+
+func Test_DBStatsEndpointFn(t *testing.T) {
+    // sql.Open doesn't connect, stats of unused pool are available
+    db, err := sql.Open("postgres", "postgres://u:p@localhost:1/db?sslmode=disable")
+    if err != nil {
+        t.Fatalf("Failed to open pool: %v", err)
+    }
+    defer db.Close()
+    db.SetMaxOpenConns(7)
+    handler := dbStatsEndpointFn(db)
+
+    // GET returns stats
+    resp := httptest.NewRecorder()
+    handler.ServeHTTP(resp, httptest.NewRequest("GET", "/stats/db", nil))
+    var body sapi.APIResponse
+    if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+        t.Fatalf("Failed to parse JSON response as APIResponse: %v", err)
+    }
+    data, ok := body.Data.(map[string]interface{})
+    if resp.Code != 200 || !ok || data["max_open_connections"] != float64(7) {
+        t.Errorf("Unexpected response: %d %+v", resp.Code, body)
+    }
+
+    // Other methods rejected
+    resp = httptest.NewRecorder()
+    handler.ServeHTTP(resp, httptest.NewRequest("POST", "/stats/db", nil))
+    if resp.Code != 405 || resp.Header().Get("Allow") != "GET" {
+        t.Errorf("Unexpeted status code:\nGot:\t%d (Allow: %q)\nWant:\t%d (Allow: \"GET\")", resp.Code, resp.Header().Get("Allow"), 405)
+    }
+}
@@ -0,0 +1,112 @@
+package main
+import (
+    "os"
+    "fmt"
+    "log"
+    "context"
+    "strconv"
+    "os/signal"
+    "syscall"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    crudmigrations "github.com/FAH2S/diar4/src/crud-api/migrations"
+)
+
+
+const usage = `usage: migrate <command>
+
+commands:
+    up              apply all pending migrations
+    down [steps]    revert last applied migration(s), default 1
+    status          list migrations and whether they are applied
+`
+
+
+func main() {
+    if len(os.Args) < 2 {
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(2)
+    }
+    if err := run(os.Args[1], os.Args[2:]); err != nil {
+        log.Printf("migrate: %v", err)
+        os.Exit(1)
+    }
+}
+
+
+// Validates command and its arguments, returns steps of down (0 for others).
+//  Done before connecting so typo doesn't need reachable DB
+func parseCommandFn(command string, args []string) (int, error) {
+    switch command {
+    case "up", "status":
+        return 0, nil
+    case "down":
+        if len(args) == 0 {
+            return 1, nil
+        }
+        steps, err := strconv.Atoi(args[0])
+        if err != nil || steps < 1 {
+            return 0, fmt.Errorf("invalid steps: %q, must be number at least 1", args[0])
+        }
+        return steps, nil
+    }
+    return 0, fmt.Errorf("unknown command: %q", command)
+}
+
+
+func run(command string, args []string) error {
+    steps, err := parseCommandFn(command, args)
+    if err != nil {
+        fmt.Fprint(os.Stderr, usage)
+        return err
+    }
+    // Load embedded migrations first, broken set should fail before touching DB
+    migrations, err := sdb.LoadMigrationsFn(crudmigrations.FS)
+    if err != nil {
+        return err
+    }
+
+    // SIGINT/SIGTERM aborts DB startup wait as well as running migrations
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    db, err := sdb.GetConnContext(ctx)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    switch command {
+    case "up":
+        applied, err := sdb.MigrateUp(ctx, db, migrations)
+        for _, version := range applied {
+            log.Printf("migrate: applied %d", version)
+        }
+        if err != nil {
+            return err
+        }
+        log.Printf("migrate: up to date, %d applied", len(applied))
+    case "down":
+        reverted, err := sdb.MigrateDown(ctx, db, migrations, steps)
+        for _, version := range reverted {
+            log.Printf("migrate: reverted %d", version)
+        }
+        if err != nil {
+            return err
+        }
+    case "status":
+        states, err := sdb.GetMigrationStatus(ctx, db, migrations)
+        if err != nil {
+            return err
+        }
+        for _, state := range states {
+            appliedAt := "pending"
+            if state.Applied {
+                appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+            }
+            fmt.Printf("%04d  %-40s  %s\n", state.Version, state.Name, appliedAt)
+        }
+    }
+    return nil
+}
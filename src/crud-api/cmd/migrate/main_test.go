@@ -0,0 +1,40 @@
+package main
+import (
+    "testing"
+    "strings"
+)
+
+
+//{{{ parseCommandFn
+func Test_ParseCommandFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        command             string
+        args                []string
+        expectedSteps       int
+        expectedErrSubStr   string
+    }{
+        {"Up",              "up",       nil,                0,  ""},
+        {"Status",          "status",   nil,                0,  ""},
+        {"DownDefault",     "down",     nil,                1,  ""},
+        {"DownSteps",       "down",     []string{"3"},      3,  ""},
+        {"DownNotNumber",   "down",     []string{"x"},      0,  "invalid steps"},
+        {"DownZero",        "down",     []string{"0"},      0,  "invalid steps"},
+        {"Unknown",         "upp",      nil,                0,  "unknown command"},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            steps, err := parseCommandFn(tc.command, tc.args)
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrSubStr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErrSubStr, err)
+            }
+            if steps != tc.expectedSteps {
+                t.Errorf("Wrong steps:\nExpected:\t%d\nGot:\t\t%d", tc.expectedSteps, steps)
+            }
+        })
+    }
+}
+//}}} parseCommandFn
@@ -0,0 +1,360 @@
+package crudentry
+import (
+    "fmt"
+    "log"
+    "time"
+    "context"
+    "strconv"
+    "net/http"
+    "encoding/json"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+
+// List page size when "limit" is omitted, and its upper bound
+const (
+    DefaultEntryListLimit   = 50
+    MaxEntryListLimit       = 100
+)
+
+
+// Holds storage used by entry endpoints, OpTimeout is deadline for single
+//  store operation (0 = only bound by request context). Body are JSON decode
+//  rules, strict (unknown keys are 422) unless changed
+type EntryHandler struct {
+    Store       EntryStore
+    OpTimeout   time.Duration
+    Body        sapi.BodyOptions
+}
+
+
+func NewEntryHandler(store EntryStore) *EntryHandler {
+    return &EntryHandler{
+        Store:  store,
+        // Numbers of map bodies (update) stay json.Number
+        Body:   sapi.BodyOptions{DisallowUnknown: true, DisallowTrailing: true, UseNumber: true},
+    }
+}
+
+
+func (h *EntryHandler) opContextFn(r *http.Request) (context.Context, context.CancelFunc) {
+    return sapi.OpContextFn(r, h.OpTimeout)
+}
+
+
+// Owner + id identify single entry, both are required by read/update/delete
+type entryKey struct {
+    Owner   string  `json:"owner"`
+    ID      int64   `json:"id"`
+}
+
+
+// Owner is validated as username but reported under owner
+func (key entryKey) validate() error {
+    if err := smodels.IsValidUsernameFn(key.Owner); err != nil {
+        return smodels.RenameFieldErrFn(err, "owner")
+    }
+    return smodels.IsValidIDFn(key.ID)
+}
+
+
+//{{{ Create entry endpoint
+func (h *EntryHandler) CreateEntryEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "CreateEntryEndpoint"
+        // Input
+        entry       smodels.Entry
+        // Response info
+        statusCode  = 500
+        message     = "Fail: create entry ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]int64
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "entry", err), err, returnData)
+    }
+
+    // Decode request body into entry model
+    err := sapi.DecodeBodyFn(r, &entry, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+    // Server side fields are never taken from client
+    entry.ID, entry.CreatedAt, entry.UpdatedAt = 0, time.Time{}, time.Time{}
+
+    // Validate shape of entry fields
+    err = entry.Validate(); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to insert entry, ciphertext is not echoed back
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    created, err := h.Store.Insert(ctx, entry)
+    statusCode = sapi.StatusCodeFromErrFn(err, 201)
+    name := ""
+    if statusCode == 201 {
+        returnData = map[string]int64{"id":created.ID}
+        name = strconv.FormatInt(created.ID, 10)
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "create", "entry", name, err)
+    respond(err); return
+}
+//}}} Create entry endpoint
+
+
+//{{{ Read entry endpoint
+func (h *EntryHandler) ReadEntryEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "ReadEntryEndpoint"
+        // Input
+        key         entryKey
+        // Response info
+        statusCode  = 500
+        message     = "Fail: read entry ''"
+        errMessage  = "Unknown error occured"
+        entry       *smodels.Entry
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "entry", err), err, entry)
+    }
+
+    // Decode owner + id
+    err := sapi.DecodeBodyFn(r, &key, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate key
+    name := strconv.FormatInt(key.ID, 10)
+    err = key.validate()
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: read entry '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to select(fetch) entry, other owner's entry is not found
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    entry, err = h.Store.Get(ctx, key.Owner, key.ID)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "read", "entry", name, err)
+    respond(err); return
+}
+//}}} Read entry endpoint
+
+
+//{{{ List entry endpoint
+func (h *EntryHandler) ListEntryEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "ListEntryEndpoint"
+        // Input
+        input       struct {
+            Owner       string  `json:"owner"`
+            AfterID     int64   `json:"after_id"`
+            Limit       int     `json:"limit"`
+        }
+        // Response info
+        statusCode  = 500
+        message     = "Fail: list entry ''"
+        errMessage  = "Unknown error occured"
+        entries     []smodels.Entry
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "entry", err), err, entries)
+    }
+
+    // Decode owner + optional page
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate owner and page
+    message = fmt.Sprintf("Fail: list entry '%s'", input.Owner)
+    if input.Limit == 0 {
+        input.Limit = DefaultEntryListLimit
+    }
+    if err = smodels.IsValidUsernameFn(input.Owner); err != nil {
+        err = smodels.RenameFieldErrFn(err, "owner")
+    }
+    if err == nil && input.AfterID < 0 {
+        err = fmt.Errorf("after_id: must not be negative")
+    }
+    if err == nil && (input.Limit < 1 || input.Limit > MaxEntryListLimit) {
+        err = fmt.Errorf("limit: must be between 1 and %d", MaxEntryListLimit)
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to list entries, next page starts after last returned id
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    entries, err = h.Store.List(ctx, input.Owner, input.AfterID, input.Limit)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "list", "entry", input.Owner, err)
+    respond(err); return
+}
+//}}} List entry endpoint
+
+
+//{{{ Update entry endpoint
+func (h *EntryHandler) UpdateEntryEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "UpdateEntryEndpoint"
+        // Input
+        inputData   map[string]interface{}
+        key         entryKey
+        // Response info
+        statusCode  = 500
+        message     = "Fail: update entry ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]int64
+        ip          = r.RemoteAddr
+        success     = false
+    )
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "entry", err), err, returnData)
+    }
+
+    // Decode request body into map/dict data, numbers kept as json.Number
+    err := sapi.DecodeBodyFn(r, &inputData, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // - owner + id are required key
+    key.Owner, _ = inputData["owner"].(string)
+    rawID, _ := inputData["id"].(json.Number)
+    key.ID, _ = rawID.Int64()
+    name := strconv.FormatInt(key.ID, 10)
+    message = fmt.Sprintf("Fail: update entry '%s'", name)
+    err = key.validate(); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Sanitize data
+    // - remove key and illegal keys => sapi, strict mode names them instead
+    allowed := []string{"ciphertext", "nonce", "tag"}
+    if h.Body.DisallowUnknown {
+        err = sapi.CheckKnownKeysFn(inputData, append([]string{"owner", "id"}, allowed...)); if err != nil {
+            statusCode, errMessage = sapi.BodyErrResponseFn(err)
+            respond(err); return
+        }
+    }
+    filterdData := sapi.SanitizeKeysFn(inputData, allowed)
+    // - ciphertext, nonce, tag must come together => smodels
+    err = smodels.ValidateEntryMap(filterdData); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Call UpdateEntry
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Update(ctx, key.Owner, key.ID, filterdData)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = map[string]int64{"id":key.ID}
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "update", "entry", name, err)
+    respond(err); return
+}
+//}}} Update entry endpoint
+
+
+//{{{ Delete entry endpoint
+func (h *EntryHandler) DeleteEntryEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "DeleteEntryEndpoint"
+        // Input
+        key         entryKey
+        // Response info
+        statusCode  = 500
+        message     = "Fail: delete entry ''"
+        errMessage  = "Unknown error occured"
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "entry", err), err, nil)
+    }
+
+    // Decode owner + id
+    err := sapi.DecodeBodyFn(r, &key, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate key
+    name := strconv.FormatInt(key.ID, 10)
+    err = key.validate()
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: delete entry '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to delete entry
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Delete(ctx, key.Owner, key.ID)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "delete", "entry", name, err)
+    respond(err); return
+}
+//}}} Delete entry endpoint
@@ -0,0 +1,170 @@
+package crudentry
+import (
+    "testing"
+    "errors"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "fmt"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    "github.com/FAH2S/diar4/src/crud-api/internal/testutil"
+)
+
+
+//{{{ Entry endpoints
+func Test_EntryEndpoints(t *testing.T){
+    handler := NewEntryHandler(NewMemEntryStore(nil))
+    body := func(owner string) string {
+        return fmt.Sprintf(`{"owner":"%s","ciphertext":"%s","nonce":"%s","tag":"%s"}`, owner, testCiphertext, testNonce, testTag)
+    }
+    testutil.RunEndpointTests(t, handler.CreateEntryEndpoint, "/create/entry", []testutil.EndpointTestCase{
+        {
+            Name:               "CreateEntry",
+            Body:               body(testOwner),
+            ExpectedStatusCode: 201,
+            ExpectedMessage:    "Success: create entry '1'",
+            ExpectedData:       map[string]any{"id":float64(1)},
+        },{
+            Name:               "CreateEntryOtherOwner",
+            Body:               body("other_owner"),
+            ExpectedStatusCode: 201,
+            ExpectedMessage:    "Success: create entry '2'",
+            ExpectedData:       map[string]any{"id":float64(2)},
+        },{
+            Name:               "CiphertextTooLarge",
+            Body:               fmt.Sprintf(`{"owner":"%s","ciphertext":"%s","nonce":"%s","tag":"%s"}`,
+                testOwner, strings.Repeat("ab", smodels.EntryCiphertextMaxLen/2+1), testNonce, testTag),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create entry ''",
+            ExpectedError:      "Invalid input format: ciphertext: length must be between 2 and 65536 char long",
+        },{
+            Name:               "MissingTag",
+            Body:               fmt.Sprintf(`{"owner":"%s","ciphertext":"%s","nonce":"%s"}`, testOwner, testCiphertext, testNonce),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create entry ''",
+            ExpectedError:      "Invalid input format: tag: length must be exactly 32 char long",
+        },
+    })
+    testutil.RunEndpointTests(t, handler.ReadEntryEndpoint, "/read/entry", []testutil.EndpointTestCase{
+        {
+            Name:               "ReadOtherOwnersEntry",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":2}`, testOwner),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: read entry '2'",
+            ExpectedError:      "Entry not found, dosen't exist",
+        },{
+            Name:               "MissingOwner",
+            Body:               `{"id":1}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: read entry '1'",
+            ExpectedError:      "Invalid input format: owner: username: length must be between 3 and 30 char long",
+        },
+    })
+    testutil.RunEndpointTests(t, handler.ListEntryEndpoint, "/list/entry", []testutil.EndpointTestCase{
+        {
+            Name:               "LimitTooLarge",
+            Body:               fmt.Sprintf(`{"owner":"%s","limit":1000}`, testOwner),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: list entry '%s'", testOwner),
+            ExpectedError:      "Invalid input format: limit: must be between 1 and 100",
+        },{
+            Name:               "EmptyPage",
+            Body:               fmt.Sprintf(`{"owner":"%s","after_id":1}`, testOwner),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: list entry '%s'", testOwner),
+            ExpectedData:       []any{},
+        },
+    })
+    testutil.RunEndpointTests(t, handler.UpdateEntryEndpoint, "/update/entry", []testutil.EndpointTestCase{
+        {
+            Name:               "UpdateEntry",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":1,"ciphertext":"abcd","nonce":"%s","tag":"%s"}`, testOwner, testNonce, testTag),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    "Success: update entry '1'",
+            ExpectedData:       map[string]any{"id":float64(1)},
+        },{
+            Name:               "CiphertextWithoutNonceAndTag",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":1,"ciphertext":"abcd"}`, testOwner),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update entry '1'",
+            ExpectedError:      "Invalid input format: nonce: required, ciphertext, nonce and tag are updated together",
+        },{
+            Name:               "UpdateOtherOwnersEntry",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":2,"ciphertext":"abcd","nonce":"%s","tag":"%s"}`, testOwner, testNonce, testTag),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: update entry '2'",
+            ExpectedError:      "Entry not found, dosen't exist",
+        },
+    })
+    testutil.RunEndpointTests(t, handler.DeleteEntryEndpoint, "/delete/entry", []testutil.EndpointTestCase{
+        {
+            Name:               "DeleteEntry",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":1}`, testOwner),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    "Success: delete entry '1'",
+        },{
+            Name:               "NotFound",
+            Body:               fmt.Sprintf(`{"owner":"%s","id":1}`, testOwner),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: delete entry '1'",
+            ExpectedError:      "Entry not found, dosen't exist",
+        },
+    })
+
+    // List returns only own entries, in id order
+    req := httptest.NewRequest("POST", "/list/entry", strings.NewReader(`{"owner":"other_owner"}`))
+    resp := httptest.NewRecorder()
+    handler.ListEntryEndpoint(resp, req)
+    var listResp struct {
+        Data    []smodels.Entry `json:"data"`
+    }
+    if err := json.Unmarshal(resp.Body.Bytes(), &listResp); err != nil {
+        t.Fatalf("Failed to parse list response: %v", err)
+    }
+    if len(listResp.Data) != 1 || listResp.Data[0].ID != 2 || listResp.Data[0].Ciphertext != testCiphertext {
+        t.Errorf("Unexpected list: %+v", listResp.Data)
+    }
+}
+//}}} Entry endpoints
+
+
+//{{{ Owner field error
+// Owner is validated as username but every violation must name owner
+func Test_EntryOwnerFieldError(t *testing.T) {
+    // Key of read/update/delete
+    var fieldErr *smodels.FieldError
+    err := entryKey{Owner: "a!", ID: 1}.validate()
+    if !errors.As(err, &fieldErr) || fieldErr.Field != "owner" || fieldErr.Code != "username_length" {
+        t.Errorf("Wrong field error:\nExpected:\towner username_length\nGot:\t\t%+v (%v)", fieldErr, err)
+    }
+    // Problem+json of read and list lists owner as invalid field
+    handler := NewEntryHandler(NewMemEntryStore(nil))
+    tests := []struct {
+        name        string
+        endpoint    http.HandlerFunc
+        body        string
+    }{
+        {"Read", handler.ReadEntryEndpoint, `{"owner":"a!","id":1}`},
+        {"List", handler.ListEntryEndpoint, `{"owner":"a!"}`},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+            req.Header.Set("Accept", sapi.ProblemContentType)
+            resp := httptest.NewRecorder()
+            tc.endpoint(sapi.NegotiateProblemFn(resp, req), req)
+            var problem sapi.Problem
+            if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+                t.Fatalf("Failed to parse problem: %v", err)
+            }
+            if resp.Code != 422 || len(problem.Errors) != 1 || problem.Errors[0].Field != "owner" {
+                t.Errorf("Wrong violations:\nExpected:\t422 [owner]\nGot:\t\t%d %+v", resp.Code, problem.Errors)
+            }
+        })
+    }
+}
+//}}} Owner field error
@@ -0,0 +1,170 @@
+package crudentry
+import (
+    "fmt"
+    "context"
+    "database/sql"
+    "strings"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// Every query is scoped by owner, entry of other user is reported as not found.
+//  Entries of soft deleted owner are hidden, see cruduser.ActiveUserSQLFn
+var activeOwnerSQL = cruduser.ActiveUserSQLFn("entries", "owner")
+
+
+//{{{ InsertEntry
+// Returns entry with ID and timestamps set by DB. Missing or soft deleted owner
+//  is ErrMissingReference
+func InsertEntry(ctx context.Context, db *sql.DB, entry smodels.Entry) (*smodels.Entry, error) {
+    wrap := "InsertEntry"
+    // Create sql query, row is inserted only for active owner
+    query := `
+        INSERT INTO entries (owner, ciphertext, nonce, tag)
+        SELECT username, $2::text, $3::text, $4::text FROM users
+        WHERE username = $1 AND deleted_at IS NULL
+        RETURNING id, created_at, updated_at
+    `
+    // Insert + load generated columns
+    err := db.QueryRowContext(ctx, query, entry.Owner, entry.Ciphertext, entry.Nonce, entry.Tag).Scan(
+        &entry.ID,
+        &entry.CreatedAt,
+        &entry.UpdatedAt,
+    )
+    // No row inserted, same error FK violation would give
+    if err == sql.ErrNoRows {
+        return nil, &sdb.DBError{Kind: sdb.ErrMissingReference, Table: "entry", Column: "owner",
+            Err: fmt.Errorf("%s: owner not present", wrap)}
+    }
+    // Map pg errors to typed errors
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    return &entry, nil
+}
+//}}} InsertEntry
+
+
+//{{{ SelectEntry
+func SelectEntry(ctx context.Context, db *sql.DB, owner string, id int64) (*smodels.Entry, error) {
+    wrap := "SelectEntry"
+    // Create query
+    query := `
+        SELECT id, owner, ciphertext, nonce, tag, created_at, updated_at FROM entries
+        WHERE id = $1 AND owner = $2 AND ` + activeOwnerSQL + ` LIMIT 1;
+    `
+    // Create entry instance
+    var entry smodels.Entry
+    // Query row + Scan load result into entry
+    err := db.QueryRowContext(ctx, query, id, owner).Scan(
+        &entry.ID,
+        &entry.Owner,
+        &entry.Ciphertext,
+        &entry.Nonce,
+        &entry.Tag,
+        &entry.CreatedAt,
+        &entry.UpdatedAt,
+    )
+    // Check for errors, not found or failed query
+    err = sdb.HandleSelectErrorFn("entry", err)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return &entry, nil
+}
+//}}} SelectEntry
+
+
+//{{{ ListEntries
+// Entries of owner with id > afterID in id order, at most limit
+func ListEntries(ctx context.Context, db *sql.DB, owner string, afterID int64, limit int) ([]smodels.Entry, error) {
+    wrap := "ListEntries"
+    // Create query
+    query := `
+        SELECT id, owner, ciphertext, nonce, tag, created_at, updated_at FROM entries
+        WHERE owner = $1 AND id > $2 AND ` + activeOwnerSQL + `
+        ORDER BY id
+        LIMIT $3;
+    `
+    rows, err := db.QueryContext(ctx, query, owner, afterID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    defer rows.Close()
+    // Scan each row, empty list is not an error
+    entries := []smodels.Entry{}
+    for rows.Next() {
+        var entry smodels.Entry
+        err = rows.Scan(
+            &entry.ID,
+            &entry.Owner,
+            &entry.Ciphertext,
+            &entry.Nonce,
+            &entry.Tag,
+            &entry.CreatedAt,
+            &entry.UpdatedAt,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+        }
+        entries = append(entries, entry)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    return entries, nil
+}
+//}}} ListEntries
+
+
+//{{{ UpdateEntry
+// data must be validated via smodels.ValidateEntryMap, updated_at is bumped by query
+func UpdateEntry(ctx context.Context, db *sql.DB, data map[string]interface{}, owner string, id int64) error {
+    wrap := "UpdateEntry"
+    // Build set parts, return err if empty
+    setParts, args, err := sdb.BuildSetPartsFn(data)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+    // Create querry
+    query := fmt.Sprintf(`UPDATE entries SET %s WHERE id = $%d AND owner = $%d AND %s`,
+        strings.Join(setParts, ", "), len(args)+1, len(args)+2, activeOwnerSQL)
+    args = append(args, id, owner)
+    // Update DB
+    result, err := db.ExecContext(ctx, query, args...)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    // Check
+    if err = sdb.CheckRowsAffectedFn("entry", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} UpdateEntry
+
+
+//{{{ DeleteEntry
+func DeleteEntry(ctx context.Context, db *sql.DB, owner string, id int64) error {
+    wrap := "DeleteEntry"
+    // Create query
+    query := `DELETE FROM entries WHERE id = $1 AND owner = $2 AND ` + activeOwnerSQL + `;`
+    // Execute
+    result, err := db.ExecContext(ctx, query, id, owner)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    // Check rows affected
+    if err = sdb.CheckRowsAffectedFn("entry", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} DeleteEntry
@@ -0,0 +1,209 @@
+package crudentry
+import (
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+    "errors"
+    "context"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// In-memory EntryStore, mirrors Postgres semantics (serial id, CHECK constraints,
+// owner scoped lookups, done context). Owner FK and soft delete are checked
+// against Users when set (nil = not checked). Safe for concurrent use.
+type MemEntryStore struct {
+    Users   cruduser.UserStore
+    mu      sync.RWMutex
+    nextID  int64
+    entries map[int64]smodels.Entry
+}
+
+
+func NewMemEntryStore(users cruduser.UserStore) *MemEntryStore {
+    return &MemEntryStore{Users: users, nextID: 1, entries: make(map[int64]smodels.Entry)}
+}
+
+
+// Columns of entries table that can be SET by Update
+var memEntryColumns = map[string]struct{}{
+    "ciphertext":   {},
+    "nonce":        {},
+    "tag":          {},
+}
+
+
+// Same typed error Postgres path would return
+func memEntryErrFn(kind error, column string, err error) error {
+    return &sdb.DBError{Kind: kind, Table: "entry", Column: column, Err: err}
+}
+
+
+// Missing or soft deleted owner is not active, its entries are hidden
+func (s *MemEntryStore) ownerActiveFn(ctx context.Context, owner string) (bool, error) {
+    if s.Users == nil {
+        return true, nil
+    }
+    _, err := s.Users.Get(ctx, owner, []string{"username"})
+    if errors.Is(err, sdb.ErrNotFound) {
+        return false, nil
+    }
+    return err == nil, err
+}
+
+
+// Stored entry of owner, not ok when missing, of other owner or owner isn't active. Caller holds s.mu
+func (s *MemEntryStore) visibleFn(ctx context.Context, owner string, id int64) (smodels.Entry, bool, error) {
+    entry, ok := s.entries[id]
+    if !ok || entry.Owner != owner {
+        return entry, false, nil
+    }
+    active, err := s.ownerActiveFn(ctx, owner)
+    return entry, active, err
+}
+
+
+func (s *MemEntryStore) Insert(ctx context.Context, entry smodels.Entry) (*smodels.Entry, error) {
+    wrap := "MemEntryStore.Insert"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    if err := entry.Validate(); err != nil {
+        return nil, memEntryErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid entry data/format: %w", wrap, err))
+    }
+    active, err := s.ownerActiveFn(ctx, entry.Owner)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !active {
+        return nil, memEntryErrFn(sdb.ErrMissingReference, "owner", fmt.Errorf("%s: owner not present", wrap))
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now().UTC()
+    entry.ID = s.nextID
+    entry.CreatedAt = now
+    entry.UpdatedAt = now
+    s.nextID++
+    s.entries[entry.ID] = entry
+    return &entry, nil
+}
+
+
+func (s *MemEntryStore) Get(ctx context.Context, owner string, id int64) (*smodels.Entry, error) {
+    wrap := "MemEntryStore.Get"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    entry, ok, err := s.visibleFn(ctx, owner, id)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return nil, memEntryErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: entry not found/dosen't exist", wrap))
+    }
+    // Return copy so caller can't mutate stored entry
+    return &entry, nil
+}
+
+
+func (s *MemEntryStore) List(ctx context.Context, owner string, afterID int64, limit int) ([]smodels.Entry, error) {
+    wrap := "MemEntryStore.List"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    // Entries of inactive owner are hidden, empty list same as owner without entries
+    entries := []smodels.Entry{}
+    active, err := s.ownerActiveFn(ctx, owner)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !active {
+        return entries, nil
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    for _, entry := range s.entries {
+        if entry.Owner == owner && entry.ID > afterID {
+            entries = append(entries, entry)
+        }
+    }
+    // Same order as ORDER BY id + LIMIT
+    sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+    if len(entries) > limit {
+        entries = entries[:limit]
+    }
+    return entries, nil
+}
+
+
+func (s *MemEntryStore) Update(ctx context.Context, owner string, id int64, data map[string]interface{}) error {
+    wrap := "MemEntryStore.Update"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    // Same order of checks as UpdateEntry: empty, unknown column, not found, CHECK
+    if len(data) == 0 {
+        return memEntryErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: no fields to update", wrap))
+    }
+    for k := range data {
+        if _, ok := memEntryColumns[k]; !ok {
+            return memEntryErrFn(sdb.ErrUnknownColumn, k, fmt.Errorf("%s: unknown column used: %q", wrap, k))
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    entry, ok, err := s.visibleFn(ctx, owner, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memEntryErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    // Apply, values come from smodels.ValidateEntryMap
+    for k, v := range data {
+        val, _ := v.(string)
+        switch k {
+        case "ciphertext":
+            entry.Ciphertext = val
+        case "nonce":
+            entry.Nonce = val
+        case "tag":
+            entry.Tag = val
+        }
+    }
+    if err := entry.Validate(); err != nil {
+        return memEntryErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid entry data/format: %w", wrap, err))
+    }
+    entry.UpdatedAt = time.Now().UTC()
+    s.entries[id] = entry
+    return nil
+}
+
+
+func (s *MemEntryStore) Delete(ctx context.Context, owner string, id int64) error {
+    wrap := "MemEntryStore.Delete"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("entry", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, ok, err := s.visibleFn(ctx, owner, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memEntryErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.entries, id)
+    return nil
+}
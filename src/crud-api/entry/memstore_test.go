@@ -0,0 +1,109 @@
+package crudentry
+import (
+    "testing"
+    "context"
+    "errors"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+const (
+    testOwner =         "test_entry_owner"
+    testCiphertext =    "0c8fd825308df79b313a71b90ee93f7d"
+    testNonce =         "00112233445566778899aabb"
+    testTag =           "00112233445566778899aabbccddeeff"
+)
+
+
+var ctx = context.Background()
+
+
+func newTestEntryFn(owner string) smodels.Entry {
+    return smodels.Entry{Owner: owner, Ciphertext: testCiphertext, Nonce: testNonce, Tag: testTag}
+}
+
+
+//{{{ MemEntryStore
+func Test_MemEntryStore(t *testing.T) {
+    store := NewMemEntryStore(nil)
+    for i := 0; i < 3; i++ {
+        if _, err := store.Insert(ctx, newTestEntryFn(testOwner)); err != nil {
+            t.Fatalf("Failed to create entry: %v", err)
+        }
+    }
+    if _, err := store.Insert(ctx, newTestEntryFn("other_owner")); err != nil {
+        t.Fatalf("Failed to create entry: %v", err)
+    }
+    // Other owner's entry is not found
+    if _, err := store.Get(ctx, testOwner, 4); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err := store.Delete(ctx, testOwner, 4); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    // Page after id 1, limit 1
+    entries, err := store.List(ctx, testOwner, 1, 1)
+    if err != nil || len(entries) != 1 || entries[0].ID != 2 {
+        t.Errorf("Wrong page: %+v (%v)", entries, err)
+    }
+    // Update needs valid shape
+    err = store.Update(ctx, testOwner, 1, map[string]interface{}{"nonce": "00"})
+    if !errors.Is(err, sdb.ErrInvalid) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrInvalid, err)
+    }
+}
+//}}} MemEntryStore
+
+
+//{{{ MemEntryStore soft deleted owner
+func Test_MemEntryStore_SoftDeletedOwner(t *testing.T) {
+    users := cruduser.NewMemUserStore()
+    if err := users.Insert(ctx, smodels.User{
+        Username:   testOwner,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }); err != nil {
+        t.Fatalf("Failed to create owner: %v", err)
+    }
+    store := NewMemEntryStore(users)
+    created, err := store.Insert(ctx, newTestEntryFn(testOwner))
+    if err != nil {
+        t.Fatalf("Failed to create entry: %v", err)
+    }
+    // Missing owner is missing reference, same as FK
+    if _, err = store.Insert(ctx, newTestEntryFn("not_found")); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if err = users.Delete(ctx, testOwner); err != nil {
+        t.Fatalf("Failed to soft delete owner: %v", err)
+    }
+    // Soft deleted owner can't get new entries, its entries are hidden
+    if _, err = store.Insert(ctx, newTestEntryFn(testOwner)); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if _, err = store.Get(ctx, testOwner, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if entries, err := store.List(ctx, testOwner, 0, 10); err != nil || len(entries) != 0 {
+        t.Errorf("Wrong page:\nExpected:\t[]\nGot:\t\t%+v (%v)", entries, err)
+    }
+    if err = store.Update(ctx, testOwner, created.ID, map[string]interface{}{"tag": testTag}); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = store.Delete(ctx, testOwner, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    // Restored owner sees its entries again
+    if err = users.Restore(ctx, testOwner); err != nil {
+        t.Fatalf("Failed to restore owner: %v", err)
+    }
+    if entries, err := store.List(ctx, testOwner, 0, 10); err != nil || len(entries) != 1 {
+        t.Errorf("Wrong page after restore: %+v (%v)", entries, err)
+    }
+}
+//}}} MemEntryStore soft deleted owner
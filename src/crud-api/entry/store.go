@@ -0,0 +1,56 @@
+package crudentry
+import (
+    "context"
+    "database/sql"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+// Storage behind entry endpoints, every operation is scoped by owner.
+//  Errors are typed shareddb errors, check with errors.Is
+type EntryStore interface {
+    Insert(ctx context.Context, entry smodels.Entry) (*smodels.Entry, error)
+    Get(ctx context.Context, owner string, id int64) (*smodels.Entry, error)
+    List(ctx context.Context, owner string, afterID int64, limit int) ([]smodels.Entry, error)
+    Update(ctx context.Context, owner string, id int64, data map[string]interface{}) error
+    Delete(ctx context.Context, owner string, id int64) error
+}
+
+
+//{{{ Postgres store
+type PgEntryStore struct {
+    DB  *sql.DB
+}
+
+
+func NewPgEntryStore(db *sql.DB) *PgEntryStore {
+    return &PgEntryStore{DB: db}
+}
+
+
+func (s *PgEntryStore) Insert(ctx context.Context, entry smodels.Entry) (*smodels.Entry, error) {
+    return InsertEntry(ctx, s.DB, entry)
+}
+
+
+func (s *PgEntryStore) Get(ctx context.Context, owner string, id int64) (*smodels.Entry, error) {
+    return SelectEntry(ctx, s.DB, owner, id)
+}
+
+
+func (s *PgEntryStore) List(ctx context.Context, owner string, afterID int64, limit int) ([]smodels.Entry, error) {
+    return ListEntries(ctx, s.DB, owner, afterID, limit)
+}
+
+
+func (s *PgEntryStore) Update(ctx context.Context, owner string, id int64, data map[string]interface{}) error {
+    return UpdateEntry(ctx, s.DB, data, owner, id)
+}
+
+
+func (s *PgEntryStore) Delete(ctx context.Context, owner string, id int64) error {
+    return DeleteEntry(ctx, s.DB, owner, id)
+}
+//}}} Postgres store
@@ -0,0 +1,277 @@
+package crudevent
+import (
+    "fmt"
+    "log"
+    "time"
+    "context"
+    "strconv"
+    "net/http"
+    "encoding/json"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+// Holds storage used by event endpoints, OpTimeout is deadline for single
+//  store operation (0 = only bound by request context). Body are JSON decode
+//  rules, strict (unknown keys are 422) unless changed
+type EventHandler struct {
+    Store       EventStore
+    OpTimeout   time.Duration
+    Body        sapi.BodyOptions
+}
+
+
+func NewEventHandler(store EventStore) *EventHandler {
+    return &EventHandler{
+        Store:  store,
+        // Numbers of map bodies (update) stay json.Number
+        Body:   sapi.BodyOptions{DisallowUnknown: true, DisallowTrailing: true, UseNumber: true},
+    }
+}
+
+
+func (h *EventHandler) opContextFn(r *http.Request) (context.Context, context.CancelFunc) {
+    return sapi.OpContextFn(r, h.OpTimeout)
+}
+
+
+//{{{ Create event endpoint
+func (h *EventHandler) CreateEventEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "CreateEventEndpoint"
+        // Input
+        event       smodels.Event
+        // Response info
+        statusCode  = 500
+        message     = "Fail: create event ''"
+        errMessage  = "Unknown error occurred"
+        returnData  *smodels.Event
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "event", err), err, returnData)
+    }
+
+    // Decode request body into event model, times must be RFC 3339
+    err := sapi.DecodeBodyFn(r, &event, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+    // Server side fields are never taken from client
+    event.ID, event.CreatedAt, event.UpdatedAt = 0, time.Time{}, time.Time{}
+
+    // Validate event model fields
+    err = event.Validate(); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to insert event
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    created, err := h.Store.Insert(ctx, event)
+    statusCode = sapi.StatusCodeFromErrFn(err, 201)
+    name := ""
+    if statusCode == 201 {
+        returnData = created
+        name = strconv.FormatInt(created.ID, 10)
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "create", "event", name, err)
+    respond(err); return
+}
+//}}} Create event endpoint
+
+
+//{{{ Read event endpoint
+func (h *EventHandler) ReadEventEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "ReadEventEndpoint"
+        // Input
+        id          int64
+        // Response info
+        statusCode  = 500
+        message     = "Fail: read event ''"
+        errMessage  = "Unknown error occured"
+        event       *smodels.Event
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "event", err), err, event)
+    }
+
+    // Extract id from request body
+    err := sapi.ExtractBodyFn(r, map[string]interface{}{"id": &id}, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate extracted id
+    name := strconv.FormatInt(id, 10)
+    err = smodels.IsValidIDFn(id)
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: read event '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to select(fetch) event
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    event, err = h.Store.Get(ctx, id)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "read", "event", name, err)
+    respond(err); return
+}
+//}}} Read event endpoint
+
+
+//{{{ Update event endpoint
+func (h *EventHandler) UpdateEventEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "UpdateEventEndpoint"
+        // Input
+        inputData   map[string]interface{}
+        // Response info
+        statusCode  = 500
+        message     = "Fail: update event ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]int64
+        ip          = r.RemoteAddr
+        success     = false
+    )
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "event", err), err, returnData)
+    }
+
+    // Decode request body into map/dict data, numbers kept as json.Number
+    err := sapi.DecodeBodyFn(r, &inputData, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // - id field must be present and positive integer
+    rawID, _ := inputData["id"].(json.Number)
+    id, err := rawID.Int64()
+    if err == nil {
+        err = smodels.IsValidIDFn(id)
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = "Missing or invalid required field: 'id'"
+        respond(err); return
+    }
+    name := strconv.FormatInt(id, 10)
+    message = fmt.Sprintf("Fail: update event '%s'", name)
+
+    // Sanitize data
+    // - remove illegal keys (id, owner, timestamps set by DB) => sapi, strict mode names them instead
+    allowed := []string{"enc_payload", "starts_at", "ends_at"}
+    if h.Body.DisallowUnknown {
+        err = sapi.CheckKnownKeysFn(inputData, append([]string{"id"}, allowed...)); if err != nil {
+            statusCode, errMessage = sapi.BodyErrResponseFn(err)
+            respond(err); return
+        }
+    }
+    filterdData := sapi.SanitizeKeysFn(inputData, allowed)
+    // - check for at least 1 field
+    if len(filterdData) < 1 {
+        statusCode = 422
+        errMessage = "Invalid input: must contain at least 1 updatable field"
+        respond(err); return
+    }
+    // - check each present field, times parsed => smodels
+    data, err := smodels.ValidateEventMap(filterdData); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Call UpdateEvent
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Update(ctx, id, data)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = map[string]int64{"id":id}
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "update", "event", name, err)
+    respond(err); return
+}
+//}}} Update event endpoint
+
+
+//{{{ Delete event endpoint
+func (h *EventHandler) DeleteEventEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "DeleteEventEndpoint"
+        // Input
+        id          int64
+        // Response info
+        statusCode  = 500
+        message     = "Fail: delete event ''"
+        errMessage  = "Unknown error occured"
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "event", err), err, nil)
+    }
+
+    // Extract id from request body
+    err := sapi.ExtractBodyFn(r, map[string]interface{}{"id": &id}, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate extracted id
+    name := strconv.FormatInt(id, 10)
+    err = smodels.IsValidIDFn(id)
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: delete event '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to delete event
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Delete(ctx, id)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "delete", "event", name, err)
+    respond(err); return
+}
+//}}} Delete event endpoint
@@ -0,0 +1,218 @@
+package crudevent
+import (
+    "testing"
+    "encoding/json"
+    "fmt"
+)
+import (
+    "github.com/FAH2S/diar4/src/crud-api/internal/testutil"
+)
+
+
+//{{{ CreateEventEndpoint
+func Test_CreateEventEndpoint(t *testing.T){
+    handler := NewEventHandler(NewMemEventStore(nil))
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "CreateEvent",
+            Body:               fmt.Sprintf(`{
+                "owner":"test_event_owner",
+                "enc_payload":"%s",
+                "starts_at":"2025-01-01T10:00:00Z",
+                "ends_at":"2025-01-01T11:00:00Z"
+            }`, testEncPayload),
+            ExpectedStatusCode: 201,
+            ExpectedMessage:    "Success: create event '1'",
+            ExpectedError:      "",
+            SkipData:           true,
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"owner":"test_event_owner"`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: create event ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedTime",
+            Body:               `{"owner":"test_event_owner","starts_at":"tomorrow"}`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: create event ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "EndsBeforeStarts",
+            Body:               fmt.Sprintf(`{
+                "owner":"test_event_owner",
+                "enc_payload":"%s",
+                "starts_at":"2025-01-01T10:00:00Z",
+                "ends_at":"2025-01-01T09:00:00Z"
+            }`, testEncPayload),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create event ''",
+            ExpectedError:      "Invalid input format: ends_at: must not be before starts_at",
+            ExpectedData:       nil,
+        },{
+            Name:               "InvalidPayload",
+            Body:               `{
+                "owner":"test_event_owner",
+                "enc_payload":"xyz",
+                "starts_at":"2025-01-01T10:00:00Z",
+                "ends_at":"2025-01-01T11:00:00Z"
+            }`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create event ''",
+            ExpectedError:      "Invalid input format: enc_payload: length must be even",
+            ExpectedData:       nil,
+        },
+    }
+    testutil.RunEndpointTests(t, handler.CreateEventEndpoint, "/create/event", tests)
+}
+//}}} CreateEventEndpoint
+
+
+//{{{ ReadEventEndpoint
+func Test_ReadEventEndpoint(t *testing.T){
+    handler := NewEventHandler(NewMemEventStore(nil))
+    created, err := handler.Store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be read/fetch-ed: %v", err)
+    }
+    // Expected data is event as it goes over the wire
+    raw, _ := json.Marshal(created)
+    var expected map[string]any
+    _ = json.Unmarshal(raw, &expected)
+
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "ReadEvent",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: read event '%d'", created.ID),
+            ExpectedError:      "",
+            ExpectedData:       expected,
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"id":1`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: read event ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "NotFound",
+            Body:               `{"id":999}`,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: read event '999'",
+            ExpectedError:      "Event not found, dosen't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableID",
+            Body:               `{"id":0}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: read event '0'",
+            ExpectedError:      "Invalid input format: id: must be positive integer",
+            ExpectedData:       nil,
+        },
+    }
+    testutil.RunEndpointTests(t, handler.ReadEventEndpoint, "/read/event", tests)
+}
+//}}} ReadEventEndpoint
+
+
+//{{{ UpdateEventEndpoint
+func Test_UpdateEventEndpoint(t *testing.T){
+    handler := NewEventHandler(NewMemEventStore(nil))
+    created, err := handler.Store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be updated: %v", err)
+    }
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "UpdateEvent",
+            Body:               fmt.Sprintf(`{"id":%d,"enc_payload":"abcd","ends_at":"2025-01-02T10:00:00Z"}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: update event '%d'", created.ID),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"id":float64(created.ID)},
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"id":1,`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: update event ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "MissingID",
+            Body:               `{"enc_payload":"abcd"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update event ''",
+            ExpectedError:      "Missing or invalid required field: 'id'",
+            ExpectedData:       nil,
+        },{
+            Name:               "OnlyIllegalFields",
+            Body:               fmt.Sprintf(`{"id":%d,"owner":"someone_else"}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update event '%d'", created.ID),
+            ExpectedError:      "Invalid input format: unknown field \"owner\"",
+            ExpectedData:       nil,
+        },{
+            Name:               "NoUpdatableFields",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update event '%d'", created.ID),
+            ExpectedError:      "Invalid input: must contain at least 1 updatable field",
+            ExpectedData:       nil,
+        },{
+            Name:               "EndsBeforeStarts",
+            Body:               fmt.Sprintf(`{"id":%d,"ends_at":"2024-01-01T10:00:00Z"}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update event '%d'", created.ID),
+            ExpectedError:      "Invalid input format",
+            ExpectedData:       nil,
+        },{
+            Name:               "NotFound",
+            Body:               `{"id":999,"enc_payload":"abcd"}`,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: update event '999'",
+            ExpectedError:      "Event not found, dosen't exist",
+            ExpectedData:       nil,
+        },
+    }
+    testutil.RunEndpointTests(t, handler.UpdateEventEndpoint, "/update/event", tests)
+}
+//}}} UpdateEventEndpoint
+
+
+//{{{ DeleteEventEndpoint
+func Test_DeleteEventEndpoint(t *testing.T){
+    handler := NewEventHandler(NewMemEventStore(nil))
+    created, err := handler.Store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be deleted: %v", err)
+    }
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "DeleteEvent",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: delete event '%d'", created.ID),
+            ExpectedError:      "",
+            ExpectedData:       nil,
+        },{
+            Name:               "NotFound",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: delete event '%d'", created.ID),
+            ExpectedError:      "Event not found, dosen't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableID",
+            Body:               `{"id":-1}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: delete event '-1'",
+            ExpectedError:      "Invalid input format: id: must be positive integer",
+            ExpectedData:       nil,
+        },
+    }
+    testutil.RunEndpointTests(t, handler.DeleteEventEndpoint, "/delete/event", tests)
+}
+//}}} DeleteEventEndpoint
@@ -0,0 +1,126 @@
+package crudevent
+import (
+    "fmt"
+    "context"
+    "database/sql"
+    "strings"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// Events of soft deleted owner are hidden, see cruduser.ActiveUserSQLFn
+var activeOwnerSQL = cruduser.ActiveUserSQLFn("events", "owner")
+
+
+//{{{ InsertEvent
+// Returns event with ID and timestamps set by DB. Missing or soft deleted owner
+//  is ErrMissingReference
+func InsertEvent(ctx context.Context, db *sql.DB, event smodels.Event) (*smodels.Event, error) {
+    wrap := "InsertEvent"
+    // Create sql query, row is inserted only for active owner
+    query := `
+        INSERT INTO events (owner, enc_payload, starts_at, ends_at)
+        SELECT username, $2::text, $3::timestamptz, $4::timestamptz FROM users
+        WHERE username = $1 AND deleted_at IS NULL
+        RETURNING id, created_at, updated_at
+    `
+    // Insert + load generated columns
+    err := db.QueryRowContext(ctx, query, event.Owner, event.EncPayload, event.StartsAt, event.EndsAt).Scan(
+        &event.ID,
+        &event.CreatedAt,
+        &event.UpdatedAt,
+    )
+    // No row inserted, same error FK violation would give
+    if err == sql.ErrNoRows {
+        return nil, &sdb.DBError{Kind: sdb.ErrMissingReference, Table: "event", Column: "owner",
+            Err: fmt.Errorf("%s: owner not present", wrap)}
+    }
+    // Map pg errors to typed errors
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    return &event, nil
+}
+//}}} InsertEvent
+
+
+//{{{ SelectEvent
+func SelectEvent(ctx context.Context, db *sql.DB, id int64) (*smodels.Event, error) {
+    wrap := "SelectEvent"
+    // Create query
+    query := `
+        SELECT id, owner, enc_payload, starts_at, ends_at, created_at, updated_at FROM events
+        WHERE id = $1 AND ` + activeOwnerSQL + ` LIMIT 1;
+    `
+    // Create event instance
+    var event smodels.Event
+    // Query row + Scan load result into event
+    err := db.QueryRowContext(ctx, query, id).Scan(
+        &event.ID,
+        &event.Owner,
+        &event.EncPayload,
+        &event.StartsAt,
+        &event.EndsAt,
+        &event.CreatedAt,
+        &event.UpdatedAt,
+    )
+    // Check for errors, not found or failed query
+    err = sdb.HandleSelectErrorFn("event", err)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return &event, nil
+}
+//}}} SelectEvent
+
+
+//{{{ UpdateEvent
+// data must be validated via smodels.ValidateEventMap, updated_at is bumped by query
+func UpdateEvent(ctx context.Context, db *sql.DB, data map[string]interface{}, id int64) error {
+    wrap := "UpdateEvent"
+    // Build set parts, return err if empty
+    setParts, args, err := sdb.BuildSetPartsFn(data)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+    // Create querry
+    query := fmt.Sprintf(`UPDATE events SET %s WHERE id = $%d AND %s`, strings.Join(setParts, ", "), len(args)+1, activeOwnerSQL)
+    args = append(args, id)
+    // Update DB
+    result, err := db.ExecContext(ctx, query, args...)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    // Check
+    if err = sdb.CheckRowsAffectedFn("event", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} UpdateEvent
+
+
+//{{{ DeleteEvent
+func DeleteEvent(ctx context.Context, db *sql.DB, id int64) error {
+    wrap := "DeleteEvent"
+    // Create query
+    query := `DELETE FROM events WHERE id = $1 AND ` + activeOwnerSQL + `;`
+    // Execute
+    result, err := db.ExecContext(ctx, query, id)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    // Check rows affected
+    if err = sdb.CheckRowsAffectedFn("event", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} DeleteEvent
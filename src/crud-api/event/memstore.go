@@ -0,0 +1,177 @@
+package crudevent
+import (
+    "fmt"
+    "sync"
+    "time"
+    "errors"
+    "context"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// In-memory EventStore, mirrors Postgres semantics (serial id, CHECK constraints,
+// not found on update/delete, done context). Owner FK and soft delete are checked
+// against Users when set (nil = not checked). Safe for concurrent use.
+type MemEventStore struct {
+    Users   cruduser.UserStore
+    mu      sync.RWMutex
+    nextID  int64
+    events  map[int64]smodels.Event
+}
+
+
+func NewMemEventStore(users cruduser.UserStore) *MemEventStore {
+    return &MemEventStore{Users: users, nextID: 1, events: make(map[int64]smodels.Event)}
+}
+
+
+// Columns of events table that can be SET by Update
+var memEventColumns = map[string]struct{}{
+    "enc_payload":  {},
+    "starts_at":    {},
+    "ends_at":      {},
+}
+
+
+// Same typed error Postgres path would return
+func memEventErrFn(kind error, column string, err error) error {
+    return &sdb.DBError{Kind: kind, Table: "event", Column: column, Err: err}
+}
+
+
+// Missing or soft deleted owner is not active, its events are hidden
+func (s *MemEventStore) ownerActiveFn(ctx context.Context, owner string) (bool, error) {
+    if s.Users == nil {
+        return true, nil
+    }
+    _, err := s.Users.Get(ctx, owner, []string{"username"})
+    if errors.Is(err, sdb.ErrNotFound) {
+        return false, nil
+    }
+    return err == nil, err
+}
+
+
+// Stored event, not ok when missing or its owner isn't active. Caller holds s.mu
+func (s *MemEventStore) visibleFn(ctx context.Context, id int64) (smodels.Event, bool, error) {
+    event, ok := s.events[id]
+    if !ok {
+        return event, false, nil
+    }
+    active, err := s.ownerActiveFn(ctx, event.Owner)
+    return event, active, err
+}
+
+
+func (s *MemEventStore) Insert(ctx context.Context, event smodels.Event) (*smodels.Event, error) {
+    wrap := "MemEventStore.Insert"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    if err := event.Validate(); err != nil {
+        return nil, memEventErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid event data/format: %w", wrap, err))
+    }
+    active, err := s.ownerActiveFn(ctx, event.Owner)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !active {
+        return nil, memEventErrFn(sdb.ErrMissingReference, "owner", fmt.Errorf("%s: owner not present", wrap))
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now().UTC()
+    event.ID = s.nextID
+    event.CreatedAt = now
+    event.UpdatedAt = now
+    s.nextID++
+    s.events[event.ID] = event
+    return &event, nil
+}
+
+
+func (s *MemEventStore) Get(ctx context.Context, id int64) (*smodels.Event, error) {
+    wrap := "MemEventStore.Get"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    event, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return nil, memEventErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: event not found/dosen't exist", wrap))
+    }
+    // Return copy so caller can't mutate stored event
+    return &event, nil
+}
+
+
+func (s *MemEventStore) Update(ctx context.Context, id int64, data map[string]interface{}) error {
+    wrap := "MemEventStore.Update"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    // Same order of checks as UpdateEvent: empty, unknown column, not found, CHECK
+    if len(data) == 0 {
+        return memEventErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: no fields to update", wrap))
+    }
+    for k := range data {
+        if _, ok := memEventColumns[k]; !ok {
+            return memEventErrFn(sdb.ErrUnknownColumn, k, fmt.Errorf("%s: unknown column used: %q", wrap, k))
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    event, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memEventErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    // Apply, values come from smodels.ValidateEventMap
+    for k, v := range data {
+        switch k {
+        case "enc_payload":
+            event.EncPayload, _ = v.(string)
+        case "starts_at":
+            event.StartsAt, _ = v.(time.Time)
+        case "ends_at":
+            event.EndsAt, _ = v.(time.Time)
+        }
+    }
+    if err := event.Validate(); err != nil {
+        return memEventErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid event data/format: %w", wrap, err))
+    }
+    event.UpdatedAt = time.Now().UTC()
+    s.events[id] = event
+    return nil
+}
+
+
+func (s *MemEventStore) Delete(ctx context.Context, id int64) error {
+    wrap := "MemEventStore.Delete"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("event", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memEventErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.events, id)
+    return nil
+}
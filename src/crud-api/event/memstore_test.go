@@ -0,0 +1,195 @@
+package crudevent
+import (
+    "testing"
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+const testEncPayload = "0c8fd825308df79b313a71b90ee93f7d"
+
+
+var (
+    ctx = context.Background()
+    testStartsAt = time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+)
+
+
+func newTestEventFn(owner string) smodels.Event {
+    return smodels.Event{
+        Owner:      owner,
+        EncPayload: testEncPayload,
+        StartsAt:   testStartsAt,
+        EndsAt:     testStartsAt.Add(time.Hour),
+    }
+}
+
+
+//{{{ Insert
+func Test_MemEventStore_Insert(t *testing.T) {
+    store := NewMemEventStore(nil)
+    // Serial ids
+    for i := int64(1); i <= 2; i++ {
+        event, err := store.Insert(ctx, newTestEventFn("test_event_owner"))
+        if err != nil || event.ID != i || event.CreatedAt.IsZero() {
+            t.Errorf("Wrong result:\nExpected id:\t%d\nGot:\t\t%+v (%v)", i, event, err)
+        }
+    }
+    // CHECK constraint
+    invalid := newTestEventFn("test_event_owner")
+    invalid.EndsAt = testStartsAt.Add(-time.Hour)
+    if _, err := store.Insert(ctx, invalid); !errors.Is(err, sdb.ErrInvalid) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrInvalid, err)
+    }
+}
+//}}} Insert
+
+
+//{{{ Get
+func Test_MemEventStore_Get(t *testing.T) {
+    store := NewMemEventStore(nil)
+    created, err := store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be read/fetch-ed: %v", err)
+    }
+    // Found
+    got, err := store.Get(ctx, created.ID)
+    if err != nil || *got != *created {
+        t.Errorf("Wrong result:\nExpected:\t%+v\nGot:\t\t%+v (%v)", created, got, err)
+    }
+    // Not found
+    if _, err := store.Get(ctx, 999); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+}
+//}}} Get
+
+
+//{{{ Update
+func Test_MemEventStore_Update(t *testing.T) {
+    store := NewMemEventStore(nil)
+    created, err := store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be updated: %v", err)
+    }
+    tests := []struct {
+        name        string
+        id          int64
+        data        map[string]interface{}
+        expectedErr error
+    }{
+        {
+            name:           "Success",
+            id:             created.ID,
+            data:           map[string]interface{}{"enc_payload": "abcd"},
+            expectedErr:    nil,
+        }, {
+            name:           "Empty",
+            id:             created.ID,
+            data:           map[string]interface{}{},
+            expectedErr:    sdb.ErrInvalid,
+        }, {
+            name:           "UnknownColumn",
+            id:             created.ID,
+            data:           map[string]interface{}{"owner": "other_user"},
+            expectedErr:    sdb.ErrUnknownColumn,
+        }, {
+            name:           "NotFound",
+            id:             999,
+            data:           map[string]interface{}{"enc_payload": "abcd"},
+            expectedErr:    sdb.ErrNotFound,
+        }, {
+            name:           "EndsBeforeStarts",
+            id:             created.ID,
+            data:           map[string]interface{}{"ends_at": testStartsAt.Add(-time.Hour)},
+            expectedErr:    sdb.ErrInvalid,
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := store.Update(ctx, tc.id, tc.data)
+            if !errors.Is(err, tc.expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+    // Failed update must not leave partial changes
+    event, _ := store.Get(ctx, created.ID)
+    if event.EncPayload != "abcd" || !event.EndsAt.Equal(created.EndsAt) {
+        t.Errorf("Unexpected stored event after updates: %+v", event)
+    }
+}
+//}}} Update
+
+
+//{{{ Delete
+func Test_MemEventStore_Delete(t *testing.T) {
+    store := NewMemEventStore(nil)
+    created, err := store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event that will be deleted: %v", err)
+    }
+    for i, expectedErr := range []error{nil, sdb.ErrNotFound} {
+        t.Run(fmt.Sprintf("Attempt%d", i+1), func(t *testing.T) {
+            err := store.Delete(ctx, created.ID)
+            if !errors.Is(err, expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Delete
+
+
+//{{{ Soft deleted owner
+func Test_MemEventStore_SoftDeletedOwner(t *testing.T) {
+    users := cruduser.NewMemUserStore()
+    if err := users.Insert(ctx, smodels.User{
+        Username:   "test_event_owner",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }); err != nil {
+        t.Fatalf("Failed to create owner: %v", err)
+    }
+    store := NewMemEventStore(users)
+    created, err := store.Insert(ctx, newTestEventFn("test_event_owner"))
+    if err != nil {
+        t.Fatalf("Failed to create event: %v", err)
+    }
+    // Missing owner is missing reference, same as FK
+    if _, err = store.Insert(ctx, newTestEventFn("not_found")); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if err = users.Delete(ctx, "test_event_owner"); err != nil {
+        t.Fatalf("Failed to soft delete owner: %v", err)
+    }
+    // Soft deleted owner can't get new events, its events are hidden
+    if _, err = store.Insert(ctx, newTestEventFn("test_event_owner")); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if _, err = store.Get(ctx, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = store.Update(ctx, created.ID, map[string]interface{}{"enc_payload": "abcd"}); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = store.Delete(ctx, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    // Restored owner sees its events again, untouched
+    if err = users.Restore(ctx, "test_event_owner"); err != nil {
+        t.Fatalf("Failed to restore owner: %v", err)
+    }
+    if event, err := store.Get(ctx, created.ID); err != nil || event.EncPayload != testEncPayload {
+        t.Errorf("Wrong result after restore: %+v (%v)", event, err)
+    }
+}
+//}}} Soft deleted owner
@@ -0,0 +1,50 @@
+package crudevent
+import (
+    "context"
+    "database/sql"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+// Storage behind event endpoints, same contract as cruduser.UserStore.
+//  Errors are typed shareddb errors, check with errors.Is
+type EventStore interface {
+    Insert(ctx context.Context, event smodels.Event) (*smodels.Event, error)
+    Get(ctx context.Context, id int64) (*smodels.Event, error)
+    Update(ctx context.Context, id int64, data map[string]interface{}) error
+    Delete(ctx context.Context, id int64) error
+}
+
+
+//{{{ Postgres store
+type PgEventStore struct {
+    DB  *sql.DB
+}
+
+
+func NewPgEventStore(db *sql.DB) *PgEventStore {
+    return &PgEventStore{DB: db}
+}
+
+
+func (s *PgEventStore) Insert(ctx context.Context, event smodels.Event) (*smodels.Event, error) {
+    return InsertEvent(ctx, s.DB, event)
+}
+
+
+func (s *PgEventStore) Get(ctx context.Context, id int64) (*smodels.Event, error) {
+    return SelectEvent(ctx, s.DB, id)
+}
+
+
+func (s *PgEventStore) Update(ctx context.Context, id int64, data map[string]interface{}) error {
+    return UpdateEvent(ctx, s.DB, data, id)
+}
+
+
+func (s *PgEventStore) Delete(ctx context.Context, id int64) error {
+    return DeleteEvent(ctx, s.DB, id)
+}
+//}}} Postgres store
@@ -67,3 +67,9 @@ require (
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/FAH2S/diar4/src/shared/api => ../shared/api
+	github.com/FAH2S/diar4/src/shared/db => ../shared/db
+	github.com/FAH2S/diar4/src/shared/models => ../shared/models
+)
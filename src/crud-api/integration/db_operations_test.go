@@ -5,11 +5,14 @@ import (
     "strings"
     "reflect"
     "fmt"
+    "time"
 )
 import (
     _ "github.com/lib/pq"
     smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
     cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudentry "github.com/FAH2S/diar4/src/crud-api/entry"
 )
 
 
@@ -21,7 +24,7 @@ func Test_InsertUser(t *testing.T) {
     tests := []struct {
         name                string
         user                smodels.User
-        expectedStatusCode  int
+        expectedKind        error
         expectedError       error
     }{
         {
@@ -32,7 +35,7 @@ func Test_InsertUser(t *testing.T) {
                 Hash:       validHash,
                 EncSymkey:  validEncSymkey,
             },
-            expectedStatusCode: 201,
+            expectedKind:       nil,
             expectedError:      nil,
         }, {
             name:               "userAlreadyExists",
@@ -42,7 +45,7 @@ func Test_InsertUser(t *testing.T) {
                 Hash:       validHash,
                 EncSymkey:  validEncSymkey,
             },
-            expectedStatusCode: 409,
+            expectedKind:       sdb.ErrConflict,
             expectedError:      errors.New("user already exists"),
         }, {// Keep in mind this is DB constraint check not .validate (validate is endpoint lvl)
             name:               "unprocessableSalt",
@@ -52,7 +55,7 @@ func Test_InsertUser(t *testing.T) {
                 Hash:        validHash,
                 EncSymkey:  validEncSymkey,
             },
-            expectedStatusCode: 422,
+            expectedKind:       sdb.ErrInvalid,
             expectedError:      errors.New("violates check constraint \"users_salt_check\""),
         }, {// Keep in mind this is DB constraint check not .validate (validate is endpoint lvl)
             name:               "unprocessableHash",
@@ -62,7 +65,7 @@ func Test_InsertUser(t *testing.T) {
                 Hash:       "invalid_hash",
                 EncSymkey:  validEncSymkey,
             },
-            expectedStatusCode: 422,
+            expectedKind:       sdb.ErrInvalid,
             expectedError:      errors.New("violates check constraint \"users_hash_check\""),
         }, {// Keep in mind this is DB constraint check not .validate (validate is endpoint lvl)
             name:               "unprocessableEncSymkey",
@@ -72,16 +75,36 @@ func Test_InsertUser(t *testing.T) {
                 Hash:       validHash,
                 EncSymkey:  "",
             },
-            expectedStatusCode: 422,
+            expectedKind:       sdb.ErrInvalid,
             expectedError:      errors.New("violates check constraint \"users_enc_symkey_check\""),
+        }, {// Longest username allowed by model, CHECK must agree
+            name:               "username30Chars",
+            user:               smodels.User{
+                Username:   strings.Repeat("a", 30),
+                Salt:       validSalt,
+                Hash:       validHash,
+                EncSymkey:  validEncSymkey,
+            },
+            expectedKind:       nil,
+            expectedError:      nil,
+        }, {// Keep in mind this is DB constraint check not .validate (validate is endpoint lvl)
+            name:               "unprocessableUsername",
+            user:               smodels.User{
+                Username:   "ab",
+                Salt:       validSalt,
+                Hash:       validHash,
+                EncSymkey:  validEncSymkey,
+            },
+            expectedKind:       sdb.ErrInvalid,
+            expectedError:      errors.New("violates check constraint \"users_username_check\""),
         },
     }
 
     for _, tc := range tests{
         t.Run(tc.name, func(t *testing.T) {
-            actual, err := cruduser.InsertUser(db, tc.user)
-            if tc.expectedStatusCode != actual {
-                t.Errorf("\nExpected:\t%d\nGot:\t%d", tc.expectedStatusCode, actual)
+            err := cruduser.InsertUser(ctx, db, tc.user)
+            if tc.expectedKind != nil && !errors.Is(err, tc.expectedKind) {
+                t.Errorf("\nExpected:\t%v\nGot:\t%v", tc.expectedKind, err)
             }
 
             if (err == nil) != (tc.expectedError == nil) {
@@ -109,7 +132,7 @@ func Test_SelectUser(t *testing.T) {
         Hash:       validHash,
         EncSymkey:  validEncSymkey,
     }
-    _, err := cruduser.InsertUser(db, createUser)
+    err := cruduser.InsertUser(ctx, db, createUser)
     if err != nil {
         t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
     }
@@ -118,14 +141,15 @@ func Test_SelectUser(t *testing.T) {
     tests := []struct {
         name                string
         username            string
-        expectedStatusCode  int
+        fields              []string
+        expectedKind        error
         expectedError       error
         expectedData        *smodels.User
     }{
         {
             name:               "validInput",
             username:           "test_select_user1",
-            expectedStatusCode: 200,
+            expectedKind:       nil,
             expectedError:      nil,
             expectedData:       &smodels.User{
                 Username:   "test_select_user1",
@@ -133,10 +157,27 @@ func Test_SelectUser(t *testing.T) {
                 Hash:       validHash,
                 EncSymkey:  validEncSymkey,
             },
+        }, {
+            name:               "projection",
+            username:           "test_select_user1",
+            fields:             []string{"username", "enc_symkey"},
+            expectedKind:       nil,
+            expectedError:      nil,
+            expectedData:       &smodels.User{
+                Username:   "test_select_user1",
+                EncSymkey:  validEncSymkey,
+            },
+        }, {
+            name:               "unknownColumn",
+            username:           "test_select_user1",
+            fields:             []string{"password"},
+            expectedKind:       sdb.ErrUnknownColumn,
+            expectedError:      fmt.Errorf("unknown column used"),
+            expectedData:       nil,
         }, {
             name:               "notFound",
             username:           "not_found",
-            expectedStatusCode: 404,
+            expectedKind:       sdb.ErrNotFound,
             expectedError:      fmt.Errorf("user not found"),
             expectedData:       nil,
         },
@@ -145,9 +186,9 @@ func Test_SelectUser(t *testing.T) {
     // Iterate
     for _, tc := range tests{
         t.Run(tc.name, func(t *testing.T) {
-            statusCode, user, err := cruduser.SelectUser(db, tc.username)
-            if tc.expectedStatusCode != statusCode {
-                t.Errorf("\nExpected:\t%d\nGot:\t%d", tc.expectedStatusCode, statusCode)
+            user, err := cruduser.SelectUser(ctx, db, tc.username, tc.fields)
+            if tc.expectedKind != nil && !errors.Is(err, tc.expectedKind) {
+                t.Errorf("\nExpected:\t%v\nGot:\t%v", tc.expectedKind, err)
             }
 
             if (err == nil) != (tc.expectedError == nil) {
@@ -180,7 +221,7 @@ func Test_UpdateUser(t *testing.T) {
         Hash:       validHash,
         EncSymkey:  validEncSymkey,
     }
-    _, err := cruduser.InsertUser(db, createUser)
+    err := cruduser.InsertUser(ctx, db, createUser)
     if err != nil {
         t.Fatalf("Failed to create user that will be updated-ed: %v", err)
     }
@@ -188,7 +229,7 @@ func Test_UpdateUser(t *testing.T) {
         name                string
         data                map[string]interface{}
         username            string
-        expectedStatusCode  int
+        expectedKind        error
         expectedError       error
     }{
         {
@@ -198,7 +239,7 @@ func Test_UpdateUser(t *testing.T) {
                 "hash":"1111d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
             },
             username:           "test_update_user1",
-            expectedStatusCode: 200,
+            expectedKind:       nil,
             expectedError:      nil,
         }, {
             name:               "FailNonExistantField400",
@@ -206,7 +247,7 @@ func Test_UpdateUser(t *testing.T) {
                 "no_hash":"2222d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
             },
             username:           "test_update_user1",
-            expectedStatusCode: 400,
+            expectedKind:       sdb.ErrUnknownColumn,
             expectedError:      errors.New("unknown column used"),
         }, {
             name:               "FailNoUpdates404",
@@ -214,22 +255,22 @@ func Test_UpdateUser(t *testing.T) {
                 "hash":"2222d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
             },
             username:           "test_update_user99",
-            expectedStatusCode: 404,
+            expectedKind:       sdb.ErrNotFound,
             expectedError:      errors.New("no rows were affected"),
         }, {
             name:               "FailDataEmpty422",
             data:               map[string]interface{}{},
             username:           "test_update_user1",
-            expectedStatusCode: 422,
+            expectedKind:       sdb.ErrInvalid,
             expectedError:      errors.New("no fields to update"),
         },
     }
     //iterate
     for _, tc := range tests{
         t.Run(tc.name, func(t *testing.T) {
-            statusCode, err := cruduser.UpdateUser(db, tc.data, tc.username)
-            if tc.expectedStatusCode != statusCode {
-                t.Errorf("Wrong status code\nExpected:\t%d\nGot:\t\t%d", tc.expectedStatusCode, statusCode)
+            _, err := cruduser.UpdateUser(ctx, db, tc.data, tc.username, 0)
+            if tc.expectedKind != nil && !errors.Is(err, tc.expectedKind) {
+                t.Errorf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", tc.expectedKind, err)
             }
 
             if (err == nil) != (tc.expectedError == nil) {
@@ -246,6 +287,102 @@ func Test_UpdateUser(t *testing.T) {
 //}}} Update user
 
 
+//{{{ Update user version
+func Test_UpdateUserVersion(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    err := cruduser.InsertUser(ctx, db, smodels.User{
+        Username: "test_version_user1", Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey,
+    })
+    if err != nil {
+        t.Fatalf("Failed to create user that will be updated: %v", err)
+    }
+    user, err := cruduser.SelectUser(ctx, db, "test_version_user1", []string{"version"})
+    if err != nil || user.Version != 1 {
+        t.Fatalf("Expected initial version 1, got: %+v (%v)", user, err)
+    }
+    data := map[string]interface{}{"hash": validSalt}
+    tests := []struct {
+        name                string
+        username            string
+        expectedVersion     int64
+        returnedVersion     int64
+        expectedKind        error
+    }{
+        {"Matching",        "test_version_user1",   1,  2,  nil},
+        {"Stale",           "test_version_user1",   1,  0,  sdb.ErrStaleVersion},
+        {"Unconditional",   "test_version_user1",   0,  3,  nil},
+        {"NotFound",        "test_version_user99",  3,  0,  sdb.ErrNotFound},
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            version, err := cruduser.UpdateUser(ctx, db, data, tc.username, tc.expectedVersion)
+            if !errors.Is(err, tc.expectedKind) || (tc.expectedKind == nil && err != nil) {
+                t.Fatalf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", tc.expectedKind, err)
+            }
+            if version != tc.returnedVersion {
+                t.Errorf("Wrong version\nExpected:\t%d\nGot:\t\t%d", tc.returnedVersion, version)
+            }
+        })
+    }
+}
+//}}} Update user version
+
+
+//{{{ Rotate user credentials
+func Test_RotateUserCredentials(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    err := cruduser.InsertUser(ctx, db, smodels.User{
+        Username: "test_rotate_user1", Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey,
+    })
+    if err != nil {
+        t.Fatalf("Failed to create user that will be rotated: %v", err)
+    }
+    creds := smodels.UserCredentials{Salt: validHash, Hash: validSalt, EncSymkey: validEncSymkey}
+    invalid := creds
+    invalid.EncSymkey = "zz"
+    tests := []struct {
+        name                string
+        username            string
+        proof               string
+        creds               smodels.UserCredentials
+        expectedKind        error
+    }{
+        {"WrongProof",              "test_rotate_user1",    validSalt,  creds,      cruduser.ErrProofMismatch},
+        {"NotFound",                "test_rotate_user99",   validHash,  creds,      sdb.ErrNotFound},
+        {"CheckConstraint",         "test_rotate_user1",    validHash,  invalid,    sdb.ErrInvalid},
+        {"Rotate",                  "test_rotate_user1",    validHash,  creds,      nil},
+        {"OldProofAfterRotation",   "test_rotate_user1",    validHash,  creds,      cruduser.ErrProofMismatch},
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            rotatedAt, err := cruduser.RotateUserCredentials(ctx, db, tc.username, tc.proof, tc.creds)
+            if !errors.Is(err, tc.expectedKind) || (tc.expectedKind == nil && err != nil) {
+                t.Fatalf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", tc.expectedKind, err)
+            }
+            if (tc.expectedKind == nil) == rotatedAt.IsZero() {
+                t.Errorf("Wrong rotation time %v for error %v", rotatedAt, err)
+            }
+        })
+    }
+    // Failed attempts changed nothing, successful one replaced all three at once
+    user, err := cruduser.SelectUser(ctx, db, "test_rotate_user1",
+        []string{"salt", "hash", "enc_symkey", "version", "credentials_rotated_at"})
+    if err != nil {
+        t.Fatalf("Failed to select rotated user: %v", err)
+    }
+    if user.Salt != validHash || user.Hash != validSalt || user.Version != 2 || user.CredentialsRotatedAt == nil {
+        t.Errorf("Wrong rotated user: %+v", user)
+    }
+}
+//}}} Rotate user credentials
+
+
 //{{{ Delete user
 func Test_DeleteUser(t *testing.T) {
     validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
@@ -258,34 +395,34 @@ func Test_DeleteUser(t *testing.T) {
         Hash:       validHash,
         EncSymkey:  validEncSymkey,
     }
-    _, err := cruduser.InsertUser(db, createUser)
+    err := cruduser.InsertUser(ctx, db, createUser)
     if err != nil {
         t.Fatalf("Failed to create user that will be updated-ed: %v", err)
     }
     tests := []struct {
         name                string
         username            string
-        expectedStatusCode  int
+        expectedKind        error
         expectedError       error
     }{
         {
             name:               "SuccessDeleteUser",
             username:           "test_delete_user1",
-            expectedStatusCode: 200,
+            expectedKind:       nil,
             expectedError:      nil,
         }, {
             name:               "FailUserNotFound",
             username:           "test_delete_user1",
-            expectedStatusCode: 404,
+            expectedKind:       sdb.ErrNotFound,
             expectedError:      errors.New("DeleteUser: CheckRowsAffectedFn: no rows were affected"),
         },
     }
     // Iterate
     for _, tc := range tests{
         t.Run(tc.name, func(t *testing.T) {
-            statusCode, err := cruduser.DeleteUser(db, tc.username)
-            if tc.expectedStatusCode != statusCode {
-                t.Errorf("Wrong status code\nExpected:\t%d\nGot:\t\t%d", tc.expectedStatusCode, statusCode)
+            err := cruduser.DeleteUser(ctx, db, tc.username)
+            if tc.expectedKind != nil && !errors.Is(err, tc.expectedKind) {
+                t.Errorf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", tc.expectedKind, err)
             }
 
             if (err == nil) != (tc.expectedError == nil) {
@@ -301,3 +438,239 @@ func Test_DeleteUser(t *testing.T) {
 //}}} Delete user
 
 
+//{{{ Soft delete user
+func Test_SoftDeleteUser(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    user := smodels.User{
+        Username: "test_soft_delete1", Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey,
+    }
+    if err := cruduser.InsertUser(ctx, db, user); err != nil {
+        t.Fatalf("Failed to create user that will be deleted: %v", err)
+    }
+    existsFn := func() error {
+        _, err := cruduser.SelectUser(ctx, db, user.Username, []string{"username"})
+        return err
+    }
+    beforeDelete := time.Now().Add(-time.Minute)
+    steps := []struct {
+        name                string
+        call                func() error
+        expectedKind        error
+    }{
+        {"PurgeActive",     func() error { return cruduser.PurgeUser(ctx, db, user.Username) },     sdb.ErrNotFound},
+        {"RestoreActive",   func() error { return cruduser.RestoreUser(ctx, db, user.Username) },   sdb.ErrNotFound},
+        {"Delete",          func() error { return cruduser.DeleteUser(ctx, db, user.Username) },    nil},
+        {"SelectDeleted",   existsFn,                                                               sdb.ErrNotFound},
+        {"UpdateDeleted",   func() error {
+            _, err := cruduser.UpdateUser(ctx, db, map[string]interface{}{"hash": validSalt}, user.Username, 0)
+            return err
+        },                                                                                          sdb.ErrNotFound},
+        {"UsernameTaken",   func() error { return cruduser.InsertUser(ctx, db, user) },             sdb.ErrConflict},
+        {"Restore",         func() error { return cruduser.RestoreUser(ctx, db, user.Username) },   nil},
+        {"SelectRestored",  existsFn,                                                               nil},
+        {"DeleteAgain",     func() error { return cruduser.DeleteUser(ctx, db, user.Username) },    nil},
+        {"Purge",           func() error { return cruduser.PurgeUser(ctx, db, user.Username) },     nil},
+        {"UsernameFree",    func() error { return cruduser.InsertUser(ctx, db, user) },             nil},
+    }
+    // Iterate, steps build on each other
+    for _, step := range steps {
+        t.Run(step.name, func(t *testing.T) {
+            err := step.call()
+            if !errors.Is(err, step.expectedKind) || (step.expectedKind == nil && err != nil) {
+                t.Fatalf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", step.expectedKind, err)
+            }
+        })
+    }
+
+    // Retention, only rows deleted before cutoff are purged (minute of slack for DB clock)
+    countFn := func() int {
+        var count int
+        db.QueryRowContext(ctx, `SELECT count(*) FROM users WHERE username = $1`, user.Username).Scan(&count)
+        return count
+    }
+    if err := cruduser.DeleteUser(ctx, db, user.Username); err != nil {
+        t.Fatalf("Failed to delete user: %v", err)
+    }
+    if _, err := cruduser.PurgeDeletedUsers(ctx, db, beforeDelete); err != nil || countFn() != 1 {
+        t.Errorf("User deleted after cutoff should be kept (%v)", err)
+    }
+    purged, err := cruduser.PurgeDeletedUsers(ctx, db, time.Now().Add(time.Minute))
+    if err != nil || purged < 1 || countFn() != 0 {
+        t.Errorf("Expected purged deleted user, got: %d (%v)", purged, err)
+    }
+}
+//}}} Soft delete user
+
+
+
+
+//{{{ Rename user
+func Test_RenameUser(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    for _, username := range []string{"test_rename_user1", "test_rename_taken"} {
+        err := cruduser.InsertUser(ctx, db, smodels.User{
+            Username: username, Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey,
+        })
+        if err != nil {
+            t.Fatalf("Failed to create user that will be renamed: %v", err)
+        }
+    }
+    // Dependent row, must follow rename
+    entry, err := crudentry.InsertEntry(ctx, db, smodels.Entry{
+        Owner:      "test_rename_user1",
+        Ciphertext: "0c8fd825308df79b",
+        Nonce:      "00112233445566778899aabb",
+        Tag:        "00112233445566778899aabbccddeeff",
+    })
+    if err != nil {
+        t.Fatalf("Failed to insert entry of renamed user: %v", err)
+    }
+    tests := []struct {
+        name                string
+        oldUsername         string
+        newUsername         string
+        expectedKind        error
+    }{
+        {"Invalid",     "test_rename_user1",    "bad name",             sdb.ErrInvalid},
+        {"Taken",       "test_rename_user1",    "test_rename_taken",    sdb.ErrConflict},
+        {"NotFound",    "test_rename_user99",   "test_rename_user3",    sdb.ErrNotFound},
+        {"Rename",      "test_rename_user1",    "test_rename_user2",    nil},
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := cruduser.RenameUser(ctx, db, tc.oldUsername, tc.newUsername)
+            if !errors.Is(err, tc.expectedKind) || (tc.expectedKind == nil && err != nil) {
+                t.Errorf("Wrong error kind\nExpected:\t%v\nGot:\t\t%v", tc.expectedKind, err)
+            }
+        })
+    }
+    // Entry moved with its owner (ON UPDATE CASCADE)
+    moved, err := crudentry.SelectEntry(ctx, db, "test_rename_user2", entry.ID)
+    if err != nil || moved.Owner != "test_rename_user2" {
+        t.Errorf("Entry didn't follow renamed owner: %+v (%v)", moved, err)
+    }
+}
+//}}} Rename user
+
+
+//{{{ List users
+// Other tests share table, so only relative order of own users is checked
+func Test_ListUsers(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    own := map[string]bool{}
+    for _, username := range []string{"test_list_user_c", "test_list_user_a", "test_list_user_b"} {
+        err := cruduser.InsertUser(ctx, db, smodels.User{
+            Username: username, Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey,
+        })
+        if err != nil {
+            t.Fatalf("Failed to create user that will be listed: %v", err)
+        }
+        own[username] = true
+    }
+    for _, order := range cruduser.UserListOrders {
+        t.Run(order, func(t *testing.T) {
+            // Walk every page of 2, keyset continues from last row
+            var (
+                after   *smodels.UserListItem
+                all     []smodels.UserListItem
+            )
+            for {
+                page, err := cruduser.ListUsers(ctx, db, order, after, 2)
+                if err != nil {
+                    t.Fatalf("Failed to list users: %v", err)
+                }
+                all = append(all, page...)
+                if len(page) < 2 {
+                    break
+                }
+                after = &page[len(page)-1]
+            }
+            // Every row exactly once, strictly increasing
+            var got []string
+            for i, user := range all {
+                if own[user.Username] {
+                    got = append(got, user.Username)
+                }
+                if i == 0 {
+                    continue
+                }
+                prev := all[i-1]
+                increasing := prev.Username < user.Username
+                if order == "created_at" {
+                    increasing = prev.CreatedAt.Before(user.CreatedAt) ||
+                        (prev.CreatedAt.Equal(user.CreatedAt) && prev.Username < user.Username)
+                }
+                if !increasing {
+                    t.Errorf("Rows out of %s order: %+v then %+v", order, prev, user)
+                }
+            }
+            expected := []string{"test_list_user_a", "test_list_user_b", "test_list_user_c"}
+            if order == "created_at" {
+                expected = []string{"test_list_user_c", "test_list_user_a", "test_list_user_b"}
+            }
+            if !reflect.DeepEqual(got, expected) {
+                t.Errorf("Wrong users:\nExpected:\t%v\nGot:\t\t%v", expected, got)
+            }
+        })
+    }
+    // Order is never taken from input as SQL
+    if _, err := cruduser.ListUsers(ctx, db, "hash; DROP TABLE users", nil, 2); !errors.Is(err, sdb.ErrUnknownColumn) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrUnknownColumn, err)
+    }
+}
+//}}} List users
+
+
+//{{{ Batch users
+func Test_BatchUsers(t *testing.T) {
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    userFn := func(username string) smodels.User {
+        return smodels.User{Username: username, Salt: validSalt, Hash: validHash, EncSymkey: validEncSymkey}
+    }
+    existsFn := func(username string) bool {
+        _, err := cruduser.SelectUser(ctx, db, username, []string{"username"})
+        return err == nil
+    }
+    if err := cruduser.InsertUser(ctx, db, userFn("test_batch_seed")); err != nil {
+        t.Fatalf("Failed to create seed user: %v", err)
+    }
+
+    // Atomic, conflict on 2nd op rolls back 1st
+    ops := []cruduser.UserOp{
+        {Kind: cruduser.UserOpCreate, User: userFn("test_batch_atomic")},
+        {Kind: cruduser.UserOpCreate, User: userFn("test_batch_seed")},
+    }
+    opErrs, err := cruduser.BatchUsers(ctx, db, ops, true)
+    if err != nil || !errors.Is(opErrs[0], sdb.ErrRolledBack) || !errors.Is(opErrs[1], sdb.ErrConflict) {
+        t.Errorf("Wrong atomic result: %v (%v)", opErrs, err)
+    }
+    if existsFn("test_batch_atomic") {
+        t.Errorf("Atomic batch should be rolled back")
+    }
+
+    // Partial, failed ops are rolled back to savepoint, rest is committed
+    ops = []cruduser.UserOp{
+        {Kind: cruduser.UserOpCreate, User: userFn("test_batch_partial")},
+        {Kind: cruduser.UserOpCreate, User: userFn("test_batch_seed")},
+        {Kind: cruduser.UserOpUpdate, Username: "test_batch_seed", Data: map[string]interface{}{"salt": "zz"}},
+        {Kind: cruduser.UserOpDelete, Username: "test_batch_seed"},
+    }
+    opErrs, err = cruduser.BatchUsers(ctx, db, ops, false)
+    if err != nil || opErrs[0] != nil || !errors.Is(opErrs[1], sdb.ErrConflict) ||
+        !errors.Is(opErrs[2], sdb.ErrInvalid) || opErrs[3] != nil {
+        t.Errorf("Wrong partial result: %v (%v)", opErrs, err)
+    }
+    if !existsFn("test_batch_partial") || existsFn("test_batch_seed") {
+        t.Errorf("Partial batch should create test_batch_partial and delete test_batch_seed")
+    }
+}
+//}}} Batch users
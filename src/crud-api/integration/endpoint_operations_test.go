@@ -1,61 +1,18 @@
 package integration
 import (
     "testing"
-    "encoding/json"
     "net/http"
     "net/http/httptest"
     "strings"
-    "reflect"
     "fmt"
 )
 import (
-    sapi "github.com/FAH2S/diar4/src/shared/api"
     smodels "github.com/FAH2S/diar4/src/shared/models"
     cruduser "github.com/FAH2S/diar4/src/crud-api/user"
     crudmiddleware "github.com/FAH2S/diar4/src/crud-api/middleware"
+    "github.com/FAH2S/diar4/src/crud-api/internal/testutil"
 )
 
-//{{{ DRY
-type EndpointTestCase struct {
-    Name                string
-    Body                string
-    ExpectedStatusCode  int
-    ExpectedMessage     string
-    ExpectedError       string
-    ExpectedData        interface{}
-}
-
-
-func assertResponse(
-    t *testing.T,
-    resp *httptest.ResponseRecorder,
-    tc EndpointTestCase,
-){
-    var bodyResp sapi.APIResponse
-    if err := json.Unmarshal(resp.Body.Bytes(), &bodyResp); err != nil {
-        t.Fatalf("Failed to parse JSON response as APIResponse: %v", err)
-    }
-    // status code
-    if tc.ExpectedStatusCode != resp.Result().StatusCode {
-        t.Errorf("Unexpeted status code:\nGot:\t%d\nWant:\t%d", resp.Result().StatusCode, tc.ExpectedStatusCode)
-    }
-    // msg
-    if !strings.Contains(bodyResp.Message, tc.ExpectedMessage) {
-        t.Errorf("Unexpected message:\nGot:\t%s\nWant:\t%s", bodyResp.Message, tc.ExpectedMessage)
-    }
-    // err
-    if !strings.Contains(bodyResp.Error, tc.ExpectedError) {
-        t.Errorf("Unexpected error:\nGot:\t%s\nWant:\t%s", bodyResp.Error, tc.ExpectedError)
-    }
-    // data
-    if !reflect.DeepEqual(bodyResp.Data, tc.ExpectedData) {
-        t.Errorf("Unexpected data:\nGot:\t%v\nWant:\t%v", bodyResp.Data, tc.ExpectedData)
-    }
-
-}
-//}}} DRY
-
-
 //{{{ Middleware
 type MiddlewareTestCase struct {
     Name                string
@@ -84,8 +41,11 @@ func Test_MiddlewareEndpoint_MethodFail(t *testing.T){
     // Call hander to simulate req and resp
     handler.ServeHTTP(resp, req)
     // Check result
-    if resp.Code != 400 {
-        t.Errorf("Unexpeted status code:\nGot:\t%d\nWant:\t%d", resp.Code, 400)
+    if resp.Code != 405 {
+        t.Errorf("Unexpeted status code:\nGot:\t%d\nWant:\t%d", resp.Code, 405)
+    }
+    if allow := resp.Header().Get("Allow"); allow != "POST" {
+        t.Errorf("Unexpeted Allow header:\nGot:\t%q\nWant:\t%q", allow, "POST")
     }
 }
 
@@ -116,7 +76,7 @@ func Test_CreateUserEndpoint(t *testing.T){
     validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
     validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
     validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
-    tests := []EndpointTestCase{
+    tests := []testutil.EndpointTestCase{
         {
             Name:               "CreateUser",
             Body:               fmt.Sprintf(`{
@@ -163,7 +123,7 @@ func Test_CreateUserEndpoint(t *testing.T){
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: create user 'fishy user |._.|><|'",
             ExpectedError:      "Invalid input format: username: contains invalid characters",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"username", "username_chars", "^[a-zA-Z0-9_]+$", "username: contains invalid characters"}),
         }, {
             Name:               "UnprocessableSalt",
             Body:               fmt.Sprintf(`{
@@ -175,7 +135,7 @@ func Test_CreateUserEndpoint(t *testing.T){
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
             ExpectedError:      "Invalid input format: salt: length must be exactly 64 char long",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"}),
         }, {
             Name:               "UnprocessableHash",
             Body:               fmt.Sprintf(`{
@@ -187,7 +147,7 @@ func Test_CreateUserEndpoint(t *testing.T){
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
             ExpectedError:      "Invalid input format: hash: contains invalid characters",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"hash", "hex_chars", "^[0-9a-fA-F]+$", "hash: contains invalid characters"}),
         }, {
             Name:               "UnprocessableEncSymkey",
             Body:               fmt.Sprintf(`{
@@ -199,7 +159,7 @@ func Test_CreateUserEndpoint(t *testing.T){
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
             ExpectedError:      "Invalid input format: enc_symkey: length must be exactly 120 char long",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"}),
         },
     }
 
@@ -210,9 +170,9 @@ func Test_CreateUserEndpoint(t *testing.T){
             req := httptest.NewRequest("POST", "/create/user", strings.NewReader(tc.Body))
             resp := httptest.NewRecorder()
             // Call endpoint
-            cruduser.CreateUserEndpoint(resp, req, db)
+            userHandler.CreateUserEndpoint(resp, req)
             // Check
-            assertResponse(t, resp, tc)
+            testutil.AssertResponse(t, resp, tc)
         })
     }
 }
@@ -229,13 +189,13 @@ func Test_ReadUserEndpoint(t *testing.T){
         Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
         EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
     }
-    _, err := cruduser.InsertUser(db, user)
+    err := cruduser.InsertUser(ctx, db, user)
     if err != nil {
         t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
     }
 
     // Define tests and its expected results
-    tests := []EndpointTestCase{
+    tests := []testutil.EndpointTestCase{
         {
             Name:               "ReadUser",
             Body:               fmt.Sprintf(`{"username":"%s"}`, username),
@@ -245,9 +205,21 @@ func Test_ReadUserEndpoint(t *testing.T){
             ExpectedData:       map[string]any{
                 "username":username,
                 "salt":user.Salt,
-                "hash":user.Hash,
-                "enc_symkey":user.EncSymkey,
             },
+        },{
+            Name:               "ReadUserFields",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["salt"]}`, username),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: read user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"salt":user.Salt},
+        },{
+            Name:               "SecretFieldHash",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["hash"]}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+            ExpectedError:      "Invalid input format: fields: field \"hash\" can't be read",
+            ExpectedData:       nil,
         },{
             Name:               "MalformedJSON",
             Body:               fmt.Sprintf(`{"username":"%s`, username),
@@ -279,9 +251,9 @@ func Test_ReadUserEndpoint(t *testing.T){
             req := httptest.NewRequest("POST", "/read/user", strings.NewReader(tc.Body))
             resp := httptest.NewRecorder()
             // Call endpoint
-            cruduser.ReadUserEndpoint(resp, req, db)
+            userHandler.ReadUserEndpoint(resp, req)
             // Check
-            assertResponse(t, resp, tc)
+            testutil.AssertResponse(t, resp, tc)
         })
     }
 }
@@ -299,17 +271,23 @@ func Test_UpdateUserEndpoint(t *testing.T){
         Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
         EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
     }
-    _, err := cruduser.InsertUser(db, user)
+    err := cruduser.InsertUser(ctx, db, user)
     if err != nil {
         t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
     }
-    tests := []EndpointTestCase{
+    // Credentials are set all together, partial set is refused
+    newSalt := "111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    credsFn := func(salt, hash, encSymkey string) string {
+        return fmt.Sprintf(`"salt":"%s","hash":"%s","enc_symkey":"%s"`, salt, hash, encSymkey)
+    }
+    requiredFn := func(field string) [4]string {
+        return [4]string{field, "required_together", "salt+hash+enc_symkey",
+            fmt.Sprintf("field %q is required, salt, hash and enc_symkey change together", field)}
+    }
+    tests := []testutil.EndpointTestCase{
         {
             Name:               "UpdateUser",
-            Body:               `{
-                "username":"test_user_update1",
-                "salt":"111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
-            }`,
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(newSalt, user.Salt, user.EncSymkey)),
             ExpectedStatusCode: 200,
             ExpectedMessage:    "Success: update user 'test_user_update1'",
             ExpectedError:      "",
@@ -328,49 +306,43 @@ func Test_UpdateUserEndpoint(t *testing.T){
             ExpectedData:       nil,
         }, {
             Name:               "UpdateUserNotFound",
-            Body:               fmt.Sprintf(`{
-                "username":"test_user_update",
-                "salt":"111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
-            }`),
+            Body:               fmt.Sprintf(`{"username":"test_user_update",%s}`, credsFn(newSalt, user.Salt, user.EncSymkey)),
             ExpectedStatusCode: 404,
             ExpectedMessage:    "Fail: update user 'test_user_update'",
             ExpectedError:      "User not found, dosen't exist",
             ExpectedData:       nil,
+        }, {
+            Name:               "PartialCredentialsSalt",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1","salt":"%s"}`, user.Salt),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      `Invalid input format: field "hash" is required, salt, hash and enc_symkey change together; ` +
+                `field "enc_symkey" is required, salt, hash and enc_symkey change together`,
+            ExpectedData:       testutil.ValidationDataFn(requiredFn("hash"), requiredFn("enc_symkey")),
         }, {
             Name:               "InvalidInputFormatSalt",
-            Body:               fmt.Sprintf(`{
-                "username":"test_user_update1",
-                "salt":"111feecf"
-            }`),
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn("111feecf", user.Hash, user.EncSymkey)),
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: update user ''",
             ExpectedError:      "Invalid input format: salt",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"}),
         }, {
             Name:               "InvalidInputFormatHash",
-            Body:               fmt.Sprintf(`{
-                "username":"test_user_update1",
-                "hash":"111feecf"
-            }`),
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(user.Salt, "111feecf", user.EncSymkey)),
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: update user ''",
             ExpectedError:      "Invalid input format: hash",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"hash", "hex_length", "64 chars", "hash: length must be exactly 64 char long"}),
         }, {
             Name:               "InvalidInputFormatEncSymkey",
-            Body:               fmt.Sprintf(`{
-                "username":"test_user_update1",
-                "enc_symkey":"111feecf"
-            }`),
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(user.Salt, user.Hash, "111feecf")),
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: update user ''",
             ExpectedError:      "Invalid input format: enc_symkey",
-            ExpectedData:       nil,
+            ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"}),
         }, {
             Name:               "MissingUsername",
-            Body:               fmt.Sprintf(`{
-                "salt":"111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
-            }`),
+            Body:               fmt.Sprintf(`{%s}`, credsFn(newSalt, user.Salt, user.EncSymkey)),
             ExpectedStatusCode: 422,
             ExpectedMessage:    "Fail: update user ''",
             ExpectedError:      "Missing required field: 'username'",
@@ -394,11 +366,16 @@ func Test_UpdateUserEndpoint(t *testing.T){
             req := httptest.NewRequest("POST", "/update/user", strings.NewReader(tc.Body))
             resp := httptest.NewRecorder()
             // Call endpoint
-            cruduser.UpdateUserEndpoint(resp, req, db)
+            userHandler.UpdateUserEndpoint(resp, req)
             // Check
-            assertResponse(t, resp, tc)
+            testutil.AssertResponse(t, resp, tc)
         })
     }
+    // Only first update was applied, refused ones left credentials as they were
+    stored, err := cruduser.SelectUser(ctx, db, username, nil)
+    if err != nil || stored.Salt != newSalt || stored.Hash != user.Salt || stored.EncSymkey != user.EncSymkey {
+        t.Errorf("Wrong credentials after updates:\nGot:\t%+v (%v)", stored, err)
+    }
 }
 
 //}}} UpdateUserEndpoint
@@ -414,12 +391,12 @@ func Test_DeleteUserEndpoint(t *testing.T){
         Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
         EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
     }
-    _, err := cruduser.InsertUser(db, user)
+    err := cruduser.InsertUser(ctx, db, user)
     if err != nil {
         t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
     }
     // Define tests and its expected results
-    tests := []EndpointTestCase{
+    tests := []testutil.EndpointTestCase{
         {
             Name:               "DeleteUser",
             Body:               fmt.Sprintf(`{"username":"%s"}`, username),
@@ -457,9 +434,9 @@ func Test_DeleteUserEndpoint(t *testing.T){
             req := httptest.NewRequest("POST", "/delete/user", strings.NewReader(tc.Body))
             resp := httptest.NewRecorder()
             // Call endpoint
-            cruduser.DeleteUserEndpoint(resp, req, db)
+            userHandler.DeleteUserEndpoint(resp, req)
             // Check
-            assertResponse(t, resp, tc)
+            testutil.AssertResponse(t, resp, tc)
         })
     }
 }
@@ -467,3 +444,59 @@ func Test_DeleteUserEndpoint(t *testing.T){
 //}}} DeleteUserEndpoint
 
 
+
+
+//{{{ VerifyUserEndpoint
+func Test_VerifyUserEndpoint(t *testing.T){
+    // Create some user that will be verified
+    username := "test_user_verify1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := cruduser.InsertUser(ctx, db, user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be verified: %v", err)
+    }
+    // Define tests and its expected results
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "VerifyUser",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, user.Hash),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: verify user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"username":username, "enc_symkey":user.EncSymkey},
+        },{
+            Name:               "WrongProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, user.Salt),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    fmt.Sprintf("Fail: verify user '%s'", username),
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownUser",
+            Body:               fmt.Sprintf(`{"username":"not_found","proof":"%s"}`, user.Hash),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    "Fail: verify user 'not_found'",
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/verify/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            userHandler.VerifyUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+}
+
+//}}} VerifyUserEndpoint
@@ -0,0 +1,109 @@
+package integration
+import (
+    "testing"
+    "errors"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudentry "github.com/FAH2S/diar4/src/crud-api/entry"
+)
+
+
+//{{{ Entry operations
+func Test_EntryOperations(t *testing.T) {
+    owner := smodels.User{
+        Username:   "test_entry_owner1",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    if err := cruduser.InsertUser(ctx, db, owner); err != nil {
+        t.Fatalf("Failed to create entry owner: %v", err)
+    }
+    entry := smodels.Entry{
+        Owner:      owner.Username,
+        Ciphertext: "0c8fd825308df79b",
+        Nonce:      "00112233445566778899aabb",
+        Tag:        "00112233445566778899aabbccddeeff",
+    }
+
+    // Insert 3, list in pages of 2
+    var ids []int64
+    for i := 0; i < 3; i++ {
+        created, err := crudentry.InsertEntry(ctx, db, entry)
+        if err != nil {
+            t.Fatalf("Failed to insert entry: %v", err)
+        }
+        ids = append(ids, created.ID)
+    }
+    page, err := crudentry.ListEntries(ctx, db, owner.Username, 0, 2)
+    if err != nil || len(page) != 2 || page[0].ID != ids[0] || page[1].ID != ids[1] {
+        t.Errorf("Wrong first page: %+v (%v)", page, err)
+    }
+    page, err = crudentry.ListEntries(ctx, db, owner.Username, ids[1], 2)
+    if err != nil || len(page) != 1 || page[0].ID != ids[2] {
+        t.Errorf("Wrong second page: %+v (%v)", page, err)
+    }
+
+    // Scoped by owner
+    if _, err = crudentry.SelectEntry(ctx, db, "someone_else", ids[0]); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+
+    // Nonce CHECK
+    err = crudentry.UpdateEntry(ctx, db, map[string]interface{}{"nonce": "zz"}, owner.Username, ids[0])
+    if !errors.Is(err, sdb.ErrInvalid) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrInvalid, err)
+    }
+
+    // Missing owner is missing reference, same as FK violation
+    orphan := entry
+    orphan.Owner = "not_found"
+    if _, err = crudentry.InsertEntry(ctx, db, orphan); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+
+    // Purging owner cascades to entries, soft delete keeps them
+    if err = cruduser.DeleteUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to delete owner: %v", err)
+    }
+    // Soft deleted owner hides its entries and can't get new ones
+    if _, err = crudentry.SelectEntry(ctx, db, owner.Username, ids[0]); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    page, err = crudentry.ListEntries(ctx, db, owner.Username, 0, 10)
+    if err != nil || len(page) != 0 {
+        t.Errorf("Entries of soft deleted owner should be hidden: %+v (%v)", page, err)
+    }
+    err = crudentry.UpdateEntry(ctx, db, map[string]interface{}{"tag": entry.Tag}, owner.Username, ids[0])
+    if !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = crudentry.DeleteEntry(ctx, db, owner.Username, ids[0]); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if _, err = crudentry.InsertEntry(ctx, db, entry); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    // Restore makes them visible again
+    if err = cruduser.RestoreUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to restore owner: %v", err)
+    }
+    page, err = crudentry.ListEntries(ctx, db, owner.Username, 0, 10)
+    if err != nil || len(page) != 3 {
+        t.Errorf("Wrong page after restore: %+v (%v)", page, err)
+    }
+    if err = cruduser.DeleteUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to delete owner: %v", err)
+    }
+    if err = cruduser.PurgeUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to purge owner: %v", err)
+    }
+    page, err = crudentry.ListEntries(ctx, db, owner.Username, 0, 10)
+    if err != nil || len(page) != 0 {
+        t.Errorf("Entries should be deleted with owner: %+v (%v)", page, err)
+    }
+}
+//}}} Entry operations
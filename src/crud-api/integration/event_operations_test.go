@@ -0,0 +1,97 @@
+package integration
+import (
+    "testing"
+    "errors"
+    "time"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudevent "github.com/FAH2S/diar4/src/crud-api/event"
+)
+
+
+//{{{ Event operations
+func Test_EventOperations(t *testing.T) {
+    owner := smodels.User{
+        Username:   "test_event_owner1",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    if err := cruduser.InsertUser(ctx, db, owner); err != nil {
+        t.Fatalf("Failed to create event owner: %v", err)
+    }
+    startsAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+    event := smodels.Event{
+        Owner:      owner.Username,
+        EncPayload: "0c8fd825308df79b",
+        StartsAt:   startsAt,
+        EndsAt:     startsAt.Add(time.Hour),
+    }
+
+    // Insert, id + timestamps from DB
+    created, err := crudevent.InsertEvent(ctx, db, event)
+    if err != nil || created.ID < 1 || created.CreatedAt.IsZero() {
+        t.Fatalf("Failed to insert event: %+v (%v)", created, err)
+    }
+
+    // Select
+    got, err := crudevent.SelectEvent(ctx, db, created.ID)
+    if err != nil || got.Owner != owner.Username || !got.StartsAt.Equal(startsAt) {
+        t.Errorf("Wrong result:\nExpected:\t%+v\nGot:\t\t%+v (%v)", created, got, err)
+    }
+
+    // Update, CHECK violation is ErrInvalid
+    err = crudevent.UpdateEvent(ctx, db, map[string]interface{}{"ends_at": startsAt.Add(-time.Hour)}, created.ID)
+    if !errors.Is(err, sdb.ErrInvalid) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrInvalid, err)
+    }
+    err = crudevent.UpdateEvent(ctx, db, map[string]interface{}{"enc_payload": "abcd"}, created.ID)
+    if err != nil {
+        t.Errorf("Failed to update event: %v", err)
+    }
+    err = crudevent.UpdateEvent(ctx, db, map[string]interface{}{"enc_payload": "abcd"}, 999999)
+    if !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+
+    // Delete
+    if err = crudevent.DeleteEvent(ctx, db, created.ID); err != nil {
+        t.Errorf("Failed to delete event: %v", err)
+    }
+    if _, err = crudevent.SelectEvent(ctx, db, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+
+    // Purging owner cascades to events, soft delete keeps them
+    cascaded, err := crudevent.InsertEvent(ctx, db, event)
+    if err != nil {
+        t.Fatalf("Failed to insert event: %v", err)
+    }
+    if err = cruduser.DeleteUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to delete owner: %v", err)
+    }
+    // Soft deleted owner hides its events and can't get new ones
+    if _, err = crudevent.SelectEvent(ctx, db, cascaded.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    err = crudevent.UpdateEvent(ctx, db, map[string]interface{}{"enc_payload": "abcd"}, cascaded.ID)
+    if !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = crudevent.DeleteEvent(ctx, db, cascaded.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if _, err = crudevent.InsertEvent(ctx, db, event); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if err = cruduser.PurgeUser(ctx, db, owner.Username); err != nil {
+        t.Fatalf("Failed to purge owner: %v", err)
+    }
+    if _, err = crudevent.SelectEvent(ctx, db, cascaded.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Event should be deleted with owner, got: %v", err)
+    }
+}
+//}}} Event operations
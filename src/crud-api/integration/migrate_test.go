@@ -0,0 +1,74 @@
+package integration
+import (
+    "testing"
+    "sync"
+    "reflect"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    crudmigrations "github.com/FAH2S/diar4/src/crud-api/migrations"
+)
+
+
+//{{{ Migrations
+func Test_Migrations(t *testing.T) {
+    migrations, err := sdb.LoadMigrationsFn(crudmigrations.FS)
+    if err != nil {
+        t.Fatalf("Failed to load migrations: %v", err)
+    }
+
+    // TestMain already applied everything, so status is all applied
+    states, err := sdb.GetMigrationStatus(ctx, db, migrations)
+    if err != nil {
+        t.Fatalf("Failed to get status: %v", err)
+    }
+    for _, state := range states {
+        if !state.Applied {
+            t.Errorf("Migration %d_%s not applied", state.Version, state.Name)
+        }
+    }
+
+    // Extra throwaway migration so down doesn't touch real schema
+    extended := append(append([]sdb.Migration{}, migrations...), sdb.Migration{
+        Version:    9999,
+        Name:       "test_table",
+        Up:         `CREATE TABLE migrate_test (id INT);`,
+        Down:       `DROP TABLE migrate_test;`,
+    })
+
+    // Concurrent runners, advisory lock must serialize them, 9999 applied once
+    var (
+        wg      sync.WaitGroup
+        mu      sync.Mutex
+        applied []int64
+    )
+    for i := 0; i < 3; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            done, err := sdb.MigrateUp(ctx, db, extended)
+            if err != nil {
+                t.Errorf("Concurrent up failed: %v", err)
+            }
+            mu.Lock()
+            applied = append(applied, done...)
+            mu.Unlock()
+        }()
+    }
+    wg.Wait()
+    if !reflect.DeepEqual(applied, []int64{9999}) {
+        t.Errorf("Wrong applied:\nExpected:\t%v\nGot:\t\t%v", []int64{9999}, applied)
+    }
+
+    // Down reverts only last one
+    reverted, err := sdb.MigrateDown(ctx, db, extended, 1)
+    if err != nil || !reflect.DeepEqual(reverted, []int64{9999}) {
+        t.Errorf("Wrong reverted:\nExpected:\t%v\nGot:\t\t%v (%v)", []int64{9999}, reverted, err)
+    }
+    var exists bool
+    err = db.QueryRowContext(ctx, `SELECT to_regclass('migrate_test') IS NOT NULL`).Scan(&exists)
+    if err != nil || exists {
+        t.Errorf("Table migrate_test should be dropped, exists: %v (%v)", exists, err)
+    }
+}
+//}}} Migrations
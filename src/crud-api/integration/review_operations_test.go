@@ -0,0 +1,109 @@
+package integration
+import (
+    "testing"
+    "errors"
+    "time"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudevent "github.com/FAH2S/diar4/src/crud-api/event"
+    crudreview "github.com/FAH2S/diar4/src/crud-api/review"
+)
+
+
+//{{{ Review operations
+func Test_ReviewOperations(t *testing.T) {
+    author := smodels.User{
+        Username:   "test_review_author1",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    if err := cruduser.InsertUser(ctx, db, author); err != nil {
+        t.Fatalf("Failed to create author: %v", err)
+    }
+    startsAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+    event, err := crudevent.InsertEvent(ctx, db, smodels.Event{
+        Owner:      author.Username,
+        EncPayload: "abcd",
+        StartsAt:   startsAt,
+        EndsAt:     startsAt.Add(time.Hour),
+    })
+    if err != nil {
+        t.Fatalf("Failed to create event: %v", err)
+    }
+    review := smodels.Review{Author: author.Username, EventID: event.ID, Rating: 4, EncBody: "abcd"}
+
+    // Insert
+    created, err := crudreview.InsertReview(ctx, db, review)
+    if err != nil || created.ID < 1 {
+        t.Fatalf("Failed to insert review: %+v (%v)", created, err)
+    }
+
+    // One review per author per event
+    _, err = crudreview.InsertReview(ctx, db, review)
+    if !errors.Is(err, sdb.ErrConflict) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrConflict, err)
+    }
+
+    // Missing event is ErrMissingReference naming event_id, same as FK violation
+    missing := review
+    missing.EventID = event.ID + 1000
+    _, err = crudreview.InsertReview(ctx, db, missing)
+    var dbErr *sdb.DBError
+    if !errors.Is(err, sdb.ErrMissingReference) || !errors.As(err, &dbErr) || dbErr.Column != "event_id" {
+        t.Errorf("Wrong error:\nExpected:\t%v (event_id)\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+
+    // Rating CHECK
+    err = crudreview.UpdateReview(ctx, db, map[string]interface{}{"rating": 6}, created.ID)
+    if !errors.Is(err, sdb.ErrInvalid) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrInvalid, err)
+    }
+    err = crudreview.UpdateReview(ctx, db, map[string]interface{}{"rating": 1}, created.ID)
+    if err != nil {
+        t.Errorf("Failed to update review: %v", err)
+    }
+    got, err := crudreview.SelectReview(ctx, db, created.ID)
+    if err != nil || got.Rating != 1 || got.EventID != event.ID {
+        t.Errorf("Wrong result: %+v (%v)", got, err)
+    }
+
+    // Soft deleted author hides its reviews and can't review, restore shows them again
+    if err = cruduser.DeleteUser(ctx, db, author.Username); err != nil {
+        t.Fatalf("Failed to delete author: %v", err)
+    }
+    if _, err = crudreview.SelectReview(ctx, db, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    err = crudreview.UpdateReview(ctx, db, map[string]interface{}{"rating": 2}, created.ID)
+    if !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = crudreview.DeleteReview(ctx, db, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    second := review
+    second.Rating = 5
+    _, err = crudreview.InsertReview(ctx, db, second)
+    if !errors.Is(err, sdb.ErrMissingReference) || !errors.As(err, &dbErr) || dbErr.Column != "author" {
+        t.Errorf("Wrong error:\nExpected:\t%v (author)\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    if err = cruduser.RestoreUser(ctx, db, author.Username); err != nil {
+        t.Fatalf("Failed to restore author: %v", err)
+    }
+    if _, err = crudreview.SelectReview(ctx, db, created.ID); err != nil {
+        t.Errorf("Review should be visible after restore, got: %v", err)
+    }
+
+    // Deleting event cascades to its reviews
+    if err = crudevent.DeleteEvent(ctx, db, event.ID); err != nil {
+        t.Fatalf("Failed to delete event: %v", err)
+    }
+    if _, err = crudreview.SelectReview(ctx, db, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Review should be deleted with event, got: %v", err)
+    }
+}
+//}}} Review operations
@@ -5,18 +5,20 @@ import (
     "os"
     "database/sql"
     "context"
-    "path/filepath"
 )
 import (
     "github.com/testcontainers/testcontainers-go/wait"
     "github.com/testcontainers/testcontainers-go"
     sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudmigrations "github.com/FAH2S/diar4/src/crud-api/migrations"
 )
 
 
 var postgresContainer testcontainers.Container
 var ctx context.Context
 var db *sql.DB
+var userHandler *cruduser.UserHandler
 
 
 //{{{ helper
@@ -41,11 +43,6 @@ func initialzeDbTestEnv() error {
 
 
 func startPostgresContainer(ctx context.Context) (testcontainers.Container, error) {
-    initFilePath, err := filepath.Abs("../../db/init.sql")
-    if err != nil {
-        return nil, fmt.Errorf("Failed to get absolute path of init.sql: %w", err)
-    }
-
     req := testcontainers.ContainerRequest{
         Image:        "postgres:15",
         ExposedPorts: []string{"5432/tcp"},
@@ -54,9 +51,6 @@ func startPostgresContainer(ctx context.Context) (testcontainers.Container, erro
             "POSTGRES_PASSWORD": "testpass",
             "POSTGRES_DB":       "testdb",
         },
-        Mounts: testcontainers.Mounts(
-            testcontainers.BindMount(initFilePath, "/docker-entrypoint-initdb.d/init.sql"),
-        ),
         WaitingFor: wait.ForListeningPort("5432/tcp"),
     }
     // testcontainers.GenericContainer retrurns container + error on its own no need 
@@ -87,6 +81,15 @@ func TestMain(m *testing.M) {
     if err != nil {
         panic(fmt.Errorf("Failed to initialize db conn: %w", err))
     }
+    // Schema comes from embedded migrations, same as production
+    migrations, err := sdb.LoadMigrationsFn(crudmigrations.FS)
+    if err != nil {
+        panic(fmt.Errorf("Failed to load migrations: %w", err))
+    }
+    if _, err = sdb.MigrateUp(ctx, db, migrations); err != nil {
+        panic(fmt.Errorf("Failed to apply migrations: %w", err))
+    }
+    userHandler = cruduser.NewUserHandler(cruduser.NewPgUserStore(db))
     // Run tests
     code := m.Run()
     // Teardown
@@ -0,0 +1,66 @@
+package integration
+import (
+    "testing"
+    "errors"
+    "database/sql"
+)
+import (
+    "github.com/lib/pq"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+//{{{ WithTx
+func Test_WithTx(t *testing.T) {
+    userFn := func(username string) smodels.User {
+        return smodels.User{
+            Username:   username,
+            Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+            Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+            EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        }
+    }
+    existsFn := func(username string) bool {
+        _, err := cruduser.SelectUser(ctx, db, username, []string{"username"})
+        return err == nil
+    }
+
+    // Commit
+    err := sdb.WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+        return cruduser.InsertUser(ctx, tx, userFn("test_tx_commit"))
+    })
+    if err != nil || !existsFn("test_tx_commit") {
+        t.Errorf("Expected committed user, got error: %v", err)
+    }
+
+    // Rollback, fn error is returned as is
+    errBoom := errors.New("boom")
+    err = sdb.WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+        if err := cruduser.InsertUser(ctx, tx, userFn("test_tx_rollback")); err != nil {
+            return err
+        }
+        return errBoom
+    })
+    if !errors.Is(err, errBoom) || existsFn("test_tx_rollback") {
+        t.Errorf("Expected rolled back user, got error: %v", err)
+    }
+
+    // Serialization failure on 1st attempt is retried, only 2nd attempt is committed
+    attempts := 0
+    err = sdb.WithTx(ctx, db, &sdb.TxOptions{Isolation: sql.LevelSerializable}, func(tx *sql.Tx) error {
+        attempts++
+        if err := cruduser.InsertUser(ctx, tx, userFn("test_tx_retry")); err != nil {
+            return err
+        }
+        if attempts == 1 {
+            return &pq.Error{Code: "40001"}
+        }
+        return nil
+    })
+    if err != nil || attempts != 2 || !existsFn("test_tx_retry") {
+        t.Errorf("Expected commit on 2nd attempt, got %d attempt(s), error: %v", attempts, err)
+    }
+}
+//}}} WithTx
@@ -0,0 +1,82 @@
+// Shared fixture of endpoint tests, table cases checked against APIResponse
+package testutil
+import (
+    "testing"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "reflect"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+
+//{{{ DRY
+type EndpointTestCase struct {
+    Name                string
+    Body                string
+    ExpectedStatusCode  int
+    ExpectedMessage     string
+    ExpectedError       string
+    ExpectedData        interface{}
+    SkipData            bool    // data holds DB generated values (timestamps)
+}
+
+
+func AssertResponse(
+    t *testing.T,
+    resp *httptest.ResponseRecorder,
+    tc EndpointTestCase,
+){
+    t.Helper()
+    var bodyResp sapi.APIResponse
+    if err := json.Unmarshal(resp.Body.Bytes(), &bodyResp); err != nil {
+        t.Fatalf("Failed to parse JSON response as APIResponse: %v", err)
+    }
+    // status code
+    if tc.ExpectedStatusCode != resp.Result().StatusCode {
+        t.Errorf("Unexpeted status code:\nGot:\t%d\nWant:\t%d", resp.Result().StatusCode, tc.ExpectedStatusCode)
+    }
+    // msg
+    if !strings.Contains(bodyResp.Message, tc.ExpectedMessage) {
+        t.Errorf("Unexpected message:\nGot:\t%s\nWant:\t%s", bodyResp.Message, tc.ExpectedMessage)
+    }
+    // err
+    if !strings.Contains(bodyResp.Error, tc.ExpectedError) {
+        t.Errorf("Unexpected error:\nGot:\t%s\nWant:\t%s", bodyResp.Error, tc.ExpectedError)
+    }
+    // data
+    if !tc.SkipData && !reflect.DeepEqual(bodyResp.Data, tc.ExpectedData) {
+        t.Errorf("Unexpected data:\nGot:\t%v\nWant:\t%v", bodyResp.Data, tc.ExpectedData)
+    }
+}
+
+
+// POSTs each case body to endpoint and checks response
+func RunEndpointTests(t *testing.T, endpoint http.HandlerFunc, path string, tests []EndpointTestCase) {
+    t.Helper()
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", path, strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            endpoint(resp, req)
+            // Check
+            AssertResponse(t, resp, tc)
+        })
+    }
+}
+
+
+// Expected data of 422 listing invalid fields, each violation is field, code, constraint, detail
+func ValidationDataFn(violations ...[4]string) map[string]any {
+    errs := make([]any, len(violations))
+    for i, v := range violations {
+        errs[i] = map[string]any{"field": v[0], "code": v[1], "constraint": v[2], "detail": v[3]}
+    }
+    return map[string]any{"errors": errs}
+}
+//}}} DRY
@@ -3,15 +3,24 @@ package middleware
 import (
     "net/http"
     "fmt"
+    "errors"
     "log"
+    "slices"
+    "strings"
 )
 import (
     sapi "github.com/FAH2S/diar4/src/shared/api"
 )
 
 
-func isMethodPOSTFn(r *http.Request) bool {
-    return r.Method == http.MethodPost
+func isMethodAllowedFn(r *http.Request, allowed []string) bool {
+    return slices.Contains(allowed, r.Method)
+}
+
+
+// Only these carry JSON body, GET/DELETE don't need Content-Type
+func hasBodyFn(method string) bool {
+    return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
 }
 
 
@@ -20,28 +29,33 @@ func isHeaderCTAJFn(r *http.Request) bool {
 }
 
 
-// Validate method and header wrapper
-func ValidateMethodAndType(w http.ResponseWriter, r *http.Request) bool {
+// Validate method and header wrapper, method not in allowed is 405 with Allow header
+func ValidateMethodAndType(w http.ResponseWriter, r *http.Request, allowed []string) bool {
     const fn = "Middleware ValidateMethodAndType"
     ip := r.RemoteAddr
-    if !isMethodPOSTFn(r){
-        sapi.WriteJSONResponseFn(
+    if !isMethodAllowedFn(r, allowed){
+        w.Header().Set("Allow", strings.Join(allowed, ", "))
+        sapi.WriteCodedResponseFn(
             w,
-            400,
+            405,
             fmt.Sprintf("Fail: process '%s'", r.URL.Path),
             fmt.Sprintf("Method not allowed"),
+            "request.method_not_allowed",
+            nil,
             nil,
         )
-        log.Printf("%s: Method not allowed | status: 400 | IP: %s", fn, ip)
+        log.Printf("%s: Method not allowed | status: 405 | IP: %s", fn, ip)
         return false
     }
     // Check content type
-    if !isHeaderCTAJFn(r){
-        sapi.WriteJSONResponseFn(
+    if hasBodyFn(r.Method) && !isHeaderCTAJFn(r){
+        sapi.WriteCodedResponseFn(
             w,
             400,
             fmt.Sprintf("Fail: process '%s'", r.URL.Path),
             fmt.Sprintf("Content-Type must be application/json"),
+            "request.unsupported_content_type",
+            nil,
             nil,
         )
         log.Printf("%s: Content-Type must be application/json | status: 400 | IP: %s", fn, ip)
@@ -51,14 +65,59 @@ func ValidateMethodAndType(w http.ResponseWriter, r *http.Request) bool {
 }
 
 
-// Validate method and header endpoint/handler
+// Validate method and header endpoint/handler, verb-in-path routes are POST only
 func ValidateMethodAndTypeEndpoint(next http.Handler) http.Handler {
+    return MethodsEndpoint(map[string]http.HandlerFunc{http.MethodPost: next.ServeHTTP})
+}
+
+
+// Dispatches by method for resource routes (ex.: GET|PATCH|DELETE /users/{username}),
+//  other methods get 405 with Allow listing handled ones
+func MethodsEndpoint(handlers map[string]http.HandlerFunc) http.Handler {
+    allowed := make([]string, 0, len(handlers))
+    for method := range handlers {
+        allowed = append(allowed, method)
+    }
+    slices.Sort(allowed)
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        if !ValidateMethodAndType(w, r) {
+        if !ValidateMethodAndType(w, r, allowed) {
             return
         }
-        next.ServeHTTP(w, r)
+        handlers[r.Method](w, r)
     })
 }
 
 
+// Caps request body of route, body is read here and cached (sapi.ReadBodyFn) on
+//  request passed to next, so endpoints decode it without reading again. Bigger body is 413
+func MaxBodyEndpoint(maxBytes int64, next http.Handler) http.Handler {
+    const fn = "Middleware MaxBodyEndpoint"
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        cached, _, err := sapi.ReadBodyFn(r, maxBytes)
+        if errors.Is(err, sapi.ErrBodyTooLarge) {
+            sapi.WriteCodedResponseFn(
+                w,
+                413,
+                fmt.Sprintf("Fail: process '%s'", r.URL.Path),
+                "Request body too large",
+                sapi.ErrorCodeFn(413, "", err),
+                err,
+                nil,
+            )
+            log.Printf("%s: %v | status: 413 | IP: %s", fn, err, r.RemoteAddr)
+            return
+        }
+        // Other read errors stay cached, endpoint reports them as Invalid JSON
+        next.ServeHTTP(w, cached)
+    })
+}
+
+
+// Error responses as RFC 9457 problem+json for clients that ask for it (Accept),
+//  wraps whole router so middleware errors are covered too
+func ProblemEndpoint(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Add("Vary", "Accept")
+        next.ServeHTTP(sapi.NegotiateProblemFn(w, r), r)
+    })
+}
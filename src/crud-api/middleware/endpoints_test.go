@@ -0,0 +1,56 @@
+package middleware
+import (
+    "io"
+    "testing"
+    "strings"
+    "net/http"
+    "net/http/httptest"
+)
+import (
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+
+//{{{ MaxBodyEndpoint
+func Test_MaxBodyEndpoint(t *testing.T) {
+    tests := []struct {
+        Name                string
+        Body                string
+        ExpectedStatusCode  int
+        ExpectedNextCalled  bool
+    }{
+        {"WithinLimit",     `{"username":"test_user"}`,     200,    true},
+        {"TooLarge",        `{"username":"test_user_long"}`, 413,   false},
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodPost, "/read/user", strings.NewReader(tc.Body))
+            ctx := req.Context()
+            called := false
+            next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                called = true
+                if r == req {
+                    t.Errorf("Next got request of server, expected derived one")
+                }
+                // Cached, limit of second read isn't applied and r.Body still holds body
+                if _, raw, err := sapi.ReadBodyFn(r, 1); err != nil || string(raw) != tc.Body {
+                    t.Errorf("Body not cached for next:\nGot:\t%s (%v)", raw, err)
+                }
+                if raw, err := io.ReadAll(r.Body); err != nil || string(raw) != tc.Body {
+                    t.Errorf("Wrong r.Body of next:\nGot:\t%s (%v)", raw, err)
+                }
+            })
+            resp := httptest.NewRecorder()
+            MaxBodyEndpoint(24, next).ServeHTTP(resp, req)
+            if resp.Code != tc.ExpectedStatusCode || called != tc.ExpectedNextCalled {
+                t.Errorf("Unexpeted result:\nGot:\t%d next: %v\nWant:\t%d next: %v",
+                    resp.Code, called, tc.ExpectedStatusCode, tc.ExpectedNextCalled)
+            }
+            // Request owned by server is left as is
+            if req.Context() != ctx {
+                t.Errorf("MaxBodyEndpoint modified request in place")
+            }
+        })
+    }
+}
+//}}} MaxBodyEndpoint
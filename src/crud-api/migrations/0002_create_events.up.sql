@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS events (
+    id          BIGSERIAL       PRIMARY KEY,
+    owner       VARCHAR(30)     NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    enc_payload TEXT            NOT NULL,   -- hex string, client encrypted, max 8192 char
+    starts_at   TIMESTAMPTZ     NOT NULL,
+    ends_at     TIMESTAMPTZ     NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (enc_payload ~ '^[0-9a-fA-F]+$' AND length(enc_payload) % 2 = 0 AND length(enc_payload) <= 8192),
+    CHECK (ends_at >= starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS events_owner_idx ON events (owner);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id          BIGSERIAL       PRIMARY KEY,
+    author      VARCHAR(30)     NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    event_id    BIGINT          NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    rating      SMALLINT        NOT NULL,
+    enc_body    TEXT            NOT NULL,   -- hex string, client encrypted, max 4096 char
+    created_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT reviews_author_event_id_key UNIQUE (author, event_id),  -- one review per author per event
+    CHECK (rating BETWEEN 1 AND 5),
+    CHECK (enc_body ~ '^[0-9a-fA-F]+$' AND length(enc_body) % 2 = 0 AND length(enc_body) <= 4096)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_event_id_idx ON reviews (event_id);
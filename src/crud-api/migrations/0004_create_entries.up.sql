@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS entries (
+    id          BIGSERIAL       PRIMARY KEY,
+    owner       VARCHAR(30)     NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+    ciphertext  TEXT            NOT NULL,   -- hex string, client encrypted, max 65536 char
+    nonce       CHAR(24)        NOT NULL,   -- 24 char hex string
+    tag         CHAR(32)        NOT NULL,   -- 32 char hex string, AEAD auth tag
+    created_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (ciphertext ~ '^[0-9a-fA-F]+$' AND length(ciphertext) % 2 = 0 AND length(ciphertext) <= 65536),
+    CHECK (nonce ~      '^[0-9a-fA-F]{24}$'),
+    CHECK (tag ~        '^[0-9a-fA-F]{32}$')
+);
+
+-- List by owner in id order
+CREATE INDEX IF NOT EXISTS entries_owner_id_idx ON entries (owner, id);
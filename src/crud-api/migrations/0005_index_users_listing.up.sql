@@ -0,0 +1,6 @@
+-- Keyset pagination over users, username order is served by UNIQUE index.
+--  created_at order needs NOT NULL so row comparison never skips rows
+UPDATE users SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE users ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS users_created_at_username_idx ON users (created_at, username);
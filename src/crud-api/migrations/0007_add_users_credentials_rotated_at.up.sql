@@ -0,0 +1,2 @@
+-- Set by credential rotation (salt, hash, enc_symkey replaced together), NULL = never rotated
+ALTER TABLE users ADD COLUMN IF NOT EXISTS credentials_rotated_at TIMESTAMPTZ;
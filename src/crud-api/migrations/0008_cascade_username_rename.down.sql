@@ -0,0 +1,12 @@
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_owner_fkey,
+    ADD CONSTRAINT events_owner_fkey FOREIGN KEY (owner)
+        REFERENCES users (username) ON DELETE CASCADE;
+ALTER TABLE reviews
+    DROP CONSTRAINT IF EXISTS reviews_author_fkey,
+    ADD CONSTRAINT reviews_author_fkey FOREIGN KEY (author)
+        REFERENCES users (username) ON DELETE CASCADE;
+ALTER TABLE entries
+    DROP CONSTRAINT IF EXISTS entries_owner_fkey,
+    ADD CONSTRAINT entries_owner_fkey FOREIGN KEY (owner)
+        REFERENCES users (username) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- Soft delete, NULL = active. Row (and its username) is kept until purged after retention
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Purge job scans only deleted rows
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
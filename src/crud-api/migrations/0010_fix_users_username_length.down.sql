@@ -0,0 +1,5 @@
+-- Fails while 30 char usernames exist, rename them first
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_username_check,
+    ADD CONSTRAINT users_username_check
+        CHECK (username ~ '^[a-zA-Z0-9_]+$' AND length(username) >= 3 AND length(username) < 30);
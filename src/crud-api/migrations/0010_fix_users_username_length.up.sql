@@ -0,0 +1,5 @@
+-- Username is 3..30 chars like VARCHAR(30) and model validation, CHECK of 0001 cut it at 29
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_username_check,
+    ADD CONSTRAINT users_username_check
+        CHECK (username ~ '^[a-zA-Z0-9_]+$' AND length(username) >= 3 AND length(username) <= 30);
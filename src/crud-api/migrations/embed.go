@@ -0,0 +1,10 @@
+package migrations
+import (
+    "embed"
+)
+
+
+// Versioned schema migrations, `NNNN_name.up.sql` + `NNNN_name.down.sql`,
+//  embedded so binaries don't depend on files on disk
+//go:embed *.sql
+var FS embed.FS
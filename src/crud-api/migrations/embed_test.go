@@ -0,0 +1,19 @@
+package migrations
+import (
+    "testing"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+)
+
+
+// Broken file names or missing down files should fail without DB
+func Test_EmbeddedMigrations(t *testing.T) {
+    migrations, err := sdb.LoadMigrationsFn(FS)
+    if err != nil {
+        t.Fatalf("Failed to load embedded migrations: %v", err)
+    }
+    if len(migrations) == 0 || migrations[0].Version != 1 {
+        t.Errorf("Expected migrations starting at version 1, got: %+v", migrations)
+    }
+}
@@ -0,0 +1,277 @@
+package crudreview
+import (
+    "fmt"
+    "log"
+    "time"
+    "context"
+    "strconv"
+    "net/http"
+    "encoding/json"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+)
+
+// Holds storage used by review endpoints, OpTimeout is deadline for single
+//  store operation (0 = only bound by request context). Body are JSON decode
+//  rules, strict (unknown keys are 422) unless changed
+type ReviewHandler struct {
+    Store       ReviewStore
+    OpTimeout   time.Duration
+    Body        sapi.BodyOptions
+}
+
+
+func NewReviewHandler(store ReviewStore) *ReviewHandler {
+    return &ReviewHandler{
+        Store:  store,
+        // Numbers of map bodies (update) stay json.Number
+        Body:   sapi.BodyOptions{DisallowUnknown: true, DisallowTrailing: true, UseNumber: true},
+    }
+}
+
+
+func (h *ReviewHandler) opContextFn(r *http.Request) (context.Context, context.CancelFunc) {
+    return sapi.OpContextFn(r, h.OpTimeout)
+}
+
+
+//{{{ Create review endpoint
+func (h *ReviewHandler) CreateReviewEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "CreateReviewEndpoint"
+        // Input
+        review      smodels.Review
+        // Response info
+        statusCode  = 500
+        message     = "Fail: create review ''"
+        errMessage  = "Unknown error occurred"
+        returnData  *smodels.Review
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "review", err), err, returnData)
+    }
+
+    // Decode request body into review model
+    err := sapi.DecodeBodyFn(r, &review, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+    // Server side fields are never taken from client
+    review.ID, review.CreatedAt, review.UpdatedAt = 0, time.Time{}, time.Time{}
+
+    // Validate review model fields
+    err = review.Validate(); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to insert review
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    created, err := h.Store.Insert(ctx, review)
+    statusCode = sapi.StatusCodeFromErrFn(err, 201)
+    name := ""
+    if statusCode == 201 {
+        returnData = created
+        name = strconv.FormatInt(created.ID, 10)
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "create", "review", name, err)
+    respond(err); return
+}
+//}}} Create review endpoint
+
+
+//{{{ Read review endpoint
+func (h *ReviewHandler) ReadReviewEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "ReadReviewEndpoint"
+        // Input
+        id          int64
+        // Response info
+        statusCode  = 500
+        message     = "Fail: read review ''"
+        errMessage  = "Unknown error occured"
+        review      *smodels.Review
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "review", err), err, review)
+    }
+
+    // Extract id from request body
+    err := sapi.ExtractBodyFn(r, map[string]interface{}{"id": &id}, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate extracted id
+    name := strconv.FormatInt(id, 10)
+    err = smodels.IsValidIDFn(id)
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: read review '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to select(fetch) review
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    review, err = h.Store.Get(ctx, id)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "read", "review", name, err)
+    respond(err); return
+}
+//}}} Read review endpoint
+
+
+//{{{ Update review endpoint
+func (h *ReviewHandler) UpdateReviewEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "UpdateReviewEndpoint"
+        // Input
+        inputData   map[string]interface{}
+        // Response info
+        statusCode  = 500
+        message     = "Fail: update review ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]int64
+        ip          = r.RemoteAddr
+        success     = false
+    )
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "review", err), err, returnData)
+    }
+
+    // Decode request body into map/dict data, numbers kept as json.Number
+    err := sapi.DecodeBodyFn(r, &inputData, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // - id field must be present and positive integer
+    rawID, _ := inputData["id"].(json.Number)
+    id, err := rawID.Int64()
+    if err == nil {
+        err = smodels.IsValidIDFn(id)
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = "Missing or invalid required field: 'id'"
+        respond(err); return
+    }
+    name := strconv.FormatInt(id, 10)
+    message = fmt.Sprintf("Fail: update review '%s'", name)
+
+    // Sanitize data
+    // - remove illegal keys (id, author, event_id, timestamps set by DB) => sapi, strict mode names them instead
+    allowed := []string{"rating", "enc_body"}
+    if h.Body.DisallowUnknown {
+        err = sapi.CheckKnownKeysFn(inputData, append([]string{"id"}, allowed...)); if err != nil {
+            statusCode, errMessage = sapi.BodyErrResponseFn(err)
+            respond(err); return
+        }
+    }
+    filterdData := sapi.SanitizeKeysFn(inputData, allowed)
+    // - check for at least 1 field
+    if len(filterdData) < 1 {
+        statusCode = 422
+        errMessage = "Invalid input: must contain at least 1 updatable field"
+        respond(err); return
+    }
+    // - check each present field, rating converted to int => smodels
+    data, err := smodels.ValidateReviewMap(filterdData); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Call UpdateReview
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Update(ctx, id, data)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = map[string]int64{"id":id}
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "update", "review", name, err)
+    respond(err); return
+}
+//}}} Update review endpoint
+
+
+//{{{ Delete review endpoint
+func (h *ReviewHandler) DeleteReviewEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "DeleteReviewEndpoint"
+        // Input
+        id          int64
+        // Response info
+        statusCode  = 500
+        message     = "Fail: delete review ''"
+        errMessage  = "Unknown error occured"
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "review", err), err, nil)
+    }
+
+    // Extract id from request body
+    err := sapi.ExtractBodyFn(r, map[string]interface{}{"id": &id}, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate extracted id
+    name := strconv.FormatInt(id, 10)
+    err = smodels.IsValidIDFn(id)
+    if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: delete review '%s'", name)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Attempt to delete review
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Delete(ctx, id)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "delete", "review", name, err)
+    respond(err); return
+}
+//}}} Delete review endpoint
@@ -0,0 +1,140 @@
+package crudreview
+import (
+    "testing"
+    "encoding/json"
+    "fmt"
+)
+import (
+    "github.com/FAH2S/diar4/src/crud-api/internal/testutil"
+)
+
+
+//{{{ CreateReviewEndpoint
+func Test_CreateReviewEndpoint(t *testing.T){
+    handler := NewReviewHandler(newTestStoreFn(t))
+    review := func(author string, eventID int, rating int) string {
+        return fmt.Sprintf(`{"author":"%s","event_id":%d,"rating":%d,"enc_body":"%s"}`, author, eventID, rating, testEncBody)
+    }
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "CreateReview",
+            Body:               review(testAuthor, 1, 5),
+            ExpectedStatusCode: 201,
+            ExpectedMessage:    "Success: create review '1'",
+            ExpectedError:      "",
+            SkipData:           true,
+        },{
+            Name:               "SecondReviewSameEvent",
+            Body:               review(testAuthor, 1, 3),
+            ExpectedStatusCode: 409,
+            ExpectedMessage:    "Fail: create review ''",
+            ExpectedError:      "Review already exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownEvent",
+            Body:               review(testAuthor, 999, 3),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create review ''",
+            ExpectedError:      "Invalid reference: 'event_id' doesn't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownAuthor",
+            Body:               review("not_found", 1, 3),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create review ''",
+            ExpectedError:      "Invalid reference: 'author' doesn't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "RatingOutOfBounds",
+            Body:               review(testAuthor, 1, 6),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create review ''",
+            ExpectedError:      "Invalid input format: rating: must be between 1 and 5",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"author":`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: create review ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },
+    }
+    testutil.RunEndpointTests(t, handler.CreateReviewEndpoint, "/create/review", tests)
+}
+//}}} CreateReviewEndpoint
+
+
+//{{{ Read/Update/Delete ReviewEndpoint
+func Test_ReadUpdateDeleteReviewEndpoint(t *testing.T){
+    handler := NewReviewHandler(newTestStoreFn(t))
+    created, err := handler.Store.Insert(ctx, newTestReviewFn())
+    if err != nil {
+        t.Fatalf("Failed to create review: %v", err)
+    }
+    // Expected data is review as it goes over the wire
+    raw, _ := json.Marshal(created)
+    var expected map[string]any
+    _ = json.Unmarshal(raw, &expected)
+
+    testutil.RunEndpointTests(t, handler.ReadReviewEndpoint, "/read/review", []testutil.EndpointTestCase{
+        {
+            Name:               "ReadReview",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: read review '%d'", created.ID),
+            ExpectedData:       expected,
+        },{
+            Name:               "NotFound",
+            Body:               `{"id":999}`,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: read review '999'",
+            ExpectedError:      "Review not found, dosen't exist",
+        },
+    })
+    testutil.RunEndpointTests(t, handler.UpdateReviewEndpoint, "/update/review", []testutil.EndpointTestCase{
+        {
+            Name:               "UpdateReview",
+            Body:               fmt.Sprintf(`{"id":%d,"rating":1}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: update review '%d'", created.ID),
+            ExpectedData:       map[string]any{"id":float64(created.ID)},
+        },{
+            Name:               "RatingNotInteger",
+            Body:               fmt.Sprintf(`{"id":%d,"rating":1.5}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update review '%d'", created.ID),
+            ExpectedError:      "Invalid input format: field \"rating\" must be integer",
+            ExpectedData:       map[string]any{"errors": []any{map[string]any{
+                "field": "rating", "code": "type", "constraint": "integer", "detail": `field "rating" must be integer`,
+            }}},
+        },{
+            Name:               "OnlyIllegalFields",
+            Body:               fmt.Sprintf(`{"id":%d,"event_id":2}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update review '%d'", created.ID),
+            ExpectedError:      "Invalid input format: unknown field \"event_id\"",
+        },{
+            Name:               "NoUpdatableFields",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: update review '%d'", created.ID),
+            ExpectedError:      "Invalid input: must contain at least 1 updatable field",
+        },
+    })
+    testutil.RunEndpointTests(t, handler.DeleteReviewEndpoint, "/delete/review", []testutil.EndpointTestCase{
+        {
+            Name:               "DeleteReview",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: delete review '%d'", created.ID),
+        },{
+            Name:               "NotFound",
+            Body:               fmt.Sprintf(`{"id":%d}`, created.ID),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: delete review '%d'", created.ID),
+            ExpectedError:      "Review not found, dosen't exist",
+        },
+    })
+}
+//}}} Read/Update/Delete ReviewEndpoint
@@ -0,0 +1,145 @@
+package crudreview
+import (
+    "fmt"
+    "context"
+    "database/sql"
+    "strings"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+)
+
+
+// Reviews of soft deleted author are hidden, see cruduser.ActiveUserSQLFn
+var activeAuthorSQL = cruduser.ActiveUserSQLFn("reviews", "author")
+
+
+//{{{ InsertReview
+// Returns review with ID and timestamps set by DB. Missing or soft deleted author,
+//  missing event or event of soft deleted owner is ErrMissingReference, second
+//  review of same event by same author is ErrConflict
+func InsertReview(ctx context.Context, db *sql.DB, review smodels.Review) (*smodels.Review, error) {
+    wrap := "InsertReview"
+    // Create sql query, row is inserted only for active author and visible event
+    query := `
+        INSERT INTO reviews (author, event_id, rating, enc_body)
+        SELECT users.username, events.id, $3::smallint, $4::text
+        FROM users, events
+        WHERE users.username = $1 AND users.deleted_at IS NULL AND events.id = $2
+            AND EXISTS (SELECT 1 FROM users owners WHERE owners.username = events.owner AND owners.deleted_at IS NULL)
+        RETURNING id, created_at, updated_at
+    `
+    // Insert + load generated columns
+    err := db.QueryRowContext(ctx, query, review.Author, review.EventID, review.Rating, review.EncBody).Scan(
+        &review.ID,
+        &review.CreatedAt,
+        &review.UpdatedAt,
+    )
+    // No row inserted, name missing reference same as FK violation would
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("%s: %w", wrap, missingReferenceFn(ctx, db, review.Author))
+    }
+    // Map pg errors to typed errors
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    return &review, nil
+}
+//}}} InsertReview
+
+
+// Which reference of not inserted review is missing, author when it isn't active else event_id
+func missingReferenceFn(ctx context.Context, db *sql.DB, author string) error {
+    fn := "missingReferenceFn"
+    var active bool
+    query := `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 AND deleted_at IS NULL)`
+    if err := db.QueryRowContext(ctx, query, author).Scan(&active); err != nil {
+        return sdb.HandlePgErrorFn("review", err)
+    }
+    column := "event_id"
+    if !active {
+        column = "author"
+    }
+    return &sdb.DBError{Kind: sdb.ErrMissingReference, Table: "review", Column: column,
+        Err: fmt.Errorf("%s: %s not present", fn, column)}
+}
+
+
+//{{{ SelectReview
+func SelectReview(ctx context.Context, db *sql.DB, id int64) (*smodels.Review, error) {
+    wrap := "SelectReview"
+    // Create query
+    query := `
+        SELECT id, author, event_id, rating, enc_body, created_at, updated_at FROM reviews
+        WHERE id = $1 AND ` + activeAuthorSQL + ` LIMIT 1;
+    `
+    // Create review instance
+    var review smodels.Review
+    // Query row + Scan load result into review
+    err := db.QueryRowContext(ctx, query, id).Scan(
+        &review.ID,
+        &review.Author,
+        &review.EventID,
+        &review.Rating,
+        &review.EncBody,
+        &review.CreatedAt,
+        &review.UpdatedAt,
+    )
+    // Check for errors, not found or failed query
+    err = sdb.HandleSelectErrorFn("review", err)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return &review, nil
+}
+//}}} SelectReview
+
+
+//{{{ UpdateReview
+// data must be validated via smodels.ValidateReviewMap, updated_at is bumped by query
+func UpdateReview(ctx context.Context, db *sql.DB, data map[string]interface{}, id int64) error {
+    wrap := "UpdateReview"
+    // Build set parts, return err if empty
+    setParts, args, err := sdb.BuildSetPartsFn(data)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+    // Create querry
+    query := fmt.Sprintf(`UPDATE reviews SET %s WHERE id = $%d AND %s`, strings.Join(setParts, ", "), len(args)+1, activeAuthorSQL)
+    args = append(args, id)
+    // Update DB
+    result, err := db.ExecContext(ctx, query, args...)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    // Check
+    if err = sdb.CheckRowsAffectedFn("review", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} UpdateReview
+
+
+//{{{ DeleteReview
+func DeleteReview(ctx context.Context, db *sql.DB, id int64) error {
+    wrap := "DeleteReview"
+    // Create query
+    query := `DELETE FROM reviews WHERE id = $1 AND ` + activeAuthorSQL + `;`
+    // Execute
+    result, err := db.ExecContext(ctx, query, id)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    // Check rows affected
+    if err = sdb.CheckRowsAffectedFn("review", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} DeleteReview
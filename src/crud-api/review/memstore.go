@@ -0,0 +1,204 @@
+package crudreview
+import (
+    "fmt"
+    "sync"
+    "time"
+    "errors"
+    "context"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudevent "github.com/FAH2S/diar4/src/crud-api/event"
+)
+
+
+// In-memory ReviewStore, mirrors Postgres semantics (serial id, CHECK constraints,
+// one review per author per event, not found on update/delete, done context).
+// Foreign keys and soft deleted author are checked against Users/Events when set
+// (nil = not checked). Safe for concurrent use.
+type MemReviewStore struct {
+    Users   cruduser.UserStore
+    Events  crudevent.EventStore
+    mu      sync.RWMutex
+    nextID  int64
+    reviews map[int64]smodels.Review
+}
+
+
+func NewMemReviewStore(users cruduser.UserStore, events crudevent.EventStore) *MemReviewStore {
+    return &MemReviewStore{Users: users, Events: events, nextID: 1, reviews: make(map[int64]smodels.Review)}
+}
+
+
+// Columns of reviews table that can be SET by Update
+var memReviewColumns = map[string]struct{}{
+    "rating":   {},
+    "enc_body": {},
+}
+
+
+// Same typed error Postgres path would return
+func memReviewErrFn(kind error, column string, err error) error {
+    return &sdb.DBError{Kind: kind, Table: "review", Column: column, Err: err}
+}
+
+
+// Foreign key check, not found in parent store is ErrMissingReference
+func (s *MemReviewStore) checkReferencesFn(ctx context.Context, review smodels.Review) error {
+    wrap := "MemReviewStore.checkReferencesFn"
+    if s.Users != nil {
+        _, err := s.Users.Get(ctx, review.Author, []string{"username"})
+        if errors.Is(err, sdb.ErrNotFound) {
+            return memReviewErrFn(sdb.ErrMissingReference, "author", fmt.Errorf("%s: author not present", wrap))
+        }
+        if err != nil {
+            return err
+        }
+    }
+    if s.Events != nil {
+        _, err := s.Events.Get(ctx, review.EventID)
+        if errors.Is(err, sdb.ErrNotFound) {
+            return memReviewErrFn(sdb.ErrMissingReference, "event_id", fmt.Errorf("%s: event not present", wrap))
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+
+// Missing or soft deleted author is not active, its reviews are hidden
+func (s *MemReviewStore) authorActiveFn(ctx context.Context, author string) (bool, error) {
+    if s.Users == nil {
+        return true, nil
+    }
+    _, err := s.Users.Get(ctx, author, []string{"username"})
+    if errors.Is(err, sdb.ErrNotFound) {
+        return false, nil
+    }
+    return err == nil, err
+}
+
+
+// Stored review, not ok when missing or its author isn't active. Caller holds s.mu
+func (s *MemReviewStore) visibleFn(ctx context.Context, id int64) (smodels.Review, bool, error) {
+    review, ok := s.reviews[id]
+    if !ok {
+        return review, false, nil
+    }
+    active, err := s.authorActiveFn(ctx, review.Author)
+    return review, active, err
+}
+
+
+func (s *MemReviewStore) Insert(ctx context.Context, review smodels.Review) (*smodels.Review, error) {
+    wrap := "MemReviewStore.Insert"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    // CHECK constraints, then foreign keys, then unique
+    if err := review.Validate(); err != nil {
+        return nil, memReviewErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid review data/format: %w", wrap, err))
+    }
+    if err := s.checkReferencesFn(ctx, review); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, existing := range s.reviews {
+        if existing.Author == review.Author && existing.EventID == review.EventID {
+            return nil, memReviewErrFn(sdb.ErrConflict, "author, event_id", fmt.Errorf("%s: review already exists", wrap))
+        }
+    }
+    now := time.Now().UTC()
+    review.ID = s.nextID
+    review.CreatedAt = now
+    review.UpdatedAt = now
+    s.nextID++
+    s.reviews[review.ID] = review
+    return &review, nil
+}
+
+
+func (s *MemReviewStore) Get(ctx context.Context, id int64) (*smodels.Review, error) {
+    wrap := "MemReviewStore.Get"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    review, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return nil, memReviewErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: review not found/dosen't exist", wrap))
+    }
+    // Return copy so caller can't mutate stored review
+    return &review, nil
+}
+
+
+func (s *MemReviewStore) Update(ctx context.Context, id int64, data map[string]interface{}) error {
+    wrap := "MemReviewStore.Update"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    // Same order of checks as UpdateReview: empty, unknown column, not found, CHECK
+    if len(data) == 0 {
+        return memReviewErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: no fields to update", wrap))
+    }
+    for k := range data {
+        if _, ok := memReviewColumns[k]; !ok {
+            return memReviewErrFn(sdb.ErrUnknownColumn, k, fmt.Errorf("%s: unknown column used: %q", wrap, k))
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    review, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memReviewErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    // Apply, values come from smodels.ValidateReviewMap
+    for k, v := range data {
+        switch k {
+        case "rating":
+            review.Rating, _ = v.(int)
+        case "enc_body":
+            review.EncBody, _ = v.(string)
+        }
+    }
+    if err := review.Validate(); err != nil {
+        return memReviewErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid review data/format: %w", wrap, err))
+    }
+    review.UpdatedAt = time.Now().UTC()
+    s.reviews[id] = review
+    return nil
+}
+
+
+func (s *MemReviewStore) Delete(ctx context.Context, id int64) error {
+    wrap := "MemReviewStore.Delete"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("review", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, ok, err := s.visibleFn(ctx, id)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    if !ok {
+        return memReviewErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.reviews, id)
+    return nil
+}
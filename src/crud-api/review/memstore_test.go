@@ -0,0 +1,197 @@
+package crudreview
+import (
+    "testing"
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    cruduser "github.com/FAH2S/diar4/src/crud-api/user"
+    crudevent "github.com/FAH2S/diar4/src/crud-api/event"
+)
+
+
+const (
+    testAuthor =    "test_review_author"
+    testEncBody =   "0c8fd825308df79b"
+)
+
+
+var ctx = context.Background()
+
+
+// Review store backed by mem user/event stores with one author and one event (id 1)
+func newTestStoreFn(t *testing.T) *MemReviewStore {
+    users := cruduser.NewMemUserStore()
+    err := users.Insert(ctx, smodels.User{
+        Username:   testAuthor,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    })
+    if err != nil {
+        t.Fatalf("Failed to create author: %v", err)
+    }
+    events := crudevent.NewMemEventStore(users)
+    startsAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+    _, err = events.Insert(ctx, smodels.Event{
+        Owner:      testAuthor,
+        EncPayload: testEncBody,
+        StartsAt:   startsAt,
+        EndsAt:     startsAt.Add(time.Hour),
+    })
+    if err != nil {
+        t.Fatalf("Failed to create event: %v", err)
+    }
+    return NewMemReviewStore(users, events)
+}
+
+
+func newTestReviewFn() smodels.Review {
+    return smodels.Review{Author: testAuthor, EventID: 1, Rating: 4, EncBody: testEncBody}
+}
+
+
+//{{{ Insert
+func Test_MemReviewStore_Insert(t *testing.T) {
+    store := newTestStoreFn(t)
+    unknownAuthor := newTestReviewFn()
+    unknownAuthor.Author = "not_found"
+    unknownEvent := newTestReviewFn()
+    unknownEvent.EventID = 999
+    badRating := newTestReviewFn()
+    badRating.Rating = 9
+    tests := []struct {
+        name            string
+        review          smodels.Review
+        expectedErr     error
+        expectedColumn  string
+    }{
+        {"Success",         newTestReviewFn(),  nil,                        ""},
+        {"SecondByAuthor",  newTestReviewFn(),  sdb.ErrConflict,            "author, event_id"},
+        {"UnknownAuthor",   unknownAuthor,      sdb.ErrMissingReference,    "author"},
+        {"UnknownEvent",    unknownEvent,       sdb.ErrMissingReference,    "event_id"},
+        {"InvalidRating",   badRating,          sdb.ErrInvalid,             ""},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, err := store.Insert(ctx, tc.review)
+            if !errors.Is(err, tc.expectedErr) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            var dbErr *sdb.DBError
+            if err != nil && (!errors.As(err, &dbErr) || dbErr.Column != tc.expectedColumn) {
+                t.Errorf("Wrong column:\nExpected:\t%q\nGot:\t\t%+v", tc.expectedColumn, dbErr)
+            }
+        })
+    }
+}
+//}}} Insert
+
+
+//{{{ Update
+func Test_MemReviewStore_Update(t *testing.T) {
+    store := newTestStoreFn(t)
+    created, err := store.Insert(ctx, newTestReviewFn())
+    if err != nil {
+        t.Fatalf("Failed to create review that will be updated: %v", err)
+    }
+    tests := []struct {
+        name        string
+        id          int64
+        data        map[string]interface{}
+        expectedErr error
+    }{
+        {"Success",         created.ID, map[string]interface{}{"rating": 2},            nil},
+        {"Empty",           created.ID, map[string]interface{}{},                       sdb.ErrInvalid},
+        {"UnknownColumn",   created.ID, map[string]interface{}{"author": "other"},      sdb.ErrUnknownColumn},
+        {"NotFound",        999,        map[string]interface{}{"rating": 2},            sdb.ErrNotFound},
+        {"InvalidRating",   created.ID, map[string]interface{}{"rating": 0},            sdb.ErrInvalid},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := store.Update(ctx, tc.id, tc.data)
+            if !errors.Is(err, tc.expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+    // Failed update must not leave partial changes
+    review, _ := store.Get(ctx, created.ID)
+    if review.Rating != 2 {
+        t.Errorf("Unexpected stored review after updates: %+v", review)
+    }
+}
+//}}} Update
+
+
+//{{{ Delete
+func Test_MemReviewStore_Delete(t *testing.T) {
+    store := newTestStoreFn(t)
+    created, err := store.Insert(ctx, newTestReviewFn())
+    if err != nil {
+        t.Fatalf("Failed to create review that will be deleted: %v", err)
+    }
+    for i, expectedErr := range []error{nil, sdb.ErrNotFound} {
+        t.Run(fmt.Sprintf("Attempt%d", i+1), func(t *testing.T) {
+            err := store.Delete(ctx, created.ID)
+            if !errors.Is(err, expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Delete
+
+
+//{{{ Soft deleted author
+func Test_MemReviewStore_SoftDeletedAuthor(t *testing.T) {
+    store := newTestStoreFn(t)
+    created, err := store.Insert(ctx, newTestReviewFn())
+    if err != nil {
+        t.Fatalf("Failed to create review: %v", err)
+    }
+    if err = store.Users.Delete(ctx, testAuthor); err != nil {
+        t.Fatalf("Failed to soft delete author: %v", err)
+    }
+    // Reviews of soft deleted author are hidden
+    if _, err = store.Get(ctx, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = store.Update(ctx, created.ID, map[string]interface{}{"rating": 1}); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    if err = store.Delete(ctx, created.ID); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    // Soft deleted author can't review, nor can anyone review event of soft deleted owner
+    second := newTestReviewFn()
+    if _, err = store.Insert(ctx, second); !errors.Is(err, sdb.ErrMissingReference) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrMissingReference, err)
+    }
+    err = store.Users.Insert(ctx, smodels.User{
+        Username:   "test_review_other",
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    })
+    if err != nil {
+        t.Fatalf("Failed to create other author: %v", err)
+    }
+    second.Author = "test_review_other"
+    var dbErr *sdb.DBError
+    if _, err = store.Insert(ctx, second); !errors.As(err, &dbErr) || dbErr.Column != "event_id" {
+        t.Errorf("Wrong error:\nExpected:\tmissing reference 'event_id'\nGot:\t\t%v", err)
+    }
+    // Restored author sees its review again
+    if err = store.Users.Restore(ctx, testAuthor); err != nil {
+        t.Fatalf("Failed to restore author: %v", err)
+    }
+    if review, err := store.Get(ctx, created.ID); err != nil || review.Author != testAuthor {
+        t.Errorf("Wrong result after restore: %+v (%v)", review, err)
+    }
+}
+//}}} Soft deleted author
@@ -0,0 +1,50 @@
+package crudreview
+import (
+    "context"
+    "database/sql"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+// Storage behind review endpoints, same contract as cruduser.UserStore.
+//  Errors are typed shareddb errors, check with errors.Is
+type ReviewStore interface {
+    Insert(ctx context.Context, review smodels.Review) (*smodels.Review, error)
+    Get(ctx context.Context, id int64) (*smodels.Review, error)
+    Update(ctx context.Context, id int64, data map[string]interface{}) error
+    Delete(ctx context.Context, id int64) error
+}
+
+
+//{{{ Postgres store
+type PgReviewStore struct {
+    DB  *sql.DB
+}
+
+
+func NewPgReviewStore(db *sql.DB) *PgReviewStore {
+    return &PgReviewStore{DB: db}
+}
+
+
+func (s *PgReviewStore) Insert(ctx context.Context, review smodels.Review) (*smodels.Review, error) {
+    return InsertReview(ctx, s.DB, review)
+}
+
+
+func (s *PgReviewStore) Get(ctx context.Context, id int64) (*smodels.Review, error) {
+    return SelectReview(ctx, s.DB, id)
+}
+
+
+func (s *PgReviewStore) Update(ctx context.Context, id int64, data map[string]interface{}) error {
+    return UpdateReview(ctx, s.DB, data, id)
+}
+
+
+func (s *PgReviewStore) Delete(ctx context.Context, id int64) error {
+    return DeleteReview(ctx, s.DB, id)
+}
+//}}} Postgres store
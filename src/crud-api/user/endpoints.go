@@ -2,19 +2,77 @@ package cruduser
 import (
     "fmt"
     "log"
+    "errors"
+    "time"
+    "context"
+    "slices"
+    "strconv"
+    "strings"
     "net/http"
     "encoding/json"
-    "database/sql"
 )
 import (
     smodels "github.com/FAH2S/diar4/src/shared/models"
     sapi "github.com/FAH2S/diar4/src/shared/api"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
 )
 
+// Holds storage used by user endpoints, OpTimeout is deadline for single
+//  store operation (0 = only bound by request context). CursorKey signs list
+//  cursors, random per handler unless set, so cursors die with process.
+//  Body are JSON decode rules, strict (unknown keys are 422) unless changed
+type UserHandler struct {
+    Store       UserStore
+    OpTimeout   time.Duration
+    CursorKey   []byte
+    Body        sapi.BodyOptions
+}
+
+
+func NewUserHandler(store UserStore) *UserHandler {
+    return &UserHandler{
+        Store:      store,
+        CursorKey:  sapi.NewCursorKeyFn(),
+        Body:       sapi.BodyOptions{DisallowUnknown: true, DisallowTrailing: true},
+    }
+}
+
+
+// List page size when "limit" is omitted, and its upper bound
+const (
+    DefaultUserListLimit    = 50
+    MaxUserListLimit        = 100
+)
+
+
+// Derive store operation context from request, client disconnect cancels it too
+func (h *UserHandler) opContextFn(r *http.Request) (context.Context, context.CancelFunc) {
+    return sapi.OpContextFn(r, h.OpTimeout)
+}
+
+
+// Version Update must match for If-Match versions, none (0) means any. With several
+//  listed, current one is picked if listed, Update still checks it atomically
+func (h *UserHandler) expectedVersionFn(ctx context.Context, username string, versions []int64) (int64, error) {
+    if len(versions) == 0 {
+        return 0, nil
+    }
+    if len(versions) == 1 {
+        return versions[0], nil
+    }
+    user, err := h.Store.Get(ctx, username, []string{"version"})
+    if err != nil {
+        return 0, err
+    }
+    if slices.Contains(versions, user.Version) {
+        return user.Version, nil
+    }
+    return versions[0], nil
+}
 
 
 //{{{ Create user endpoint
-func CreateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+func (h *UserHandler) CreateUserEndpoint(w http.ResponseWriter, r *http.Request) {
     var (
         wrap        = "CreateUserEndpoint"
         // Input
@@ -34,13 +92,12 @@ func CreateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
         } else {
             log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
         }
-        sapi.WriteJSONResponseFn(w, statusCode, message, errMessage, nil)
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, nil)
     }
 
     // Decode request body into user model
-    err := json.NewDecoder(r.Body).Decode(&user); if err != nil {
-        statusCode = 400
-        errMessage = "Invalid JSON"
+    err := sapi.DecodeBodyFn(r, &user, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
         respond(err); return
     }
 
@@ -53,7 +110,10 @@ func CreateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
     }
 
     // Attempt to insert user
-    statusCode, err = InsertUser(db, user)
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Insert(ctx, user)
+    statusCode = sapi.StatusCodeFromErrFn(err, 201)
     message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "create", "user", user.Username, err)
     respond(err); return
 }
@@ -61,16 +121,23 @@ func CreateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 
 
 //{{{ Read user endpoint
-func ReadUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+// Returns only requested "fields" (smodels.ReadableUserFields), smodels.DefaultUserFields when
+//  omitted, hash and enc_symkey are never returned.
+//  Row version is sent as ETag header, use it as If-Match on update
+func (h *UserHandler) ReadUserEndpoint(w http.ResponseWriter, r *http.Request) {
     var (
         wrap        = "ReadUserEndpoint"
         // Input
-        username    = ""
+        input       struct {
+            Username    string      `json:"username"`
+            Fields      *[]string   `json:"fields"`
+        }
+        fields      = smodels.DefaultUserFields
         // Response info
         statusCode  = 500
         message     = "Fail: read user ''"
         errMessage  = "Unknown error occured"
-        user        *smodels.User
+        returnData  map[string]string
         ip          = r.RemoteAddr
         success     = false
     )
@@ -82,15 +149,15 @@ func ReadUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
         } else {
             log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
         }
-        sapi.WriteJSONResponseFn(w, statusCode, message, errMessage, user)
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
     }
 
-    // Extract username from request body
-    err := sapi.ExtractJSONValueFn(r, "username", &username); if err != nil {
-        statusCode = 400
-        errMessage = "Invalid JSON"
+    // Decode username and optional fields from request body
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
         respond(err); return
     }
+    username := input.Username
 
     // Validate extracted username
     err = smodels.IsValidUsernameFn(username)
@@ -101,16 +168,156 @@ func ReadUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
         respond(err); return
     }
 
+    // Validate requested fields against User model
+    if input.Fields != nil {
+        fields = *input.Fields
+        err = smodels.ValidateUserFieldsFn(fields)
+        if err != nil {
+            statusCode = 422
+            message = fmt.Sprintf("Fail: read user '%s'", username)
+            errMessage = fmt.Sprintf("Invalid input format: %v", err)
+            respond(err); return
+        }
+    }
+
     // Attempt to select(fetch) user
-    statusCode, user, err = SelectUser(db, username)
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    user, err := h.Store.Get(ctx, username, append(slices.Clip(fields), "version"))
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = user.Project(fields)
+        w.Header().Set("ETag", sapi.FormatETagFn(user.Version))
+    }
     message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "read", "user", username, err)
     respond(err); return
 }
 //}}} Read user endpoint
 
 
+//{{{ List users endpoint
+// Position of last returned user, signed so client can't craft arbitrary keyset
+type userCursor struct {
+    Order       string      `json:"order"`
+    Username    string      `json:"username"`
+    CreatedAt   time.Time   `json:"created_at"`
+}
+
+
+func (h *UserHandler) encodeCursor(order string, last smodels.UserListItem) string {
+    payload, _ := json.Marshal(userCursor{Order: order, Username: last.Username, CreatedAt: last.CreatedAt})
+    return sapi.SignCursorFn(h.CursorKey, payload)
+}
+
+
+func (h *UserHandler) decodeCursor(cursor string) (userCursor, error) {
+    var decoded userCursor
+    payload, err := sapi.VerifyCursorFn(h.CursorKey, cursor)
+    if err != nil {
+        return decoded, fmt.Errorf("cursor: %w", err)
+    }
+    if err = json.Unmarshal(payload, &decoded); err != nil {
+        return decoded, fmt.Errorf("cursor: %w", sapi.ErrInvalidCursor)
+    }
+    return decoded, nil
+}
+
+
+// Page of usernames + created_at, "next_cursor" is omitted on last page
+type userListPage struct {
+    Users       []smodels.UserListItem  `json:"users"`
+    NextCursor  string                  `json:"next_cursor,omitempty"`
+}
+
+
+// Returns "limit" users ordered by "order" (username|created_at), next page is
+//  requested with same order and "cursor" from previous response
+func (h *UserHandler) ListUsersEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "ListUsersEndpoint"
+        // Input
+        input       struct {
+            Order       string  `json:"order"`
+            Limit       int     `json:"limit"`
+            Cursor      string  `json:"cursor"`
+        }
+        after       *smodels.UserListItem
+        // Response info
+        statusCode  = 500
+        message     = "Fail: list users ''"
+        errMessage  = "Unknown error occured"
+        returnData  *userListPage
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
+    }
+
+    // Decode optional order, limit and cursor
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate order and page size
+    if input.Order == "" {
+        input.Order = "username"
+    }
+    if input.Limit == 0 {
+        input.Limit = DefaultUserListLimit
+    }
+    message = fmt.Sprintf("Fail: list users '%s'", input.Order)
+    if !slices.Contains(UserListOrders, input.Order) {
+        err = fmt.Errorf("order: must be one of %v", UserListOrders)
+    }
+    if err == nil && (input.Limit < 1 || input.Limit > MaxUserListLimit) {
+        err = fmt.Errorf("limit: must be between 1 and %d", MaxUserListLimit)
+    }
+    // Cursor must be ours and issued for same order
+    if err == nil && input.Cursor != "" {
+        var cursor userCursor
+        cursor, err = h.decodeCursor(input.Cursor)
+        if err == nil && cursor.Order != input.Order {
+            err = fmt.Errorf("cursor: issued for order '%s'", cursor.Order)
+        }
+        after = &smodels.UserListItem{Username: cursor.Username, CreatedAt: cursor.CreatedAt}
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Fetch one extra row to know if there is next page
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    users, err := h.Store.List(ctx, input.Order, after, input.Limit+1)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = &userListPage{Users: users}
+        if len(users) > input.Limit {
+            returnData.Users = users[:input.Limit]
+            returnData.NextCursor = h.encodeCursor(input.Order, users[input.Limit-1])
+        }
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "list", "users", input.Order, err)
+    respond(err); return
+}
+//}}} List users endpoint
+
+
 //{{{ Update user endpoint
-func UpdateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+// Optional If-Match header ("version" from ETag) makes update conditional, new ETag is returned.
+//  Credential fields (salt, hash, enc_symkey) are set all together or not at all
+func (h *UserHandler) UpdateUserEndpoint(w http.ResponseWriter, r *http.Request) {
     var (
         wrap        = "UpdateUserEndpoint"
         // Input
@@ -131,19 +338,30 @@ func UpdateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
         } else {
             log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
         }
-        sapi.WriteJSONResponseFn(w, statusCode, message, errMessage, returnData)
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
     }
 
     // Decode request body into map/dict data
-    err := json.NewDecoder(r.Body).Decode(&inputData); if err != nil {
-        statusCode = 400
-        errMessage = "Invalid JSON"
+    err := sapi.DecodeBodyFn(r, &inputData, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
         respond(err); return
     }
 
     // Sanitize data
-    // - remove illegal keys => sapi
+    // - remove illegal keys => sapi, strict mode names them instead
     allowed := []string{"username", "salt", "hash", "enc_symkey"}
+    if h.Body.DisallowUnknown {
+        err = sapi.CheckKnownKeysFn(inputData, allowed); if err != nil {
+            statusCode, errMessage = sapi.BodyErrResponseFn(err)
+            respond(err); return
+        }
+    }
+    // - credentials change together, partial set would leave hash not matching salt
+    err = smodels.RejectPartialCredentialsFn(inputData); if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
     filterdData := sapi.SanitizeKeysFn(inputData, allowed)
     // - check each present field via some user validate => smodels
     err = smodels.ValidateUserMap(filterdData); if err != nil {
@@ -166,12 +384,31 @@ func UpdateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
     }
     // - pop username from map
     delete (filterdData, "username")
+    // - optional If-Match, stale version or only weak tags fail with 412
+    versions, err := sapi.ParseIfMatchFn(r.Header.Get("If-Match")); if err != nil {
+        statusCode = 422
+        message = fmt.Sprintf("Fail: update user '%s'", username)
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        if errors.Is(err, sapi.ErrPreconditionFailed) {
+            statusCode = 412
+            errMessage = err.Error()
+        }
+        respond(err); return
+    }
 
 
     // Call UpdateUser
-    statusCode, err = UpdateUser(db, filterdData, username)
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    var version int64
+    expectedVersion, err := h.expectedVersionFn(ctx, username, versions)
+    if err == nil {
+        version, err = h.Store.Update(ctx, username, filterdData, expectedVersion)
+    }
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
     if statusCode == 200 {
         returnData = map[string]string{"username":username}
+        w.Header().Set("ETag", sapi.FormatETagFn(version))
     }
     message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "update", "user", username, err)
     // Return API response
@@ -182,15 +419,104 @@ func UpdateUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 //}}} Update user endpoint
 
 
+//{{{ Rename user endpoint
+// Update can't change username (it is lookup key), rename moves user + dependent rows to new one
+func (h *UserHandler) RenameUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "RenameUserEndpoint"
+        // Input
+        input       struct {
+            Username    string `json:"username"`
+            NewUsername string `json:"new_username"`
+        }
+        // Response info
+        statusCode  = 500
+        message     = "Fail: rename user ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]string
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
+    }
+
+    // Decode request body
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate both usernames, IsValidUsernameFn errors start with "username:"
+    message = fmt.Sprintf("Fail: rename user '%s'", input.Username)
+    err = smodels.IsValidUsernameFn(input.Username)
+    if err == nil {
+        if err = smodels.IsValidUsernameFn(input.NewUsername); err != nil {
+            err = fmt.Errorf("new_%w", err)
+        }
+    }
+    if err == nil && input.NewUsername == input.Username {
+        err = fmt.Errorf("new_username: must differ from username")
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Rename, taken new username is 409
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = h.Store.Rename(ctx, input.Username, input.NewUsername)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    if statusCode == 200 {
+        returnData = map[string]string{"username":input.NewUsername}
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "rename", "user", input.Username, err)
+    respond(err); return
+}
+//}}} Rename user endpoint
+
+
 //{{{ Delete user endpoint
-func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+// Soft delete, user can be restored until it is purged (purge endpoint or retention job)
+func (h *UserHandler) DeleteUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.usernameEndpoint(w, r, "DeleteUserEndpoint", "delete", h.Store.Delete)
+}
+
+
+func (h *UserHandler) RestoreUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.usernameEndpoint(w, r, "RestoreUserEndpoint", "restore", h.Store.Restore)
+}
+
+
+// Hard delete, only soft deleted user can be purged
+func (h *UserHandler) PurgeUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.usernameEndpoint(w, r, "PurgeUserEndpoint", "purge", h.Store.Purge)
+}
+
+
+// Shared by endpoints whose body is only {"username"}, op gets validated username
+func (h *UserHandler) usernameEndpoint(
+    w http.ResponseWriter,
+    r *http.Request,
+    wrap string,
+    action string,
+    op func(ctx context.Context, username string) error,
+) {
     var (
-        wrap        = "DeleteUserEndpoint"
         // Input
         username    = ""
         // Response info
         statusCode  = 500
-        message     = "Fail: delete user ''"
+        message     = fmt.Sprintf("Fail: %s user ''", action)
         errMessage  = "Unknown error occured"
         ip          = r.RemoteAddr
         success     = false
@@ -203,13 +529,12 @@ func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
         } else {
             log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
         }
-        sapi.WriteJSONResponseFn(w, statusCode, message, errMessage, nil)
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, nil)
     }
 
     // Extract username from request body
-    err := sapi.ExtractJSONValueFn(r, "username", &username); if err != nil {
-        statusCode = 400
-        errMessage = "Invalid JSON"
+    err := sapi.ExtractBodyFn(r, map[string]interface{}{"username": &username}, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
         respond(err); return
     }
 
@@ -217,14 +542,408 @@ func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request, db *sql.DB) {
     err = smodels.IsValidUsernameFn(username)
     if err != nil {
         statusCode = 422
-        message = fmt.Sprintf("Fail: delete user '%s'", username)
+        message = fmt.Sprintf("Fail: %s user '%s'", action, username)
         errMessage = fmt.Sprintf("Invalid input format: %v", err)
         respond(err); return
     }
 
-    // Attempt to delete user
-    statusCode, err = DeleteUser(db, username)
-    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "delete", "user", username, err)
+    // Attempt operation
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    err = op(ctx, username)
+    statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, action, "user", username, err)
     respond(err); return
 }
 ///}}} Delete user endpoint
+
+
+//{{{ Verify user endpoint
+// Hash never leaves service, only enc_symkey is returned on success
+func (h *UserHandler) VerifyUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "VerifyUserEndpoint"
+        // Input
+        input       struct {
+            Username    string `json:"username"`
+            Proof       string `json:"proof"`
+        }
+        // Response info
+        statusCode  = 500
+        message     = "Fail: verify user ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]string
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
+    }
+
+    // Decode request body
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate username and proof format
+    message = fmt.Sprintf("Fail: verify user '%s'", input.Username)
+    err = smodels.IsValidUsernameFn(input.Username)
+    if err == nil {
+        err = smodels.IsValidHexStringFn(input.Proof, "proof", 64)
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Fetch user, missing user is compared against dummy hash and reported as 401
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    user, err := h.Store.Get(ctx, input.Username, []string{"username", "hash", "enc_symkey"})
+    storedHash := dummyHash
+    if err == nil {
+        storedHash = user.Hash
+    } else if !errors.Is(err, sdb.ErrNotFound) {
+        statusCode = sapi.StatusCodeFromErrFn(err, 200)
+        message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "verify", "user", input.Username, err)
+        respond(err); return
+    }
+
+    // Constant time compare
+    if !VerifyProofFn(storedHash, input.Proof) || user == nil {
+        statusCode = 401
+        if err == nil {
+            err = fmt.Errorf("%s: proof mismatch", wrap)
+        }
+    } else {
+        statusCode = 200
+        returnData = map[string]string{"username":user.Username, "enc_symkey":user.EncSymkey}
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "verify", "user", input.Username, err)
+    respond(err); return
+}
+//}}} Verify user endpoint
+
+
+//{{{ Rotate user credentials endpoint
+// Password change, new salt, hash and enc_symkey are all required and replaced together.
+//  Proof of current hash is checked same as verify, unknown user is 401 too
+func (h *UserHandler) RotateUserCredentialsEndpoint(w http.ResponseWriter, r *http.Request) {
+    var (
+        wrap        = "RotateUserCredentialsEndpoint"
+        // Input
+        input       struct {
+            Username    string `json:"username"`
+            Proof       string `json:"proof"`
+            smodels.UserCredentials
+        }
+        // Response info
+        statusCode  = 500
+        message     = "Fail: rotate user ''"
+        errMessage  = "Unknown error occurred"
+        returnData  map[string]string
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, returnData)
+    }
+
+    // Decode request body
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate username, proof and all of new credentials
+    message = fmt.Sprintf("Fail: rotate user '%s'", input.Username)
+    err = smodels.IsValidUsernameFn(input.Username)
+    if err == nil {
+        err = smodels.IsValidHexStringFn(input.Proof, "proof", 64)
+    }
+    if err == nil {
+        err = input.UserCredentials.Validate()
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Rotate, missing user and wrong proof are both invalid credentials
+    ctx, cancel := h.opContextFn(r)
+    defer cancel()
+    rotatedAt, err := h.Store.RotateCredentials(ctx, input.Username, input.Proof, input.UserCredentials)
+    if errors.Is(err, sdb.ErrNotFound) || errors.Is(err, ErrProofMismatch) {
+        statusCode = 401
+    } else {
+        statusCode = sapi.StatusCodeFromErrFn(err, 200)
+    }
+    if statusCode == 200 {
+        returnData = map[string]string{
+            "username":                 input.Username,
+            "credentials_rotated_at":   rotatedAt.UTC().Format(time.RFC3339Nano),
+        }
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "rotate", "user", input.Username, err)
+    respond(err); return
+}
+//}}} Rotate user credentials endpoint
+
+
+//{{{ Batch user endpoints
+// Upper bound of items in single batch, whole batch shares one transaction
+const MaxUserBatchSize = 100
+
+
+// "atomic" (default) rolls back whole batch on first failure, "partial" commits what succeeded
+var UserBatchModes = []string{"atomic", "partial"}
+
+
+// Outcome of single batch item, message/error are same as single item endpoint returns
+type userBatchResult struct {
+    Index       int     `json:"index"`
+    Status      int     `json:"status"`
+    Message     string  `json:"message"`
+    Error       string  `json:"error"`
+    Code        string  `json:"code,omitempty"`
+}
+
+
+// Turns raw item into op + name used in messages, error means item is invalid (422)
+type userBatchParseFn func(raw json.RawMessage) (UserOp, string, error)
+
+
+// Unknown key of item is named (strict mode), any other decode error is generic
+func batchItemErrFn(err error) error {
+    if errors.Is(err, sapi.ErrUnknownField) {
+        return err
+    }
+    return fmt.Errorf("item must be user object")
+}
+
+
+func (h *UserHandler) BatchCreateUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.batchEndpoint(w, r, "BatchCreateUserEndpoint", "create", 201, func(raw json.RawMessage) (UserOp, string, error) {
+        var user smodels.User
+        if err := sapi.DecodeJSONFn(raw, &user, h.Body); err != nil {
+            return UserOp{}, "", batchItemErrFn(err)
+        }
+        return UserOp{Kind: UserOpCreate, User: user}, user.Username, user.Validate()
+    })
+}
+
+
+// Items are same as /update/user body: username + at least 1 field to set, credentials all or none
+func (h *UserHandler) BatchUpdateUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.batchEndpoint(w, r, "BatchUpdateUserEndpoint", "update", 200, func(raw json.RawMessage) (UserOp, string, error) {
+        var data map[string]interface{}
+        if err := json.Unmarshal(raw, &data); err != nil || data == nil {
+            return UserOp{}, "", fmt.Errorf("item must be user object")
+        }
+        username, _ := data["username"].(string)
+        if h.Body.DisallowUnknown {
+            if err := sapi.CheckKnownKeysFn(data, smodels.UserFields); err != nil {
+                return UserOp{}, username, err
+            }
+        }
+        if err := smodels.RejectPartialCredentialsFn(data); err != nil {
+            return UserOp{}, username, err
+        }
+        filterdData := sapi.SanitizeKeysFn(data, smodels.UserFields)
+        if err := smodels.ValidateUserMap(filterdData); err != nil {
+            return UserOp{}, username, err
+        }
+        if username == "" {
+            return UserOp{}, username, fmt.Errorf("username: missing required field")
+        }
+        delete(filterdData, "username")
+        if len(filterdData) == 0 {
+            return UserOp{}, username, fmt.Errorf("must contain at least 1 field to update")
+        }
+        return UserOp{Kind: UserOpUpdate, Username: username, Data: filterdData}, username, nil
+    })
+}
+
+
+func (h *UserHandler) BatchDeleteUserEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.batchEndpoint(w, r, "BatchDeleteUserEndpoint", "delete", 200, func(raw json.RawMessage) (UserOp, string, error) {
+        var item struct {
+            Username    string  `json:"username"`
+        }
+        if err := sapi.DecodeJSONFn(raw, &item, h.Body); err != nil {
+            return UserOp{}, "", batchItemErrFn(err)
+        }
+        return UserOp{Kind: UserOpDelete, Username: item.Username}, item.Username, smodels.IsValidUsernameFn(item.Username)
+    })
+}
+
+
+// Shared flow of batch endpoints: decode, validate every item, run valid ones in
+//  one transaction, report per item. Overall status:
+//  atomic  -> succCode, or status of failed item (rest are 424)
+//  partial -> succCode, or 207 when some item failed
+func (h *UserHandler) batchEndpoint(
+    w http.ResponseWriter,
+    r *http.Request,
+    wrap string,
+    action string,
+    succCode int,
+    parseFn userBatchParseFn,
+){
+    var (
+        // Input
+        input       struct {
+            Mode        string              `json:"mode"`
+            Users       []json.RawMessage   `json:"users"`
+        }
+        ops         []UserOp
+        opIndex     []int       // position of op in input.Users
+        opNames     []string
+        // Response info
+        statusCode  = 500
+        message     = fmt.Sprintf("Fail: batch %s user ''", action)
+        errMessage  = "Unknown error occurred"
+        results     []userBatchResult
+        failErr     error       // first failure, drives overall status in atomic mode
+        failCode    = 0
+        ip          = r.RemoteAddr
+        success     = false
+    )
+
+    // Helper fn, logs result and writes JSON response
+    respond := func(err error) {
+        if success {
+            log.Printf("%s: %s | status: %d | IP: %s", wrap, message, statusCode, ip)
+        } else {
+            log.Printf("%s: %v | status: %d | IP: %s", wrap, err, statusCode, ip)
+        }
+        sapi.WriteCodedResponseFn(w, statusCode, message, errMessage, sapi.ErrorCodeFn(statusCode, "user", err), err, results)
+    }
+    // Helper fn, fills item result with same strings as single item endpoint
+    setResult := func(i int, code int, name string, err error) {
+        msg, errMsg, _ := sapi.MapStatusCodeFn(code, action, "user", name, err)
+        results[i] = userBatchResult{Index: i, Status: code, Message: msg, Error: errMsg, Code: sapi.ErrorCodeFn(code, "user", err)}
+        if code != succCode && code != 424 && failCode == 0 {
+            failCode, failErr = code, err
+        }
+    }
+
+    // Decode mode + raw items, items are decoded one by one
+    err := sapi.DecodeBodyFn(r, &input, h.Body); if err != nil {
+        statusCode, errMessage = sapi.BodyErrResponseFn(err)
+        respond(err); return
+    }
+
+    // Validate mode and batch size
+    if input.Mode == "" {
+        input.Mode = "atomic"
+    }
+    atomic := input.Mode == "atomic"
+    name := strconv.Itoa(len(input.Users))
+    message = fmt.Sprintf("Fail: batch %s user '%s'", action, name)
+    if !slices.Contains(UserBatchModes, input.Mode) {
+        err = fmt.Errorf("mode: must be one of %v", UserBatchModes)
+    }
+    if err == nil && (len(input.Users) < 1 || len(input.Users) > MaxUserBatchSize) {
+        err = fmt.Errorf("users: must contain between 1 and %d items", MaxUserBatchSize)
+    }
+    if err != nil {
+        statusCode = 422
+        errMessage = fmt.Sprintf("Invalid input format: %v", err)
+        respond(err); return
+    }
+
+    // Validate every item before touching DB
+    results = make([]userBatchResult, len(input.Users))
+    for i, raw := range input.Users {
+        op, itemName, err := parseFn(raw)
+        if err != nil {
+            setResult(i, 422, itemName, err)
+            continue
+        }
+        ops = append(ops, op)
+        opIndex = append(opIndex, i)
+        opNames = append(opNames, itemName)
+    }
+    // - atomic batch with invalid item is never started
+    if atomic && failCode != 0 {
+        for k, i := range opIndex {
+            setResult(i, 424, opNames[k], sdb.ErrRolledBack)
+        }
+        ops = nil
+    }
+
+    // Attempt batch, error here means nothing was committed
+    if len(ops) > 0 {
+        ctx, cancel := h.opContextFn(r)
+        defer cancel()
+        opErrs, err := h.Store.Batch(ctx, ops, atomic)
+        if err != nil {
+            results = nil
+            statusCode = sapi.StatusCodeFromErrFn(err, succCode)
+            message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "batch " + action, "user", name, err)
+            respond(err); return
+        }
+        for k, opErr := range opErrs {
+            setResult(opIndex[k], sapi.StatusCodeFromErrFn(opErr, succCode), opNames[k], opErr)
+        }
+    }
+
+    // Overall status
+    statusCode = succCode
+    if failCode != 0 {
+        statusCode = failCode
+        if !atomic {
+            statusCode = 207
+        }
+    }
+    message, errMessage, success = sapi.MapStatusCodeFn(statusCode, "batch " + action, "user", name, failErr)
+    respond(failErr); return
+}
+//}}} Batch user endpoints
+
+
+//{{{ User resource endpoints
+// REST routes next to verb-in-path ones: POST /users is CreateUserEndpoint,
+//  GET|PATCH|DELETE /users/{username} below. Path {username} is merged into JSON
+//  body, so validation, status codes and messages are same as verb routes
+
+
+// GET /users/{username}, optional ?fields=username,salt
+func (h *UserHandler) GetUserResourceEndpoint(w http.ResponseWriter, r *http.Request) {
+    values := map[string]interface{}{"username": r.PathValue("username")}
+    if fields := r.URL.Query().Get("fields"); fields != "" {
+        values["fields"] = strings.Split(fields, ",")
+    }
+    h.ReadUserEndpoint(w, sapi.MergeJSONBodyFn(r, values))
+}
+
+
+// PATCH /users/{username}, body holds only changed fields, If-Match works same as update
+func (h *UserHandler) PatchUserResourceEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.UpdateUserEndpoint(w, sapi.MergeJSONBodyFn(r, map[string]interface{}{"username": r.PathValue("username")}))
+}
+
+
+// DELETE /users/{username}, soft delete same as /delete/user
+func (h *UserHandler) DeleteUserResourceEndpoint(w http.ResponseWriter, r *http.Request) {
+    h.DeleteUserEndpoint(w, sapi.MergeJSONBodyFn(r, map[string]interface{}{"username": r.PathValue("username")}))
+}
+//}}} User resource endpoints
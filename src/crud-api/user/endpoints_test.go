@@ -0,0 +1,1539 @@
+package cruduser
+import (
+    "testing"
+    "context"
+    "time"
+    "encoding/json"
+    "net/http/httptest"
+    "strings"
+    "reflect"
+    "fmt"
+    "net/http"
+    "errors"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    sapi "github.com/FAH2S/diar4/src/shared/api"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    "github.com/FAH2S/diar4/src/crud-api/internal/testutil"
+)
+
+
+// Same suite as integration/endpoint_operations_test.go, backed by MemUserStore
+
+
+//{{{ CreateUserEndpoint
+func Test_CreateUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    validSalt :=        "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    validHash :=        "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    validEncSymkey :=   "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "CreateUser",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"%s",
+                "hash":"%s",
+                "enc_symkey":"%s"
+            }`, validSalt, validHash, validEncSymkey),
+            ExpectedStatusCode: 201,
+            ExpectedMessage:    "Success: create user 'test_user_endpoint1'",
+            ExpectedError:      "",
+            ExpectedData:       nil,
+        }, {
+            Name:               "MalformedJSON",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"%s",
+                "hash":"0c8f
+                `, validSalt),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: create user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        }, {
+            Name:               "UserAlreadyExist",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"%s",
+                "hash":"%s",
+                "enc_symkey":"%s"
+            }`, validSalt, validHash, validEncSymkey),
+            ExpectedStatusCode: 409,
+            ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
+            ExpectedError:      "User already exist",
+            ExpectedData:       nil,
+        }, {
+            Name:               "UnprocessableUsername",
+            Body:               fmt.Sprintf(`{
+                "username":"fishy user |._.|><|",
+                "salt":"%s",
+                "hash":"%s",
+                "enc_symkey":"%s"
+            }`, validSalt, validHash, validEncSymkey),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create user 'fishy user |._.|><|'",
+            ExpectedError:      "Invalid input format: username: contains invalid characters",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"username", "username_chars", "^[a-zA-Z0-9_]+$", "username: contains invalid characters"}),
+        }, {
+            Name:               "UnprocessableSalt",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"344feecf40d261e0341a87aa5df6d49c4e31",
+                "hash":"%s",
+                "enc_symkey":"%s"
+            }`, validHash, validEncSymkey),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
+            ExpectedError:      "Invalid input format: salt: length must be exactly 64 char long",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"}),
+        }, {
+            Name:               "UnprocessableHash",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"%s",
+                "hash":"^c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+                "enc_symkey":"%s"
+            }`, validSalt, validEncSymkey),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
+            ExpectedError:      "Invalid input format: hash: contains invalid characters",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"hash", "hex_chars", "^[0-9a-fA-F]+$", "hash: contains invalid characters"}),
+        }, {
+            Name:               "UnprocessableEncSymkey",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_endpoint1",
+                "salt":"%s",
+                "hash":"%s",
+                "enc_symkey":""
+            }`, validSalt, validHash),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create user 'test_user_endpoint1'",
+            ExpectedError:      "Invalid input format: enc_symkey: length must be exactly 120 char long",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"}),
+        }, {
+            Name:               "UnprocessableAllFields",
+            Body:               `{"username":"x","salt":"zz","hash":"0c8f","enc_symkey":""}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: create user 'x'",
+            ExpectedError:      "Invalid input format: username: length must be between 3 and 30 char long; salt: length must be exactly 64 char long; " +
+                "hash: length must be exactly 64 char long; enc_symkey: length must be exactly 120 char long",
+            ExpectedData:       testutil.ValidationDataFn(
+                [4]string{"username", "username_length", "3..30 chars", "username: length must be between 3 and 30 char long"},
+                [4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"},
+                [4]string{"hash", "hex_length", "64 chars", "hash: length must be exactly 64 char long"},
+                [4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"},
+            ),
+        },
+    }
+
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/create/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.CreateUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+}
+//}}} CreateUserEndpoint
+
+
+//{{{ ReadUserEndpoint
+func Test_ReadUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create some user that will be fetched
+    username := "test_user_read_user1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := handler.Store.Insert(context.Background(), user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
+    }
+
+    // Define tests and its expected results
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "ReadUser",
+            Body:               fmt.Sprintf(`{"username":"%s"}`, username),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: read user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{
+                "username":username,
+                "salt":user.Salt,
+            },
+        },{
+            Name:               "ReadUserFields",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["salt"]}`, username),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: read user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{
+                "salt":user.Salt,
+            },
+        },{
+            Name:               "SecretFieldHash",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["salt","hash"]}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+            ExpectedError:      "Invalid input format: fields: field \"hash\" can't be read",
+            ExpectedData:       nil,
+        },{
+            Name:               "SecretFieldEncSymkey",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["enc_symkey"]}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+            ExpectedError:      "Invalid input format: fields: field \"enc_symkey\" can't be read",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownField",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":["username","password"]}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+            ExpectedError:      "Invalid input format: fields: unknown field \"password\"",
+            ExpectedData:       nil,
+        },{
+            Name:               "EmptyFields",
+            Body:               fmt.Sprintf(`{"username":"%s","fields":[]}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+            ExpectedError:      "Invalid input format: fields: must contain at least 1 field",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               fmt.Sprintf(`{"username":"%s`, username),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: read user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "NotFound",
+            Body:               `{"username":"not_found"}`,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: read user 'not_found'",
+            ExpectedError:      "User not found, dosen't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableUsername",
+            Body:               `{"username":"fishy user |._.|><|"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: read user 'fishy user |._.|><|'",
+            ExpectedError:      "Invalid input format: username: contains invalid characters",
+            ExpectedData:       nil,
+        },
+    }
+
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/read/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.ReadUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+}
+
+//}}} ReadUserEndpoint
+
+
+//{{{ UpdateUserEndpoint
+func Test_UpdateUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create some user that will be updated 
+    username := "test_user_update1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := handler.Store.Insert(context.Background(), user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
+    }
+    // Credentials are set all together, partial set is refused
+    newSalt := "111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    credsFn := func(salt, hash, encSymkey string) string {
+        return fmt.Sprintf(`"salt":"%s","hash":"%s","enc_symkey":"%s"`, salt, hash, encSymkey)
+    }
+    requiredFn := func(field string) [4]string {
+        return [4]string{field, "required_together", "salt+hash+enc_symkey",
+            fmt.Sprintf("field %q is required, salt, hash and enc_symkey change together", field)}
+    }
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "UpdateUser",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(newSalt, testSalt, testEncSymkey)),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    "Success: update user 'test_user_update1'",
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{
+                "username":"test_user_update1",
+            },
+        }, {
+            Name:               "MalformedJson",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_update1",
+                "salt":"111feecf4
+            `),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        }, {
+            Name:               "UpdateUserNotFound",
+            Body:               fmt.Sprintf(`{"username":"test_user_update",%s}`, credsFn(newSalt, testSalt, testEncSymkey)),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: update user 'test_user_update'",
+            ExpectedError:      "User not found, dosen't exist",
+            ExpectedData:       nil,
+        }, {
+            Name:               "PartialCredentialsSalt",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1","salt":"%s"}`, testSalt),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      `Invalid input format: field "hash" is required, salt, hash and enc_symkey change together; ` +
+                `field "enc_symkey" is required, salt, hash and enc_symkey change together`,
+            ExpectedData:       testutil.ValidationDataFn(requiredFn("hash"), requiredFn("enc_symkey")),
+        }, {
+            Name:               "PartialCredentialsSaltHash",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1","salt":"%s","hash":"%s"}`, testSalt, testHash),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      `Invalid input format: field "enc_symkey" is required, salt, hash and enc_symkey change together`,
+            ExpectedData:       testutil.ValidationDataFn(requiredFn("enc_symkey")),
+        }, {
+            Name:               "InvalidInputFormatSalt",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn("111feecf", testHash, testEncSymkey)),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Invalid input format: salt",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"}),
+        }, {
+            Name:               "InvalidInputFormatHash",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(testSalt, "111feecf", testEncSymkey)),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Invalid input format: hash",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"hash", "hex_length", "64 chars", "hash: length must be exactly 64 char long"}),
+        }, {
+            Name:               "InvalidInputFormatEncSymkey",
+            Body:               fmt.Sprintf(`{"username":"test_user_update1",%s}`, credsFn(testSalt, testHash, "111feecf")),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Invalid input format: enc_symkey",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"}),
+        }, {
+            Name:               "InvalidInputFormatAllFields",
+            Body:               `{"username":"test_user_update1","enc_symkey":"ab","hash":5,"salt":"zz"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      `Invalid input format: salt: length must be exactly 64 char long; field "hash" must be string; enc_symkey: length must be exactly 120 char long`,
+            ExpectedData:       testutil.ValidationDataFn(
+                [4]string{"salt", "hex_length", "64 chars", "salt: length must be exactly 64 char long"},
+                [4]string{"hash", "type", "string", `field "hash" must be string`},
+                [4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"},
+            ),
+        }, {
+            Name:               "MissingUsername",
+            Body:               fmt.Sprintf(`{%s}`, credsFn(newSalt, testSalt, testEncSymkey)),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Missing required field: 'username'",
+            ExpectedData:       nil,
+        }, {
+            Name:               "InvalidInputNotEnoughFileds",
+            Body:               fmt.Sprintf(`{
+                "username":"test_user_update1"
+            }`),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: update user ''",
+            ExpectedError:      "Invalid input: must contain at least 2 fields total",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/update/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.UpdateUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+    // Only first update was applied, refused ones left credentials as they were
+    stored, err := handler.Store.Get(context.Background(), username, nil)
+    if err != nil || stored.Salt != newSalt || stored.Hash != testSalt || stored.EncSymkey != testEncSymkey {
+        t.Errorf("Wrong credentials after updates:\nGot:\t%+v (%v)", stored, err)
+    }
+}
+
+//}}} UpdateUserEndpoint
+
+
+//{{{ ReadUserEndpoint ETag
+func Test_ReadUserEndpoint_ETag(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    username := "test_user_etag1"
+    if err := handler.Store.Insert(context.Background(), newTestUserFn(username)); err != nil {
+        t.Fatalf("Failed to create user that will be read: %v", err)
+    }
+    // Read gives current version as ETag
+    req := httptest.NewRequest("POST", "/read/user", strings.NewReader(fmt.Sprintf(`{"username":"%s"}`, username)))
+    resp := httptest.NewRecorder()
+    handler.ReadUserEndpoint(resp, req)
+    if etag := resp.Header().Get("ETag"); etag != `"1"` {
+        t.Fatalf("Wrong read ETag:\nExpected:\t%s\nGot:\t\t%s", `"1"`, etag)
+    }
+}
+//}}} ReadUserEndpoint ETag
+
+
+//{{{ UpdateUserEndpoint If-Match
+func Test_UpdateUserEndpoint_IfMatch(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    username := "test_user_etag3"
+    if err := handler.Store.Insert(context.Background(), newTestUserFn(username)); err != nil {
+        t.Fatalf("Failed to create user that will be updated: %v", err)
+    }
+    body := fmt.Sprintf(`{"username":"%s","salt":"%s","hash":"%s","enc_symkey":"%s"}`, username, testHash, testSalt, testEncSymkey)
+    tests := []struct {
+        ifMatch         string
+        expectedETag    string
+        tc              testutil.EndpointTestCase
+    }{
+        {
+            ifMatch:        `"1"`,
+            expectedETag:   `"2"`,
+            tc:             testutil.EndpointTestCase{
+                Name:               "MatchingVersion",
+                Body:               body,
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: update user '%s'", username),
+                ExpectedError:      "",
+                ExpectedData:       map[string]any{"username": username},
+            },
+        }, {
+            ifMatch:        `"1"`,
+            expectedETag:   "",
+            tc:             testutil.EndpointTestCase{
+                Name:               "StaleVersion",
+                Body:               body,
+                ExpectedStatusCode: 412,
+                ExpectedMessage:    fmt.Sprintf("Fail: update user '%s'", username),
+                ExpectedError:      "User was modified, read it again and retry",
+                ExpectedData:       nil,
+            },
+        }, {
+            ifMatch:        "2",
+            expectedETag:   "",
+            tc:             testutil.EndpointTestCase{
+                Name:               "MalformedIfMatch",
+                Body:               body,
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    fmt.Sprintf("Fail: update user '%s'", username),
+                ExpectedError:      `Invalid input format: If-Match: must be quoted version ex.: "3" or list of them`,
+                ExpectedData:       nil,
+            },
+        }, {
+            // Strong comparison, weak tag never matches even when version is current
+            ifMatch:        `W/"2"`,
+            expectedETag:   "",
+            tc:             testutil.EndpointTestCase{
+                Name:               "WeakOnly",
+                Body:               body,
+                ExpectedStatusCode: 412,
+                ExpectedMessage:    fmt.Sprintf("Fail: update user '%s'", username),
+                ExpectedError:      `If-Match: weak entity tag never matches`,
+                ExpectedData:       nil,
+            },
+        }, {
+            ifMatch:        `"1", W/"2", "2"`,
+            expectedETag:   `"3"`,
+            tc:             testutil.EndpointTestCase{
+                Name:               "ListWithCurrent",
+                Body:               body,
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: update user '%s'", username),
+                ExpectedError:      "",
+                ExpectedData:       map[string]any{"username": username},
+            },
+        }, {
+            ifMatch:        `"1", "2"`,
+            expectedETag:   "",
+            tc:             testutil.EndpointTestCase{
+                Name:               "ListStale",
+                Body:               body,
+                ExpectedStatusCode: 412,
+                ExpectedMessage:    fmt.Sprintf("Fail: update user '%s'", username),
+                ExpectedError:      "User was modified, read it again and retry",
+                ExpectedData:       nil,
+            },
+        }, {
+            ifMatch:        "",
+            expectedETag:   `"4"`,
+            tc:             testutil.EndpointTestCase{
+                Name:               "Unconditional",
+                Body:               body,
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: update user '%s'", username),
+                ExpectedError:      "",
+                ExpectedData:       map[string]any{"username": username},
+            },
+        },
+    }
+    // Iterate, cases build on each other
+    for _, tt := range tests {
+        t.Run(tt.tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest("POST", "/update/user", strings.NewReader(tt.tc.Body))
+            if tt.ifMatch != "" {
+                req.Header.Set("If-Match", tt.ifMatch)
+            }
+            resp := httptest.NewRecorder()
+            handler.UpdateUserEndpoint(resp, req)
+            testutil.AssertResponse(t, resp, tt.tc)
+            if etag := resp.Header().Get("ETag"); etag != tt.expectedETag {
+                t.Errorf("Wrong ETag:\nExpected:\t%s\nGot:\t\t%s", tt.expectedETag, etag)
+            }
+        })
+    }
+}
+//}}} UpdateUserEndpoint If-Match
+
+
+//{{{ expectedVersionFn
+func Test_ExpectedVersionFn(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    username := "test_user_etag2"
+    if err := handler.Store.Insert(context.Background(), newTestUserFn(username)); err != nil {
+        t.Fatalf("Failed to create user: %v", err)
+    }
+    tests := []struct {
+        Name                string
+        Username            string
+        Versions            []int64
+        ExpectedVersion     int64
+        ExpectedErr         error
+    }{
+        {"Any",             username,           nil,                0,  nil},
+        {"Single",          username,           []int64{4},         4,  nil},
+        {"ListCurrent",     username,           []int64{4, 1},      1,  nil},
+        {"ListStale",       username,           []int64{4, 5},      4,  nil},
+        {"ListNoUser",      "test_user_none",   []int64{4, 5},      0,  sdb.ErrNotFound},
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            version, err := handler.expectedVersionFn(context.Background(), tc.Username, tc.Versions)
+            if !errors.Is(err, tc.ExpectedErr) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.ExpectedErr, err)
+            }
+            if version != tc.ExpectedVersion {
+                t.Errorf("Wrong version:\nExpected:\t%d\nGot:\t\t%d", tc.ExpectedVersion, version)
+            }
+        })
+    }
+}
+//}}} expectedVersionFn
+
+
+//{{{ RenameUserEndpoint
+func Test_RenameUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create user that will be renamed + one whose name is taken
+    for _, username := range []string{"test_user_rename1", "test_user_taken1"} {
+        if err := handler.Store.Insert(context.Background(), newTestUserFn(username)); err != nil {
+            t.Fatalf("Failed to create user that will be renamed: %v", err)
+        }
+    }
+    // Define tests and its expected results, cases build on each other
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "Collision",
+            Body:               `{"username":"test_user_rename1","new_username":"test_user_taken1"}`,
+            ExpectedStatusCode: 409,
+            ExpectedMessage:    "Fail: rename user 'test_user_rename1'",
+            ExpectedError:      "User already exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableNewUsername",
+            Body:               `{"username":"test_user_rename1","new_username":"fishy user |._.|><|"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: rename user 'test_user_rename1'",
+            ExpectedError:      "Invalid input format: new_username: contains invalid characters",
+            ExpectedData:       nil,
+        },{
+            Name:               "SameUsername",
+            Body:               `{"username":"test_user_rename1","new_username":"test_user_rename1"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: rename user 'test_user_rename1'",
+            ExpectedError:      "Invalid input format: new_username: must differ from username",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"username":"test_user_rename1`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: rename user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "RenameUser",
+            Body:               `{"username":"test_user_rename1","new_username":"test_user_renamed1"}`,
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    "Success: rename user 'test_user_rename1'",
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"username":"test_user_renamed1"},
+        },{
+            Name:               "OldUsernameNotFound",
+            Body:               `{"username":"test_user_rename1","new_username":"test_user_renamed2"}`,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    "Fail: rename user 'test_user_rename1'",
+            ExpectedError:      "User not found, dosen't exist",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/rename/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.RenameUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+    // Renamed user keeps its credentials
+    user, err := handler.Store.Get(context.Background(), "test_user_renamed1", nil)
+    if err != nil || user.Hash != testHash {
+        t.Errorf("Renamed user lost data: %+v (%v)", user, err)
+    }
+}
+//}}} RenameUserEndpoint
+
+
+//{{{ DeleteUserEndpoint
+func Test_DeleteUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create some user that will be fetched
+    username := "test_user_delete1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := handler.Store.Insert(context.Background(), user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
+    }
+    // Define tests and its expected results
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "DeleteUser",
+            Body:               fmt.Sprintf(`{"username":"%s"}`, username),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: delete user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               fmt.Sprintf(`{"username":"%s`, username),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: delete user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "NotFound",
+            Body:               fmt.Sprintf(`{"username":"%s"}`, username),
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: delete user '%s'", username),
+            ExpectedError:      "User not found, dosen't exist",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableUsername",
+            Body:               `{"username":"fishy user |._.|><|"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: delete user 'fishy user |._.|><|'",
+            ExpectedError:      "Invalid input format: username: contains invalid characters",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/delete/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.DeleteUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+}
+
+//}}} DeleteUserEndpoint
+
+
+//{{{ Restore/PurgeUserEndpoint
+func Test_RestorePurgeUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    username := "test_user_restore1"
+    if err := handler.Store.Insert(context.Background(), newTestUserFn(username)); err != nil {
+        t.Fatalf("Failed to create user that will be restored: %v", err)
+    }
+    body := fmt.Sprintf(`{"username":"%s"}`, username)
+    endpoints := map[string]http.HandlerFunc{
+        "delete":   handler.DeleteUserEndpoint,
+        "restore":  handler.RestoreUserEndpoint,
+        "purge":    handler.PurgeUserEndpoint,
+    }
+    // Define tests and its expected results, cases build on each other
+    tests := []struct {
+        action      string
+        tc          testutil.EndpointTestCase
+    }{
+        {"restore", testutil.EndpointTestCase{
+            Name:               "RestoreActive",
+            Body:               body,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: restore user '%s'", username),
+            ExpectedError:      "User not found, dosen't exist",
+        }}, {"purge", testutil.EndpointTestCase{
+            Name:               "PurgeActive",
+            Body:               body,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: purge user '%s'", username),
+            ExpectedError:      "User not found, dosen't exist",
+        }}, {"delete", testutil.EndpointTestCase{
+            Name:               "SoftDelete",
+            Body:               body,
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: delete user '%s'", username),
+        }}, {"restore", testutil.EndpointTestCase{
+            Name:               "Restore",
+            Body:               body,
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: restore user '%s'", username),
+        }}, {"delete", testutil.EndpointTestCase{
+            Name:               "DeleteAgain",
+            Body:               body,
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: delete user '%s'", username),
+        }}, {"purge", testutil.EndpointTestCase{
+            Name:               "Purge",
+            Body:               body,
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: purge user '%s'", username),
+        }}, {"restore", testutil.EndpointTestCase{
+            Name:               "RestorePurged",
+            Body:               body,
+            ExpectedStatusCode: 404,
+            ExpectedMessage:    fmt.Sprintf("Fail: restore user '%s'", username),
+            ExpectedError:      "User not found, dosen't exist",
+        }}, {"purge", testutil.EndpointTestCase{
+            Name:               "UnprocessableUsername",
+            Body:               `{"username":"fishy user |._.|><|"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: purge user 'fishy user |._.|><|'",
+            ExpectedError:      "Invalid input format: username: contains invalid characters",
+        }},
+    }
+    // Iterate
+    for _, tt := range tests {
+        t.Run(tt.tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/" + tt.action + "/user", strings.NewReader(tt.tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            endpoints[tt.action](resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tt.tc)
+        })
+    }
+}
+//}}} Restore/PurgeUserEndpoint
+
+
+
+
+//{{{ VerifyUserEndpoint
+func Test_VerifyUserEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create some user that will be verified
+    username := "test_user_verify1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := handler.Store.Insert(context.Background(), user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be verified: %v", err)
+    }
+    wrongProof := "1c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    // Define tests and its expected results
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "VerifyUser",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, user.Hash),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: verify user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"username":username, "enc_symkey":user.EncSymkey},
+        },{
+            Name:               "VerifyUserUpperCaseProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, strings.ToUpper(user.Hash)),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: verify user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       map[string]any{"username":username, "enc_symkey":user.EncSymkey},
+        },{
+            Name:               "WrongProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, wrongProof),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    fmt.Sprintf("Fail: verify user '%s'", username),
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownUserSameAsWrongProof",
+            Body:               fmt.Sprintf(`{"username":"not_found","proof":"%s"}`, dummyHash),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    "Fail: verify user 'not_found'",
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               fmt.Sprintf(`{"username":"%s`, username),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: verify user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"abc"}`, username),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: verify user '%s'", username),
+            ExpectedError:      "Invalid input format: proof: length must be exactly 64 char long",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnprocessableUsername",
+            Body:               fmt.Sprintf(`{"username":"fishy user |._.|><|","proof":"%s"}`, user.Hash),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: verify user 'fishy user |._.|><|'",
+            ExpectedError:      "Invalid input format: username: contains invalid characters",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/verify/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.VerifyUserEndpoint(resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc)
+            // Hash must never be part of response
+            if strings.Contains(resp.Body.String(), `"hash"`) {
+                t.Errorf("Response leaks hash: %s", resp.Body.String())
+            }
+        })
+    }
+}
+
+//}}} VerifyUserEndpoint
+
+
+//{{{ RotateUserCredentialsEndpoint
+func Test_RotateUserCredentialsEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    // Create some user whose credentials will be rotated
+    username := "test_user_rotate1"
+    user := newTestUserFn(username)
+    if err := handler.Store.Insert(context.Background(), user); err != nil {
+        t.Fatalf("Failed to create user that will be rotated: %v", err)
+    }
+    newCreds := fmt.Sprintf(`"salt":"%s","hash":"%s","enc_symkey":"%s"`, testHash, testSalt, testEncSymkey)
+    // Define tests and its expected results, cases build on each other
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "MissingEncSymkey",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s","salt":"%s","hash":"%s"}`,
+                                    username, testHash, testHash, testSalt),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: rotate user '%s'", username),
+            ExpectedError:      "Invalid input format: enc_symkey: length must be exactly 120 char long",
+            ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "hex_length", "120 chars", "enc_symkey: length must be exactly 120 char long"}),
+        },{
+            Name:               "UnprocessableProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"abc",%s}`, username, newCreds),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    fmt.Sprintf("Fail: rotate user '%s'", username),
+            ExpectedError:      "Invalid input format: proof: length must be exactly 64 char long",
+            ExpectedData:       nil,
+        },{
+            Name:               "WrongProof",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s",%s}`, username, testSalt, newCreds),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    fmt.Sprintf("Fail: rotate user '%s'", username),
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },{
+            Name:               "UnknownUserSameAsWrongProof",
+            Body:               fmt.Sprintf(`{"username":"not_found","proof":"%s",%s}`, testHash, newCreds),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    "Fail: rotate user 'not_found'",
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               fmt.Sprintf(`{"username":"%s`, username),
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: rotate user ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },{
+            Name:               "RotateUser",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s",%s}`, username, testHash, newCreds),
+            ExpectedStatusCode: 200,
+            ExpectedMessage:    fmt.Sprintf("Success: rotate user '%s'", username),
+            ExpectedError:      "",
+            ExpectedData:       nil,    // rotation time checked below
+        },{
+            Name:               "OldProofAfterRotation",
+            Body:               fmt.Sprintf(`{"username":"%s","proof":"%s",%s}`, username, testHash, newCreds),
+            ExpectedStatusCode: 401,
+            ExpectedMessage:    fmt.Sprintf("Fail: rotate user '%s'", username),
+            ExpectedError:      "Invalid credentials",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp
+            req := httptest.NewRequest("POST", "/rotate/user", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            handler.RotateUserCredentialsEndpoint(resp, req)
+            if tc.ExpectedStatusCode != 200 {
+                testutil.AssertResponse(t, resp, tc)
+                return
+            }
+            // Check success, data has generated rotation time
+            var bodyResp struct {
+                sapi.APIResponse
+                Data    map[string]string `json:"data"`
+            }
+            if err := json.Unmarshal(resp.Body.Bytes(), &bodyResp); err != nil {
+                t.Fatalf("Failed to parse JSON response: %v", err)
+            }
+            if resp.Code != 200 || bodyResp.Message != tc.ExpectedMessage || bodyResp.Data["username"] != username {
+                t.Fatalf("Unexpected response: %d %s", resp.Code, resp.Body.String())
+            }
+            if _, err := time.Parse(time.RFC3339Nano, bodyResp.Data["credentials_rotated_at"]); err != nil {
+                t.Errorf("Wrong credentials_rotated_at: %v", err)
+            }
+        })
+    }
+    // New hash is now valid proof
+    req := httptest.NewRequest("POST", "/verify/user",
+        strings.NewReader(fmt.Sprintf(`{"username":"%s","proof":"%s"}`, username, testSalt)))
+    resp := httptest.NewRecorder()
+    handler.VerifyUserEndpoint(resp, req)
+    if resp.Code != 200 {
+        t.Errorf("Verify with new hash failed: %d %s", resp.Code, resp.Body.String())
+    }
+}
+//}}} RotateUserCredentialsEndpoint
+
+
+
+
+//{{{ ListUsersEndpoint
+func Test_ListUsersEndpoint(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    for _, username := range []string{"test_user_list_c", "test_user_list_a", "test_user_list_b"} {
+        user := smodels.User{
+            Username:   username,
+            Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+            Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+            EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        }
+        if err := handler.Store.Insert(context.Background(), user); err != nil {
+            t.Fatalf("Failed to create user that will be listed: %v", err)
+        }
+    }
+    // Calls endpoint, returns decoded response
+    listFn := func(body string) (int, sapi.APIResponse) {
+        req := httptest.NewRequest("POST", "/list/users", strings.NewReader(body))
+        resp := httptest.NewRecorder()
+        handler.ListUsersEndpoint(resp, req)
+        var bodyResp sapi.APIResponse
+        if err := json.Unmarshal(resp.Body.Bytes(), &bodyResp); err != nil {
+            t.Fatalf("Failed to parse JSON response as APIResponse: %v", err)
+        }
+        return resp.Result().StatusCode, bodyResp
+    }
+    pageFn := func(bodyResp sapi.APIResponse) ([]string, string) {
+        data, _ := bodyResp.Data.(map[string]any)
+        users, _ := data["users"].([]any)
+        names := []string{}
+        for _, user := range users {
+            names = append(names, user.(map[string]any)["username"].(string))
+        }
+        cursor, _ := data["next_cursor"].(string)
+        return names, cursor
+    }
+
+    // Walk pages of 2 by username
+    code, bodyResp := listFn(`{"limit":2}`)
+    names, cursor := pageFn(bodyResp)
+    if code != 200 || !reflect.DeepEqual(names, []string{"test_user_list_a", "test_user_list_b"}) || cursor == "" {
+        t.Fatalf("Wrong first page: %d %+v", code, bodyResp)
+    }
+    if bodyResp.Message != "Success: list users 'username'" {
+        t.Errorf("Unexpected message:\nGot:\t%s\nWant:\t%s", bodyResp.Message, "Success: list users 'username'")
+    }
+    code, bodyResp = listFn(fmt.Sprintf(`{"limit":2,"cursor":"%s"}`, cursor))
+    names, lastCursor := pageFn(bodyResp)
+    if code != 200 || !reflect.DeepEqual(names, []string{"test_user_list_c"}) || lastCursor != "" {
+        t.Fatalf("Wrong last page: %d %+v", code, bodyResp)
+    }
+    // created_at order matches store, clock ties fall back to username
+    byCreated, _ := handler.Store.List(context.Background(), "created_at", nil, 10)
+    expected := []string{}
+    for _, user := range byCreated {
+        expected = append(expected, user.Username)
+    }
+    code, bodyResp = listFn(`{"order":"created_at"}`)
+    names, _ = pageFn(bodyResp)
+    if code != 200 || !reflect.DeepEqual(names, expected) {
+        t.Errorf("Wrong created_at page: %d %+v", code, bodyResp)
+    }
+
+    // Cursor from other key is rejected as tampered
+    foreign := NewUserHandler(NewMemUserStore())
+    foreignCursor := foreign.encodeCursor("username", smodels.UserListItem{Username: "test_user_list_a"})
+    tests := []testutil.EndpointTestCase{
+        {
+            Name:               "UnknownOrder",
+            Body:               `{"order":"hash"}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'hash'",
+            ExpectedError:      "Invalid input format: order: must be one of [username created_at]",
+            ExpectedData:       nil,
+        },{
+            Name:               "LimitTooBig",
+            Body:               fmt.Sprintf(`{"limit":%d}`, MaxUserListLimit+1),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'username'",
+            ExpectedError:      fmt.Sprintf("Invalid input format: limit: must be between 1 and %d", MaxUserListLimit),
+            ExpectedData:       nil,
+        },{
+            Name:               "NegativeLimit",
+            Body:               `{"limit":-1}`,
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'username'",
+            ExpectedError:      "Invalid input format: limit: must be between 1",
+            ExpectedData:       nil,
+        },{
+            Name:               "TamperedCursor",
+            Body:               fmt.Sprintf(`{"cursor":"x%s"}`, cursor),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'username'",
+            ExpectedError:      "Invalid input format: cursor: VerifyCursorFn: invalid or tampered cursor",
+            ExpectedData:       nil,
+        },{
+            Name:               "ForeignCursor",
+            Body:               fmt.Sprintf(`{"cursor":"%s"}`, foreignCursor),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'username'",
+            ExpectedError:      "invalid or tampered cursor",
+            ExpectedData:       nil,
+        },{
+            Name:               "CursorOtherOrder",
+            Body:               fmt.Sprintf(`{"order":"created_at","cursor":"%s"}`, cursor),
+            ExpectedStatusCode: 422,
+            ExpectedMessage:    "Fail: list users 'created_at'",
+            ExpectedError:      "Invalid input format: cursor: issued for order 'username'",
+            ExpectedData:       nil,
+        },{
+            Name:               "MalformedJSON",
+            Body:               `{"limit":`,
+            ExpectedStatusCode: 400,
+            ExpectedMessage:    "Fail: list users ''",
+            ExpectedError:      "Invalid JSON",
+            ExpectedData:       nil,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            req := httptest.NewRequest("POST", "/list/users", strings.NewReader(tc.Body))
+            resp := httptest.NewRecorder()
+            handler.ListUsersEndpoint(resp, req)
+            testutil.AssertResponse(t, resp, tc)
+        })
+    }
+}
+//}}} ListUsersEndpoint
+
+
+//{{{ Batch user endpoints
+func Test_BatchUserEndpoints(t *testing.T){
+    userJSONFn := func(username string) string {
+        return fmt.Sprintf(`{"username":"%s","salt":"%s","hash":"%s","enc_symkey":"%s"}`,
+            username, testSalt, testHash, testEncSymkey)
+    }
+    // Credentials of update item, set all together
+    creds := fmt.Sprintf(`"salt":"%s","hash":"%s","enc_symkey":"%s"`, testHash, testSalt, testEncSymkey)
+    resultFn := func(i int, status int, code string, message string, errMessage string) map[string]any {
+        result := map[string]any{"index": float64(i), "status": float64(status), "message": message, "error": errMessage}
+        if code != "" {
+            result["code"] = code
+        }
+        return result
+    }
+    tests := []struct {
+        endpoint            func(h *UserHandler) http.HandlerFunc
+        tc                  testutil.EndpointTestCase
+        expectedUsers       []string    // stored after call, seeded with test_batch_seed
+    }{
+        {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchCreateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "CreateAtomic",
+                Body:               fmt.Sprintf(`{"users":[%s,%s]}`, userJSONFn("test_batch_a"), userJSONFn("test_batch_b")),
+                ExpectedStatusCode: 201,
+                ExpectedMessage:    "Success: batch create user '2'",
+                ExpectedError:      "",
+                ExpectedData:       []any{
+                    resultFn(0, 201, "", "Success: create user 'test_batch_a'", ""),
+                    resultFn(1, 201, "", "Success: create user 'test_batch_b'", ""),
+                },
+            },
+            expectedUsers:  []string{"test_batch_a", "test_batch_b", "test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchCreateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "CreateAtomicConflict",
+                Body:               fmt.Sprintf(`{"users":[%s,%s]}`, userJSONFn("test_batch_a"), userJSONFn("test_batch_seed")),
+                ExpectedStatusCode: 409,
+                ExpectedMessage:    "Fail: batch create user '2'",
+                ExpectedError:      "User already exist",
+                ExpectedData:       []any{
+                    resultFn(0, 424, "user.not_applied", "Fail: create user 'test_batch_a'", "Not applied, other item in transaction failed"),
+                    resultFn(1, 409, "user.conflict", "Fail: create user 'test_batch_seed'", "User already exist"),
+                },
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchCreateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "CreateAtomicInvalidItem",
+                Body:               fmt.Sprintf(`{"users":[%s,"oops"]}`, userJSONFn("test_batch_a")),
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: batch create user '2'",
+                ExpectedError:      "Invalid input format: item must be user object",
+                ExpectedData:       []any{
+                    resultFn(0, 424, "user.not_applied", "Fail: create user 'test_batch_a'", "Not applied, other item in transaction failed"),
+                    resultFn(1, 422, "validation.invalid", "Fail: create user ''", "Invalid input format: item must be user object"),
+                },
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchCreateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "CreatePartial",
+                Body:               fmt.Sprintf(`{"mode":"partial","users":[%s,%s,%s]}`,
+                    userJSONFn("test_batch_a"), userJSONFn("test_batch_seed"), userJSONFn("x")),
+                ExpectedStatusCode: 207,
+                ExpectedMessage:    "Partial: batch create user '3'",
+                ExpectedError:      "Some items failed, see data",
+                ExpectedData:       []any{
+                    resultFn(0, 201, "", "Success: create user 'test_batch_a'", ""),
+                    resultFn(1, 409, "user.conflict", "Fail: create user 'test_batch_seed'", "User already exist"),
+                    resultFn(2, 422, "validation.username_length", "Fail: create user 'x'", "Invalid input format: username: length must be between 3 and 30 char long"),
+                },
+            },
+            expectedUsers:  []string{"test_batch_a", "test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchUpdateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "UpdatePartial",
+                Body:               fmt.Sprintf(`{"mode":"partial","users":[{"username":"test_batch_seed",%s},{"username":"not_found",%s},{"username":"test_batch_seed","hash":"%s"},{"username":"test_batch_seed"}]}`, creds, creds, testSalt),
+                ExpectedStatusCode: 207,
+                ExpectedMessage:    "Partial: batch update user '4'",
+                ExpectedError:      "Some items failed, see data",
+                ExpectedData:       []any{
+                    resultFn(0, 200, "", "Success: update user 'test_batch_seed'", ""),
+                    resultFn(1, 404, "user.not_found", "Fail: update user 'not_found'", "User not found, dosen't exist"),
+                    resultFn(2, 422, "validation.required_together", "Fail: update user 'test_batch_seed'", `Invalid input format: field "salt" is required, salt, hash and enc_symkey change together; field "enc_symkey" is required, salt, hash and enc_symkey change together`),
+                    resultFn(3, 422, "validation.invalid", "Fail: update user 'test_batch_seed'", "Invalid input format: must contain at least 1 field to update"),
+                },
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchUpdateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "UpdateUnknownField",
+                Body:               fmt.Sprintf(`{"users":[{"username":"test_batch_seed","hsah":"%s"}]}`, testSalt),
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: batch update user '1'",
+                ExpectedError:      "Invalid input format: unknown field \"hsah\"",
+                ExpectedData:       []any{
+                    resultFn(0, 422, "validation.unknown_field", "Fail: update user 'test_batch_seed'", "Invalid input format: unknown field \"hsah\""),
+                },
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchDeleteUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "DeleteAtomic",
+                Body:               `{"mode":"atomic","users":[{"username":"test_batch_seed"}]}`,
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    "Success: batch delete user '1'",
+                ExpectedError:      "",
+                ExpectedData:       []any{
+                    resultFn(0, 200, "", "Success: delete user 'test_batch_seed'", ""),
+                },
+            },
+            expectedUsers:  nil,
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchDeleteUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "UnknownMode",
+                Body:               `{"mode":"best_effort","users":[{"username":"test_batch_seed"}]}`,
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: batch delete user '1'",
+                ExpectedError:      "Invalid input format: mode: must be one of [atomic partial]",
+                ExpectedData:       nil,
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchDeleteUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "EmptyBatch",
+                Body:               `{"users":[]}`,
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: batch delete user '0'",
+                ExpectedError:      fmt.Sprintf("Invalid input format: users: must contain between 1 and %d items", MaxUserBatchSize),
+                ExpectedData:       nil,
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.BatchCreateUserEndpoint },
+            tc:         testutil.EndpointTestCase{
+                Name:               "MalformedJSON",
+                Body:               `{"users":[`,
+                ExpectedStatusCode: 400,
+                ExpectedMessage:    "Fail: batch create user ''",
+                ExpectedError:      "Invalid JSON",
+                ExpectedData:       nil,
+            },
+            expectedUsers:  []string{"test_batch_seed"},
+        },
+    }
+    // Iterate, each case starts from same seeded store
+    for _, tt := range tests {
+        t.Run(tt.tc.Name, func(t *testing.T) {
+            handler := NewUserHandler(NewMemUserStore())
+            if err := handler.Store.Insert(context.Background(), newTestUserFn("test_batch_seed")); err != nil {
+                t.Fatalf("Failed to create seed user: %v", err)
+            }
+            req := httptest.NewRequest("POST", "/batch/user", strings.NewReader(tt.tc.Body))
+            resp := httptest.NewRecorder()
+            tt.endpoint(handler)(resp, req)
+            testutil.AssertResponse(t, resp, tt.tc)
+            // Stored state
+            users, _ := handler.Store.List(context.Background(), "username", nil, 10)
+            var got []string
+            for _, user := range users {
+                got = append(got, user.Username)
+            }
+            if !reflect.DeepEqual(got, tt.expectedUsers) {
+                t.Errorf("Wrong stored users:\nExpected:\t%v\nGot:\t\t%v", tt.expectedUsers, got)
+            }
+        })
+    }
+}
+//}}} Batch user endpoints
+
+
+//{{{ User resource endpoints
+func Test_UserResourceEndpoints(t *testing.T){
+    handler := NewUserHandler(NewMemUserStore())
+    username := "test_user_resource1"
+    user := smodels.User{
+        Username:   username,
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+    }
+    err := handler.Store.Insert(context.Background(), user)
+    if err != nil {
+        t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
+    }
+    newSalt := "111feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    // Run in order, PATCH and DELETE change state for following cases
+    tests := []struct {
+        Method      string
+        Path        string
+        Username    string
+        testutil.EndpointTestCase
+    }{
+        {
+            "GET", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "GetUser",
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: read user '%s'", username),
+                ExpectedData:       map[string]any{
+                    "username":username,
+                    "salt":user.Salt,
+                },
+            },
+        },{
+            "PATCH", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "PatchUser",
+                // Path wins over body username
+                Body:               fmt.Sprintf(`{"username":"someone_else","salt":"%s","hash":"%s","enc_symkey":"%s"}`, newSalt, testSalt, testEncSymkey),
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: update user '%s'", username),
+                ExpectedData:       map[string]any{"username":username},
+            },
+        },{
+            "PATCH", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "PatchPartialCredentials",
+                Body:               fmt.Sprintf(`{"salt":"%s","hash":"%s"}`, testSalt, testHash),
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: update user ''",
+                ExpectedError:      `Invalid input format: field "enc_symkey" is required, salt, hash and enc_symkey change together`,
+                ExpectedData:       testutil.ValidationDataFn([4]string{"enc_symkey", "required_together", "salt+hash+enc_symkey",
+                    `field "enc_symkey" is required, salt, hash and enc_symkey change together`}),
+            },
+        },{
+            "GET", "/users/" + username + "?fields=username,salt", username,
+            testutil.EndpointTestCase{
+                Name:               "GetUserFields",
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: read user '%s'", username),
+                ExpectedData:       map[string]any{
+                    "username":username,
+                    "salt":newSalt,
+                },
+            },
+        },{
+            "GET", "/users/" + username + "?fields=salt,hash", username,
+            testutil.EndpointTestCase{
+                Name:               "GetSecretField",
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+                ExpectedError:      "Invalid input format: fields: field \"hash\" can't be read",
+            },
+        },{
+            "GET", "/users/" + username + "?fields=password", username,
+            testutil.EndpointTestCase{
+                Name:               "GetUnknownField",
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+                ExpectedError:      "Invalid input format: fields: unknown field \"password\"",
+            },
+        },{
+            "PATCH", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "PatchMalformedJSON",
+                Body:               `{"salt":"111`,
+                ExpectedStatusCode: 400,
+                ExpectedMessage:    "Fail: update user ''",
+                ExpectedError:      "Invalid JSON",
+            },
+        },{
+            "DELETE", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "DeleteUser",
+                ExpectedStatusCode: 200,
+                ExpectedMessage:    fmt.Sprintf("Success: delete user '%s'", username),
+            },
+        },{
+            "GET", "/users/" + username, username,
+            testutil.EndpointTestCase{
+                Name:               "GetDeleted",
+                ExpectedStatusCode: 404,
+                ExpectedMessage:    fmt.Sprintf("Fail: read user '%s'", username),
+                ExpectedError:      "User not found, dosen't exist",
+            },
+        },{
+            "DELETE", "/users/fishy", "fishy user |._.|><|",
+            testutil.EndpointTestCase{
+                Name:               "UnprocessableUsername",
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: delete user 'fishy user |._.|><|'",
+                ExpectedError:      "Invalid input format: username: contains invalid characters",
+            },
+        },
+    }
+    endpoints := map[string]http.HandlerFunc{
+        "GET":      handler.GetUserResourceEndpoint,
+        "PATCH":    handler.PatchUserResourceEndpoint,
+        "DELETE":   handler.DeleteUserResourceEndpoint,
+    }
+    for _, tc := range tests {
+        t.Run(tc.Name, func(t *testing.T) {
+            // Create req, resp, path value is set by ServeMux in router
+            req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+            req.SetPathValue("username", tc.Username)
+            resp := httptest.NewRecorder()
+            // Call endpoint
+            endpoints[tc.Method](resp, req)
+            // Check
+            testutil.AssertResponse(t, resp, tc.EndpointTestCase)
+        })
+    }
+}
+//}}} User resource endpoints
+
+
+//{{{ Body limit and strict decoding
+func Test_UserEndpoints_Body(t *testing.T){
+    userJSON := fmt.Sprintf(`{"username":"test_user_body1","salt":"%s","hash":"%s","enc_symkey":"%s"}`,
+        testSalt, testHash, testEncSymkey)
+    tests := []struct {
+        endpoint    func(h *UserHandler) http.HandlerFunc
+        strict      bool
+        tc          testutil.EndpointTestCase
+    }{
+        {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.CreateUserEndpoint },
+            strict:     true,
+            tc:         testutil.EndpointTestCase{
+                Name:               "UnknownField",
+                Body:               strings.Replace(userJSON, `"salt"`, `"sallt"`, 1),
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: create user ''",
+                ExpectedError:      "Invalid input format: unknown field \"sallt\"",
+                ExpectedData:       nil,
+            },
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.CreateUserEndpoint },
+            strict:     false,
+            tc:         testutil.EndpointTestCase{
+                Name:               "UnknownFieldNotStrict",
+                Body:               strings.Replace(userJSON, `{`, `{"note":"ignored",`, 1),
+                ExpectedStatusCode: 201,
+                ExpectedMessage:    "Success: create user 'test_user_body1'",
+                ExpectedError:      "",
+                ExpectedData:       nil,
+            },
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.CreateUserEndpoint },
+            strict:     false,
+            tc:         testutil.EndpointTestCase{
+                Name:               "ConcatenatedJSON",
+                Body:               userJSON + userJSON,
+                ExpectedStatusCode: 400,
+                ExpectedMessage:    "Fail: create user ''",
+                ExpectedError:      fmt.Sprintf("Invalid JSON: trailing data after JSON value (at byte %d)", len(userJSON)),
+                ExpectedData:       nil,
+            },
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.CreateUserEndpoint },
+            strict:     true,
+            tc:         testutil.EndpointTestCase{
+                Name:               "TooLarge",
+                Body:               fmt.Sprintf(`{"username":"%s"}`, strings.Repeat("a", int(sapi.DefaultMaxBodyBytes))),
+                ExpectedStatusCode: 413,
+                ExpectedMessage:    "Fail: create user ''",
+                ExpectedError:      "Request body too large",
+                ExpectedData:       nil,
+            },
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.UpdateUserEndpoint },
+            strict:     true,
+            tc:         testutil.EndpointTestCase{
+                Name:               "UpdateUnknownField",
+                Body:               fmt.Sprintf(`{"username":"test_user_body1","enc_symkey":"%s","symkey":"ab"}`, testEncSymkey),
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: update user ''",
+                ExpectedError:      "Invalid input format: unknown field \"symkey\"",
+                ExpectedData:       nil,
+            },
+        }, {
+            endpoint:   func(h *UserHandler) http.HandlerFunc { return h.DeleteUserEndpoint },
+            strict:     true,
+            tc:         testutil.EndpointTestCase{
+                Name:               "DeleteUnknownField",
+                Body:               `{"username":"test_user_body1","force":true}`,
+                ExpectedStatusCode: 422,
+                ExpectedMessage:    "Fail: delete user ''",
+                ExpectedError:      "Invalid input format: unknown field \"force\"",
+                ExpectedData:       nil,
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.tc.Name, func(t *testing.T) {
+            handler := NewUserHandler(NewMemUserStore())
+            handler.Body.DisallowUnknown = tt.strict
+            req := httptest.NewRequest("POST", "/", strings.NewReader(tt.tc.Body))
+            resp := httptest.NewRecorder()
+            tt.endpoint(handler)(resp, req)
+            testutil.AssertResponse(t, resp, tt.tc)
+        })
+    }
+}
+//}}} Body limit and strict decoding
+
+
+//{{{ Operation timeout
+// Store that blocks until operation context is done
+type blockingUserStore struct {
+    *MemUserStore
+}
+func (s blockingUserStore) Get(ctx context.Context, username string, fields []string) (*smodels.User, error) {
+    <-ctx.Done()
+    return s.MemUserStore.Get(ctx, username, fields)
+}
+
+
+func Test_ReadUserEndpoint_Timeout(t *testing.T){
+    handler := NewUserHandler(blockingUserStore{NewMemUserStore()})
+    handler.OpTimeout = 10 * time.Millisecond
+    tc := testutil.EndpointTestCase{
+        Name:               "Timeout",
+        Body:               `{"username":"test_user_slow"}`,
+        ExpectedStatusCode: 504,
+        ExpectedMessage:    "Fail: read user 'test_user_slow'",
+        ExpectedError:      "Operation timed out",
+        ExpectedData:       nil,
+    }
+    req := httptest.NewRequest("POST", "/read/user", strings.NewReader(tc.Body))
+    resp := httptest.NewRecorder()
+    handler.ReadUserEndpoint(resp, req)
+    testutil.AssertResponse(t, resp, tc)
+}
+//}}} Operation timeout
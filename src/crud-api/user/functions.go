@@ -1,8 +1,13 @@
 package cruduser
 import (
     "fmt"
+    "errors"
+    "time"
+    "context"
     "database/sql"
     "strings"
+    "encoding/hex"
+    "crypto/subtle"
 )
 import (
     smodels "github.com/FAH2S/diar4/src/shared/models"
@@ -10,7 +15,15 @@ import (
 )
 
 
-func InsertUser(db *sql.DB, user smodels.User) (int, error) {
+// SQL predicate, true while user referenced by table.column isn't soft deleted. Rows of
+//  soft deleted user are hidden as if already purged, FK alone still resolves until
+//  purge, so every query of dependent rows (events, reviews, entries) checks it
+func ActiveUserSQLFn(table string, column string) string {
+    return fmt.Sprintf(`EXISTS (SELECT 1 FROM users WHERE users.username = %s.%s AND users.deleted_at IS NULL)`, table, column)
+}
+
+
+func InsertUser(ctx context.Context, db sdb.Querier, user smodels.User) error {
     wrap := "InsertUser"
     // Create sql query
     query := `
@@ -18,98 +31,409 @@ func InsertUser(db *sql.DB, user smodels.User) (int, error) {
         VALUES ($1, $2, $3, $4)
     `
     // Insert
-    result, err := db.Exec(query, user.Username, user.Salt, user.Hash, user.EncSymkey)
-    // Map error codes to status codes
+    result, err := db.ExecContext(ctx, query, user.Username, user.Salt, user.Hash, user.EncSymkey)
+    // Map pg errors to typed errors
     if err != nil {
-        statusCode, err := sdb.HandlePgErrorFn("user", err)
-        return statusCode, fmt.Errorf("%s: %w", wrap, err)
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
     }
     // Check rows affected
     if err = sdb.CheckRowsAffectedInsertFn(result); err != nil {
-        return 500, fmt.Errorf("%s: %w", wrap, err)
+        return fmt.Errorf("%s: %w", wrap, err)
     }
 
-    return 201, nil
+    return nil
 }
 
 //{{{ SelectUser
-func SelectUser(db *sql.DB, username string) (int, *smodels.User, error) {
+// Empty fields selects every column of smodels.UserFields, "version" and
+//  "credentials_rotated_at" are selected only on request. Soft deleted user is ErrNotFound.
+//  Columns come only from scan target map never from input
+func SelectUser(ctx context.Context, db *sql.DB, username string, fields []string) (*smodels.User, error) {
     wrap := "SelectUser"
-    // Create query
-    query := `
-        SELECT username, salt, hash, enc_symkey FROM users
-        WHERE username = $1 LIMIT 1;
-    `
-    // Create user instance
+    if len(fields) == 0 {
+        fields = smodels.UserFields
+    }
+    // Create user instance + map columns to scan targets
     var user smodels.User
-    // Query row inser + Scan load result into user
-    err := db.QueryRow(query, username).Scan(
-        &user.Username,
-        &user.Salt,
-        &user.Hash,
-        &user.EncSymkey,
-    )
-    // Check for errors 404, 500, otherwise 200
-    statusCode, err := sdb.HandleSelectErrorFn(err)
+    targets := map[string]interface{}{
+        "username":                 &user.Username,
+        "salt":                     &user.Salt,
+        "hash":                     &user.Hash,
+        "enc_symkey":               &user.EncSymkey,
+        "version":                  &user.Version,
+        "credentials_rotated_at":   &user.CredentialsRotatedAt,
+    }
+    columns := make([]string, 0, len(fields))
+    dest := make([]interface{}, 0, len(fields))
+    for _, field := range fields {
+        target, ok := targets[field]
+        if !ok {
+            return nil, fmt.Errorf("%s: %w", wrap, &sdb.DBError{
+                Kind: sdb.ErrUnknownColumn, Table: "user", Column: field,
+                Err: fmt.Errorf("unknown column used: %q", field),
+            })
+        }
+        columns = append(columns, field)
+        dest = append(dest, target)
+    }
+    // Create query
+    query := fmt.Sprintf(`
+        SELECT %s FROM users
+        WHERE username = $1 AND deleted_at IS NULL LIMIT 1;
+    `, strings.Join(columns, ", "))
+    // Query row + Scan load result into user
+    err := db.QueryRowContext(ctx, query, username).Scan(dest...)
+    // Check for errors, not found or failed query
+    err = sdb.HandleSelectErrorFn("user", err)
     if err != nil {
-        err = fmt.Errorf("%s: %w", wrap, err)
-        return statusCode, nil, err
+        return nil, fmt.Errorf("%s: %w", wrap, err)
     }
-    return statusCode, &user, nil
+    return &user, nil
 }
 //}}} SelectUser
 
 
+//{{{ ListUsers
+// Orders accepted by ListUsers, username is unique so it also breaks created_at ties
+var UserListOrders = []string{"username", "created_at"}
+
+
+// Keyset page of not deleted users in order, starting strictly after "after" (nil = first page), at most limit
+func ListUsers(ctx context.Context, db *sql.DB, order string, after *smodels.UserListItem, limit int) ([]smodels.UserListItem, error) {
+    wrap := "ListUsers"
+    // Pick order + keyset condition, order never comes from input directly
+    var (
+        orderBy string
+        where   string
+        args    []interface{}
+    )
+    switch order {
+    case "username":
+        orderBy = "username"
+        if after != nil {
+            where = "AND username > $1"
+            args = append(args, after.Username)
+        }
+    case "created_at":
+        orderBy = "created_at, username"
+        if after != nil {
+            where = "AND (created_at, username) > ($1, $2)"
+            args = append(args, after.CreatedAt, after.Username)
+        }
+    default:
+        return nil, fmt.Errorf("%s: %w", wrap, &sdb.DBError{
+            Kind: sdb.ErrUnknownColumn, Table: "user", Column: order,
+            Err: fmt.Errorf("unknown order column used: %q", order),
+        })
+    }
+    args = append(args, limit)
+    // Create query
+    query := fmt.Sprintf(`
+        SELECT username, created_at FROM users
+        WHERE deleted_at IS NULL %s
+        ORDER BY %s
+        LIMIT $%d;
+    `, where, orderBy, len(args))
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    defer rows.Close()
+    // Scan each row, empty list is not an error
+    users := []smodels.UserListItem{}
+    for rows.Next() {
+        var user smodels.UserListItem
+        if err = rows.Scan(&user.Username, &user.CreatedAt); err != nil {
+            return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+        }
+        users = append(users, user)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    return users, nil
+}
+//}}} ListUsers
+
+
 //{{{ UpdateUser
-func UpdateUser(db *sql.DB, data map[string]interface{}, username string) (int, error) {
+// expectedVersion > 0 updates only if row still has that version (optimistic lock),
+//  0 = unconditional. Every update bumps version, new one is returned.
+//  Soft deleted user is ErrNotFound
+func UpdateUser(ctx context.Context, db sdb.Querier, data map[string]interface{}, username string, expectedVersion int64) (int64, error) {
     wrap := "UpdateUser"
     // Build set parts, return err if empty
     setParts, args, err := sdb.BuildSetPartsFn(data)
     if err != nil {
-        return 422, fmt.Errorf("%s: %w", wrap, err)
+        return 0, fmt.Errorf("%s: %w", wrap, err)
     }
+    setParts = append(setParts, "version = version + 1")
     // Create querry
-    query := fmt.Sprintf(`Update users SET %s WHERE username = $%d`, strings.Join(setParts, ", "), len(args)+1)
     args = append(args, username)
+    where := fmt.Sprintf("username = $%d AND deleted_at IS NULL", len(args))
+    if expectedVersion > 0 {
+        args = append(args, expectedVersion)
+        where += fmt.Sprintf(" AND version = $%d", len(args))
+    }
+    query := fmt.Sprintf(`UPDATE users SET %s WHERE %s RETURNING version`, strings.Join(setParts, ", "), where)
     // Update DB
-    result, err := db.Exec(query, args...)
+    var version int64
+    err = db.QueryRowContext(ctx, query, args...).Scan(&version)
+    // No row matched, missing user or stale version
+    if errors.Is(err, sql.ErrNoRows) {
+        return 0, fmt.Errorf("%s: %w", wrap, noUserRowErrFn(ctx, db, username, expectedVersion))
+    }
     // Map errors
     if err != nil {
-        statusCode, err := sdb.HandlePgErrorFn("user", err)
-        return statusCode, fmt.Errorf("%s: %w", wrap, err)
+        return 0, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    return version, nil
+}
+
+
+// Tells apart missing user (ErrNotFound) from version mismatch (ErrStaleVersion)
+func noUserRowErrFn(ctx context.Context, db sdb.Querier, username string, expectedVersion int64) error {
+    notFound := &sdb.DBError{Kind: sdb.ErrNotFound, Table: "user", Err: fmt.Errorf("no rows were affected")}
+    if expectedVersion == 0 {
+        return notFound
+    }
+    var current int64
+    err := db.QueryRowContext(ctx, `SELECT version FROM users WHERE username = $1 AND deleted_at IS NULL`, username).Scan(&current)
+    if errors.Is(err, sql.ErrNoRows) {
+        return notFound
     }
-    // Check
-    statusCode, err := sdb.CheckRowsAffectedFn(result)
     if err != nil {
-        return statusCode, fmt.Errorf("%s: %w", wrap, err)
+        return sdb.HandleSelectErrorFn("user", err)
+    }
+    return &sdb.DBError{
+        Kind: sdb.ErrStaleVersion, Table: "user", Column: "version",
+        Err: fmt.Errorf("version mismatch: expected %d, current %d", expectedVersion, current),
     }
-    // Return
-    return 200, nil
 }
 //}}} UpdateUser
 
 
 //{{{ DeleteUser
-func DeleteUser(db *sql.DB, username string) (int, error) {
+// Soft delete, only marks deleted_at so user can be restored until purged.
+//  Already deleted user is ErrNotFound
+func DeleteUser(ctx context.Context, db sdb.Querier, username string) error {
     wrap := "DeleteUser"
     // Create query
-    query := `DELETE FROM users WHERE username = $1;`
+    query := `UPDATE users SET deleted_at = now() WHERE username = $1 AND deleted_at IS NULL;`
     // Execute
-    result, err := db.Exec(query, username)
+    result, err := db.ExecContext(ctx, query, username)
     // Map errors
     if err != nil {
-        statusCode, err := sdb.HandlePgErrorFn("user", err)
-        return statusCode, fmt.Errorf("%s: %w", wrap, err)
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
     }
     // Check rows affected 
-    statusCode, err := sdb.CheckRowsAffectedFn(result)
-    if err != nil {
-        return statusCode, fmt.Errorf("%s: %w", wrap, err)
+    if err = sdb.CheckRowsAffectedFn("user", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
     }
     //Return
-    return 200, nil
+    return nil
+
+}
+
+
+// Undo of DeleteUser, user that isn't soft deleted is ErrNotFound. Bumps version
+func RestoreUser(ctx context.Context, db sdb.Querier, username string) error {
+    wrap := "RestoreUser"
+    query := `UPDATE users SET deleted_at = NULL, version = version + 1 WHERE username = $1 AND deleted_at IS NOT NULL;`
+    result, err := db.ExecContext(ctx, query, username)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    if err = sdb.CheckRowsAffectedFn("user", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+
+
+// Hard delete of soft deleted user, dependent rows go with it (ON DELETE CASCADE).
+//  User that isn't soft deleted is ErrNotFound, so purge can't skip delete
+func PurgeUser(ctx context.Context, db sdb.Querier, username string) error {
+    wrap := "PurgeUser"
+    query := `DELETE FROM users WHERE username = $1 AND deleted_at IS NOT NULL;`
+    result, err := db.ExecContext(ctx, query, username)
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    if err = sdb.CheckRowsAffectedFn("user", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
 
+
+// Hard deletes every user soft deleted before "before", returns how many
+func PurgeDeletedUsers(ctx context.Context, db sdb.Querier, before time.Time) (int64, error) {
+    wrap := "PurgeDeletedUsers"
+    query := `DELETE FROM users WHERE deleted_at < $1;`
+    result, err := db.ExecContext(ctx, query, before)
+    if err != nil {
+        return 0, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    purged, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return purged, nil
 }
 //}}} DeleteUser
 
 
+//{{{ RenameUser
+// Changes username (lookup key), events/reviews/entries follow via ON UPDATE CASCADE.
+//  Taken new username (soft deleted ones included) is ErrConflict, invalid one
+//  ErrInvalid (CHECK). Bumps version
+func RenameUser(ctx context.Context, db sdb.Querier, oldUsername string, newUsername string) error {
+    wrap := "RenameUser"
+    // Create query
+    query := `UPDATE users SET username = $1, version = version + 1 WHERE username = $2 AND deleted_at IS NULL;`
+    // Execute
+    result, err := db.ExecContext(ctx, query, newUsername, oldUsername)
+    // Map errors
+    if err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    // Check rows affected
+    if err = sdb.CheckRowsAffectedFn("user", result); err != nil {
+        return fmt.Errorf("%s: %w", wrap, err)
+    }
+    return nil
+}
+//}}} RenameUser
+
+
+//{{{ BatchUsers
+// Runs ops in one transaction (sdb.WithTx), returns error of each op (nil = applied).
+//  atomic: first failure rolls back everything, other ops get ErrRolledBack.
+//  partial: each op runs in own SAVEPOINT, only failed ops are rolled back.
+//  Serialization failure retries whole batch. Returned error means nothing was committed
+func BatchUsers(ctx context.Context, db *sql.DB, ops []UserOp, atomic bool) ([]error, error) {
+    wrap := "BatchUsers"
+    var (
+        opErrs  []error
+        failed  int     // index of op that aborted atomic batch, -1 = none
+    )
+    err := sdb.WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+        // Fresh state every attempt
+        opErrs, failed = make([]error, len(ops)), -1
+        for i, op := range ops {
+            if atomic {
+                if opErrs[i] = applyUserOpFn(ctx, tx, op); opErrs[i] != nil {
+                    failed = i
+                    return opErrs[i]
+                }
+                continue
+            }
+            // Failed statement aborts whole transaction, savepoint isolates it
+            if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+                return sdb.HandlePgErrorFn("user", err)
+            }
+            opErrs[i] = applyUserOpFn(ctx, tx, op)
+            if sdb.IsRetryableTxErrFn(opErrs[i]) {
+                return opErrs[i]
+            }
+            query := "RELEASE SAVEPOINT batch_item"
+            if opErrs[i] != nil {
+                query = "ROLLBACK TO SAVEPOINT batch_item"
+            }
+            if _, err := tx.ExecContext(ctx, query); err != nil {
+                return sdb.HandlePgErrorFn("user", err)
+            }
+        }
+        return nil
+    })
+    // Atomic batch aborted by op, mark every other op, failed one keeps its own error
+    if err != nil && failed >= 0 {
+        for j := range opErrs {
+            if j != failed {
+                opErrs[j] = &sdb.DBError{Kind: sdb.ErrRolledBack, Table: "user"}
+            }
+        }
+        return opErrs, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return opErrs, nil
+}
+
+
+func applyUserOpFn(ctx context.Context, q sdb.Querier, op UserOp) error {
+    switch op.Kind {
+    case UserOpCreate:
+        return InsertUser(ctx, q, op.User)
+    case UserOpUpdate:
+        _, err := UpdateUser(ctx, q, op.Data, op.Username, 0)
+        return err
+    case UserOpDelete:
+        return DeleteUser(ctx, q, op.Username)
+    }
+    return fmt.Errorf("applyUserOpFn: unknown op kind: %q", op.Kind)
+}
+//}}} BatchUsers
+
+
+//{{{ RotateUserCredentials
+// Proof doesn't match stored hash, reported as 401 same as failed verify
+var ErrProofMismatch = errors.New("proof mismatch")
+
+
+// Replaces salt, hash and enc_symkey together if proof matches current hash.
+//  Row is locked between check and update, so concurrent rotation can't slip in
+//  with old proof. Sets credentials_rotated_at, bumps version, returns rotation time
+func RotateUserCredentials(ctx context.Context, db *sql.DB, username string, proof string, creds smodels.UserCredentials) (time.Time, error) {
+    wrap := "RotateUserCredentials"
+    var rotatedAt time.Time
+    err := sdb.WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+        // Lock row + check proof
+        var storedHash string
+        err := tx.QueryRowContext(ctx, `SELECT hash FROM users WHERE username = $1 AND deleted_at IS NULL FOR UPDATE`, username).Scan(&storedHash)
+        if err != nil {
+            return sdb.HandleSelectErrorFn("user", err)
+        }
+        if !VerifyProofFn(storedHash, proof) {
+            return ErrProofMismatch
+        }
+        // Replace all three at once
+        query := `
+            UPDATE users
+            SET salt = $1, hash = $2, enc_symkey = $3,
+                credentials_rotated_at = now(), version = version + 1
+            WHERE username = $4
+            RETURNING credentials_rotated_at;
+        `
+        err = tx.QueryRowContext(ctx, query, creds.Salt, creds.Hash, creds.EncSymkey, username).Scan(&rotatedAt)
+        if err != nil {
+            return sdb.HandlePgErrorFn("user", err)
+        }
+        return nil
+    })
+    if err != nil {
+        return time.Time{}, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return rotatedAt, nil
+}
+//}}} RotateUserCredentials
+
+
+// Compared against when user doesn't exist, so unknown username costs same as wrong proof
+var dummyHash = strings.Repeat("0", 64)
+
+
+// Compares client derived proof with stored hash in constant time, hex case is ignored
+func VerifyProofFn(storedHash string, proof string) bool {
+    stored, err := hex.DecodeString(storedHash)
+    if err != nil {
+        return false
+    }
+    given, err := hex.DecodeString(proof)
+    if err != nil {
+        return false
+    }
+    return subtle.ConstantTimeCompare(stored, given) == 1
+}
@@ -0,0 +1,405 @@
+package cruduser
+import (
+    "fmt"
+    "sync"
+    "sort"
+    "time"
+    "context"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+)
+
+
+// In-memory UserStore, mirrors Postgres semantics (unique username, CHECK
+// constraints, not found on update/delete, soft delete, done context). Safe for concurrent use.
+type MemUserStore struct {
+    mu      sync.RWMutex
+    users   map[string]smodels.User
+    created map[string]time.Time        // created_at column, keyed same as users
+    deleted map[string]memDeletedUser   // soft deleted rows, username stays taken
+}
+
+
+// Soft deleted row + its deleted_at
+type memDeletedUser struct {
+    user        smodels.User
+    deletedAt   time.Time
+}
+
+
+func NewMemUserStore() *MemUserStore {
+    return &MemUserStore{
+        users:      make(map[string]smodels.User),
+        created:    make(map[string]time.Time),
+        deleted:    make(map[string]memDeletedUser),
+    }
+}
+
+
+// Unique index covers soft deleted rows too, caller holds s.mu
+func (s *MemUserStore) taken(username string) bool {
+    _, active := s.users[username]
+    _, deleted := s.deleted[username]
+    return active || deleted
+}
+
+
+// Columns of users table that can be SET by Update or selected by Get
+var memUserColumns = map[string]struct{}{
+    "username":     {},
+    "salt":         {},
+    "hash":         {},
+    "enc_symkey":   {},
+}
+
+
+// Same typed error Postgres path would return
+func memUserErrFn(kind error, column string, err error) error {
+    return &sdb.DBError{Kind: kind, Table: "user", Column: column, Err: err}
+}
+
+
+func (s *MemUserStore) Insert(ctx context.Context, user smodels.User) error {
+    wrap := "MemUserStore.Insert"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    // CHECK constraints are evaluated before unique index
+    if err := user.Validate(); err != nil {
+        return memUserErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid user data/format: %w", wrap, err))
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.taken(user.Username) {
+        return memUserErrFn(sdb.ErrConflict, "username", fmt.Errorf("%s: user already exists", wrap))
+    }
+    // Column default
+    user.Version = 1
+    s.users[user.Username] = user
+    s.created[user.Username] = time.Now().UTC()
+    return nil
+}
+
+
+func (s *MemUserStore) Get(ctx context.Context, username string, fields []string) (*smodels.User, error) {
+    wrap := "MemUserStore.Get"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    // Unknown column fails before lookup, same as SelectUser
+    for _, field := range fields {
+        if _, ok := memUserColumns[field]; !ok && field != "version" && field != "credentials_rotated_at" {
+            return nil, memUserErrFn(sdb.ErrUnknownColumn, field, fmt.Errorf("%s: unknown column used: %q", wrap, field))
+        }
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    user, ok := s.users[username]
+    if !ok {
+        return nil, memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: user not found/dosen't exist", wrap))
+    }
+    if len(fields) == 0 {
+        fields = smodels.UserFields
+    }
+    // Only requested fields, rest stay zero like unscanned columns.
+    //  Fresh value so caller can't mutate stored user
+    projected := smodels.User{}
+    for _, field := range fields {
+        switch field {
+        case "username":
+            projected.Username = user.Username
+        case "salt":
+            projected.Salt = user.Salt
+        case "hash":
+            projected.Hash = user.Hash
+        case "enc_symkey":
+            projected.EncSymkey = user.EncSymkey
+        case "version":
+            projected.Version = user.Version
+        case "credentials_rotated_at":
+            projected.CredentialsRotatedAt = user.CredentialsRotatedAt
+        }
+    }
+    return &projected, nil
+}
+
+
+// Sorted copy of all rows then keyset cut, fine for test sized stores
+func (s *MemUserStore) List(ctx context.Context, order string, after *smodels.UserListItem, limit int) ([]smodels.UserListItem, error) {
+    wrap := "MemUserStore.List"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    // Same ordering as ListUsers, username breaks created_at ties
+    var less func(a, b smodels.UserListItem) bool
+    switch order {
+    case "username":
+        less = func(a, b smodels.UserListItem) bool {
+            return a.Username < b.Username
+        }
+    case "created_at":
+        less = func(a, b smodels.UserListItem) bool {
+            if !a.CreatedAt.Equal(b.CreatedAt) {
+                return a.CreatedAt.Before(b.CreatedAt)
+            }
+            return a.Username < b.Username
+        }
+    default:
+        return nil, memUserErrFn(sdb.ErrUnknownColumn, order, fmt.Errorf("%s: unknown order column used: %q", wrap, order))
+    }
+
+    s.mu.RLock()
+    all := make([]smodels.UserListItem, 0, len(s.users))
+    for username := range s.users {
+        all = append(all, smodels.UserListItem{Username: username, CreatedAt: s.created[username]})
+    }
+    s.mu.RUnlock()
+    sort.Slice(all, func(i, j int) bool { return less(all[i], all[j]) })
+
+    users := []smodels.UserListItem{}
+    for _, user := range all {
+        if len(users) == limit {
+            break
+        }
+        if after != nil && !less(*after, user) {
+            continue
+        }
+        users = append(users, user)
+    }
+    return users, nil
+}
+
+
+func (s *MemUserStore) Update(ctx context.Context, username string, data map[string]interface{}, expectedVersion int64) (int64, error) {
+    wrap := "MemUserStore.Update"
+    if err := ctx.Err(); err != nil {
+        return 0, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    // Same order of checks as UpdateUser: empty, unknown column, not found, version, CHECK, unique
+    if len(data) == 0 {
+        return 0, memUserErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: no fields to update", wrap))
+    }
+    for k := range data {
+        if _, ok := memUserColumns[k]; !ok {
+            return 0, memUserErrFn(sdb.ErrUnknownColumn, k, fmt.Errorf("%s: unknown column used: %q", wrap, k))
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    user, ok := s.users[username]
+    if !ok {
+        return 0, memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    if expectedVersion > 0 && user.Version != expectedVersion {
+        return 0, memUserErrFn(sdb.ErrStaleVersion, "version",
+            fmt.Errorf("%s: version mismatch: expected %d, current %d", wrap, expectedVersion, user.Version))
+    }
+    if err := smodels.ValidateUserMap(data); err != nil {
+        return 0, memUserErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid user data/format: %w", wrap, err))
+    }
+    // Apply, ValidateUserMap guarantees string values
+    for k, v := range data {
+        val := v.(string)
+        switch k {
+        case "username":
+            user.Username = val
+        case "salt":
+            user.Salt = val
+        case "hash":
+            user.Hash = val
+        case "enc_symkey":
+            user.EncSymkey = val
+        }
+    }
+    if user.Username != username {
+        if s.taken(user.Username) {
+            return 0, memUserErrFn(sdb.ErrConflict, "username", fmt.Errorf("%s: user already exists", wrap))
+        }
+        delete(s.users, username)
+        s.created[user.Username] = s.created[username]
+        delete(s.created, username)
+    }
+    user.Version++
+    s.users[user.Username] = user
+    return user.Version, nil
+}
+
+
+// Soft delete, row moves to deleted until restored or purged
+func (s *MemUserStore) Delete(ctx context.Context, username string) error {
+    wrap := "MemUserStore.Delete"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    user, ok := s.users[username]
+    if !ok {
+        return memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.users, username)
+    s.deleted[username] = memDeletedUser{user: user, deletedAt: time.Now().UTC()}
+    return nil
+}
+
+
+func (s *MemUserStore) Restore(ctx context.Context, username string) error {
+    wrap := "MemUserStore.Restore"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    trashed, ok := s.deleted[username]
+    if !ok {
+        return memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.deleted, username)
+    trashed.user.Version++
+    s.users[username] = trashed.user
+    return nil
+}
+
+
+func (s *MemUserStore) Purge(ctx context.Context, username string) error {
+    wrap := "MemUserStore.Purge"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.deleted[username]; !ok {
+        return memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    delete(s.deleted, username)
+    delete(s.created, username)
+    return nil
+}
+
+
+func (s *MemUserStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    wrap := "MemUserStore.PurgeDeleted"
+    if err := ctx.Err(); err != nil {
+        return 0, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var purged int64
+    for username, trashed := range s.deleted {
+        if trashed.deletedAt.Before(before) {
+            delete(s.deleted, username)
+            delete(s.created, username)
+            purged++
+        }
+    }
+    return purged, nil
+}
+
+
+// Same order of checks as RenameUser: CHECK, not found, unique.
+//  Dependent rows of other mem stores are not cascaded
+func (s *MemUserStore) Rename(ctx context.Context, oldUsername string, newUsername string) error {
+    wrap := "MemUserStore.Rename"
+    if err := ctx.Err(); err != nil {
+        return fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    if err := smodels.IsValidUsernameFn(newUsername); err != nil {
+        return memUserErrFn(sdb.ErrInvalid, "username", fmt.Errorf("%s: invalid user data/format: %w", wrap, err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    user, ok := s.users[oldUsername]
+    if !ok {
+        return memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: no rows were affected", wrap))
+    }
+    if s.taken(newUsername) && newUsername != oldUsername {
+        return memUserErrFn(sdb.ErrConflict, "username", fmt.Errorf("%s: user already exists", wrap))
+    }
+    delete(s.users, oldUsername)
+    user.Username = newUsername
+    user.Version++
+    s.users[newUsername] = user
+    created := s.created[oldUsername]
+    delete(s.created, oldUsername)
+    s.created[newUsername] = created
+    return nil
+}
+
+
+// Same order of checks as RotateUserCredentials: not found, proof. Credentials are
+//  validated first like CHECK constraints would on UPDATE
+func (s *MemUserStore) RotateCredentials(ctx context.Context, username string, proof string, creds smodels.UserCredentials) (time.Time, error) {
+    wrap := "MemUserStore.RotateCredentials"
+    if err := ctx.Err(); err != nil {
+        return time.Time{}, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    if err := creds.Validate(); err != nil {
+        return time.Time{}, memUserErrFn(sdb.ErrInvalid, "", fmt.Errorf("%s: invalid user data/format: %w", wrap, err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    user, ok := s.users[username]
+    if !ok {
+        return time.Time{}, memUserErrFn(sdb.ErrNotFound, "", fmt.Errorf("%s: user not found/dosen't exist", wrap))
+    }
+    if !VerifyProofFn(user.Hash, proof) {
+        return time.Time{}, fmt.Errorf("%s: %w", wrap, ErrProofMismatch)
+    }
+    rotatedAt := time.Now().UTC()
+    user.Salt, user.Hash, user.EncSymkey = creds.Salt, creds.Hash, creds.EncSymkey
+    user.CredentialsRotatedAt = &rotatedAt
+    user.Version++
+    s.users[username] = user
+    return rotatedAt, nil
+}
+
+
+// Ops run against copy of store while holding write lock, copy replaces store
+//  on commit. Failed mem op never mutates, so partial mode needs no savepoint
+func (s *MemUserStore) Batch(ctx context.Context, ops []UserOp, atomic bool) ([]error, error) {
+    wrap := "MemUserStore.Batch"
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, sdb.HandlePgErrorFn("user", err))
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    tx := NewMemUserStore()
+    for username, user := range s.users {
+        tx.users[username] = user
+    }
+    for username, created := range s.created {
+        tx.created[username] = created
+    }
+    for username, trashed := range s.deleted {
+        tx.deleted[username] = trashed
+    }
+
+    opErrs := make([]error, len(ops))
+    for i, op := range ops {
+        switch op.Kind {
+        case UserOpCreate:
+            opErrs[i] = tx.Insert(ctx, op.User)
+        case UserOpUpdate:
+            _, opErrs[i] = tx.Update(ctx, op.Username, op.Data, 0)
+        case UserOpDelete:
+            opErrs[i] = tx.Delete(ctx, op.Username)
+        default:
+            opErrs[i] = fmt.Errorf("%s: unknown op kind: %q", wrap, op.Kind)
+        }
+        if atomic && opErrs[i] != nil {
+            failed := opErrs[i]
+            for j := range opErrs {
+                opErrs[j] = memUserErrFn(sdb.ErrRolledBack, "", nil)
+            }
+            opErrs[i] = failed
+            return opErrs, nil
+        }
+    }
+    // Commit
+    s.users, s.created, s.deleted = tx.users, tx.created, tx.deleted
+    return opErrs, nil
+}
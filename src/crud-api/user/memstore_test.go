@@ -0,0 +1,654 @@
+package cruduser
+import (
+    "testing"
+    "context"
+    "errors"
+    "sync"
+    "fmt"
+    "reflect"
+    "strings"
+    "time"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+)
+
+
+// hash and salt for pwd: 'strong_password'
+const (
+    testSalt =      "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+    testHash =      "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de"
+    testEncSymkey = "0c8fd08df79b313a71b90ee93f7d889207c2277c477b424f831a5aa4de344feecf40d3753805f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31"
+)
+
+
+var ctx = context.Background()
+
+
+func newTestUserFn(username string) smodels.User {
+    return smodels.User{
+        Username:   username,
+        Salt:       testSalt,
+        Hash:       testHash,
+        EncSymkey:  testEncSymkey,
+    }
+}
+
+
+//{{{ Insert
+func Test_MemUserStore_Insert(t *testing.T) {
+    store := NewMemUserStore()
+    invalidSalt := newTestUserFn("test_user_invalid")
+    invalidSalt.Salt = "invalid_salt"
+    tests := []struct {
+        name        string
+        user        smodels.User
+        expectedErr error
+    }{
+        {
+            name:           "Success",
+            user:           newTestUserFn("test_user_123"),
+            expectedErr:    nil,
+        }, {
+            name:           "Conflict",
+            user:           newTestUserFn("test_user_123"),
+            expectedErr:    sdb.ErrConflict,
+        }, {
+            name:           "Invalid",
+            user:           invalidSalt,
+            expectedErr:    sdb.ErrInvalid,
+        }, {
+            // Longest allowed, same as users_username_check
+            name:           "Username30Chars",
+            user:           newTestUserFn(strings.Repeat("a", 30)),
+            expectedErr:    nil,
+        }, {
+            name:           "Username31Chars",
+            user:           newTestUserFn(strings.Repeat("a", 31)),
+            expectedErr:    sdb.ErrInvalid,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := store.Insert(ctx, tc.user)
+            if !errors.Is(err, tc.expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+}
+
+
+// Many goroutines insert same username, exactly one must win
+func Test_MemUserStore_Insert_Concurrent(t *testing.T) {
+    store := NewMemUserStore()
+    var (
+        wg          sync.WaitGroup
+        mu          sync.Mutex
+        succ        = 0
+        conflicts   = 0
+    )
+    for i := 0; i < 50; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            err := store.Insert(ctx, newTestUserFn("test_user_race"))
+            mu.Lock()
+            defer mu.Unlock()
+            if err == nil {
+                succ++
+            } else if errors.Is(err, sdb.ErrConflict) {
+                conflicts++
+            }
+        }()
+    }
+    wg.Wait()
+    if succ != 1 || conflicts != 49 {
+        t.Errorf("Wrong outcome:\nExpected:\t1 success, 49 conflicts\nGot:\t\t%d success, %d conflicts", succ, conflicts)
+    }
+}
+//}}} Insert
+
+
+//{{{ Get
+func Test_MemUserStore_Get(t *testing.T) {
+    store := NewMemUserStore()
+    user := newTestUserFn("test_select_user1")
+    if err := store.Insert(ctx, user); err != nil {
+        t.Fatalf("Failed to create user that will be read/fetch-ed: %v", err)
+    }
+    // Found
+    got, err := store.Get(ctx, "test_select_user1", nil)
+    if err != nil || !reflect.DeepEqual(*got, user) {
+        t.Errorf("Wrong result:\nExpected:\t%+v\nGot:\t\t%+v (%v)", user, got, err)
+    }
+    // Returned user is a copy
+    got.Hash = "mutated"
+    if again, _ := store.Get(ctx, "test_select_user1", nil); again.Hash != testHash {
+        t.Errorf("Stored user was mutated through returned pointer")
+    }
+    // Not found
+    if _, err := store.Get(ctx, "not_found", nil); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+    // Projection, only requested fields are filled
+    got, err = store.Get(ctx, "test_select_user1", []string{"username", "salt"})
+    expected := smodels.User{Username: user.Username, Salt: user.Salt}
+    if err != nil || !reflect.DeepEqual(*got, expected) {
+        t.Errorf("Wrong result:\nExpected:\t%+v\nGot:\t\t%+v (%v)", expected, got, err)
+    }
+    // Unknown column
+    if _, err := store.Get(ctx, "test_select_user1", []string{"password"}); !errors.Is(err, sdb.ErrUnknownColumn) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrUnknownColumn, err)
+    }
+}
+//}}} Get
+
+
+//{{{ List
+func Test_MemUserStore_List(t *testing.T) {
+    store := NewMemUserStore()
+    // Inserted out of username order, created_at follows insert order
+    for _, username := range []string{"test_list_c", "test_list_a", "test_list_b"} {
+        if err := store.Insert(ctx, newTestUserFn(username)); err != nil {
+            t.Fatalf("Failed to create user that will be listed: %v", err)
+        }
+    }
+    usernamesFn := func(users []smodels.UserListItem) []string {
+        names := []string{}
+        for _, user := range users {
+            names = append(names, user.Username)
+        }
+        return names
+    }
+    byName, _ := store.List(ctx, "username", nil, 10)
+    byCreated, _ := store.List(ctx, "created_at", nil, 10)
+    tests := []struct {
+        name                string
+        inputOrder          string
+        inputAfter          *smodels.UserListItem
+        inputLimit          int
+        expectedUsernames   []string
+        expectedErr         error
+    }{
+        {
+            name:               "UsernameFirstPage",
+            inputOrder:         "username",
+            inputAfter:         nil,
+            inputLimit:         2,
+            expectedUsernames:  []string{"test_list_a", "test_list_b"},
+            expectedErr:        nil,
+        }, {
+            name:               "UsernameAfter",
+            inputOrder:         "username",
+            inputAfter:         &byName[1],
+            inputLimit:         2,
+            expectedUsernames:  []string{"test_list_c"},
+            expectedErr:        nil,
+        }, {
+            name:               "CreatedAtFirstPage",
+            inputOrder:         "created_at",
+            inputAfter:         nil,
+            inputLimit:         2,
+            expectedUsernames:  usernamesFn(byCreated[:2]),
+            expectedErr:        nil,
+        }, {
+            name:               "CreatedAtAfter",
+            inputOrder:         "created_at",
+            inputAfter:         &byCreated[1],
+            inputLimit:         2,
+            expectedUsernames:  usernamesFn(byCreated[2:]),
+            expectedErr:        nil,
+        }, {
+            name:               "PastEnd",
+            inputOrder:         "username",
+            inputAfter:         &byName[2],
+            inputLimit:         2,
+            expectedUsernames:  []string{},
+            expectedErr:        nil,
+        }, {
+            name:               "UnknownOrder",
+            inputOrder:         "hash",
+            inputAfter:         nil,
+            inputLimit:         2,
+            expectedUsernames:  []string{},
+            expectedErr:        sdb.ErrUnknownColumn,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := store.List(ctx, tc.inputOrder, tc.inputAfter, tc.inputLimit)
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if names := usernamesFn(got); !reflect.DeepEqual(names, tc.expectedUsernames) {
+                t.Errorf("Wrong usernames:\nExpected:\t%v\nGot:\t\t%v", tc.expectedUsernames, names)
+            }
+        })
+    }
+    // created_at order holds the insert sequence
+    for i := 1; i < len(byCreated); i++ {
+        if byCreated[i].CreatedAt.Before(byCreated[i-1].CreatedAt) {
+            t.Errorf("created_at order broken: %+v", byCreated)
+        }
+    }
+}
+//}}} List
+
+
+//{{{ Update
+func Test_MemUserStore_Update(t *testing.T) {
+    store := NewMemUserStore()
+    for _, username := range []string{"test_update_user1", "test_update_user2"} {
+        if err := store.Insert(ctx, newTestUserFn(username)); err != nil {
+            t.Fatalf("Failed to create user that will be updated: %v", err)
+        }
+    }
+    tests := []struct {
+        name        string
+        username    string
+        data        map[string]interface{}
+        expectedErr error
+    }{
+        {
+            name:           "Success",
+            username:       "test_update_user1",
+            data:           map[string]interface{}{
+                "hash": "1111d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+            },
+            expectedErr:    nil,
+        }, {
+            name:           "UnknownColumn",
+            username:       "test_update_user1",
+            data:           map[string]interface{}{
+                "no_hash": "2222d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+            },
+            expectedErr:    sdb.ErrUnknownColumn,
+        }, {
+            name:           "NotFound",
+            username:       "test_update_user99",
+            data:           map[string]interface{}{
+                "hash": "2222d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+            },
+            expectedErr:    sdb.ErrNotFound,
+        }, {
+            name:           "Empty",
+            username:       "test_update_user1",
+            data:           map[string]interface{}{},
+            expectedErr:    sdb.ErrInvalid,
+        }, {
+            name:           "Invalid",
+            username:       "test_update_user1",
+            data:           map[string]interface{}{
+                "salt": "1234",
+            },
+            expectedErr:    sdb.ErrInvalid,
+        }, {
+            name:           "UsernameConflict",
+            username:       "test_update_user1",
+            data:           map[string]interface{}{
+                "username": "test_update_user2",
+            },
+            expectedErr:    sdb.ErrConflict,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            _, err := store.Update(ctx, tc.username, tc.data, 0)
+            if !errors.Is(err, tc.expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+    // Failed update must not leave partial changes
+    user, _ := store.Get(ctx, "test_update_user1", nil)
+    if user.Hash != "1111d825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de" || user.Salt != testSalt {
+        t.Errorf("Unexpected stored user after updates: %+v", user)
+    }
+}
+//}}} Update
+
+
+//{{{ Update version
+func Test_MemUserStore_UpdateVersion(t *testing.T) {
+    store := NewMemUserStore()
+    if err := store.Insert(ctx, newTestUserFn("test_version_user1")); err != nil {
+        t.Fatalf("Failed to create user that will be updated: %v", err)
+    }
+    data := map[string]interface{}{"hash": testSalt}
+    tests := []struct {
+        name                string
+        username            string
+        expectedVersion     int64
+        returnedVersion     int64
+        expectedErr         error
+    }{
+        {
+            name:               "Unconditional",
+            username:           "test_version_user1",
+            expectedVersion:    0,
+            returnedVersion:    2,
+            expectedErr:        nil,
+        }, {
+            name:               "Matching",
+            username:           "test_version_user1",
+            expectedVersion:    2,
+            returnedVersion:    3,
+            expectedErr:        nil,
+        }, {
+            name:               "Stale",
+            username:           "test_version_user1",
+            expectedVersion:    2,
+            returnedVersion:    0,
+            expectedErr:        sdb.ErrStaleVersion,
+        }, {
+            name:               "NotFoundBeforeVersion",
+            username:           "not_found",
+            expectedVersion:    2,
+            returnedVersion:    0,
+            expectedErr:        sdb.ErrNotFound,
+        },
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            version, err := store.Update(ctx, tc.username, data, tc.expectedVersion)
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if version != tc.returnedVersion {
+                t.Errorf("Wrong version:\nExpected:\t%d\nGot:\t\t%d", tc.returnedVersion, version)
+            }
+        })
+    }
+    // Version is only selected on request
+    user, _ := store.Get(ctx, "test_version_user1", nil)
+    versioned, _ := store.Get(ctx, "test_version_user1", []string{"version"})
+    if user.Version != 0 || versioned.Version != 3 {
+        t.Errorf("Wrong selected version: all fields %d, version field %d", user.Version, versioned.Version)
+    }
+}
+//}}} Update version
+
+
+//{{{ Delete
+func Test_MemUserStore_Delete(t *testing.T) {
+    store := NewMemUserStore()
+    if err := store.Insert(ctx, newTestUserFn("test_delete_user1")); err != nil {
+        t.Fatalf("Failed to create user that will be deleted: %v", err)
+    }
+    for i, expectedErr := range []error{nil, sdb.ErrNotFound} {
+        t.Run(fmt.Sprintf("Attempt%d", i+1), func(t *testing.T) {
+            err := store.Delete(ctx, "test_delete_user1")
+            if !errors.Is(err, expectedErr) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Delete
+
+
+//{{{ Soft delete
+func Test_MemUserStore_SoftDelete(t *testing.T) {
+    store := NewMemUserStore()
+    user := newTestUserFn("test_soft_user1")
+    if err := store.Insert(ctx, user); err != nil {
+        t.Fatalf("Failed to create user that will be deleted: %v", err)
+    }
+    getFn := func() error {
+        _, err := store.Get(ctx, user.Username, nil)
+        return err
+    }
+    steps := []struct {
+        name                string
+        call                func() error
+        expectedErr         error
+    }{
+        {"PurgeActive",     func() error { return store.Purge(ctx, user.Username) },    sdb.ErrNotFound},
+        {"RestoreActive",   func() error { return store.Restore(ctx, user.Username) },  sdb.ErrNotFound},
+        {"Delete",          func() error { return store.Delete(ctx, user.Username) },   nil},
+        {"GetDeleted",      getFn,                                                      sdb.ErrNotFound},
+        {"UsernameTaken",   func() error { return store.Insert(ctx, user) },            sdb.ErrConflict},
+        {"Restore",         func() error { return store.Restore(ctx, user.Username) },  nil},
+        {"GetRestored",     getFn,                                                      nil},
+        {"DeleteAgain",     func() error { return store.Delete(ctx, user.Username) },   nil},
+        {"Purge",           func() error { return store.Purge(ctx, user.Username) },    nil},
+        {"UsernameFree",    func() error { return store.Insert(ctx, user) },            nil},
+    }
+    // Iterate, steps build on each other
+    for _, step := range steps {
+        t.Run(step.name, func(t *testing.T) {
+            err := step.call()
+            if !errors.Is(err, step.expectedErr) || (step.expectedErr == nil && err != nil) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", step.expectedErr, err)
+            }
+        })
+    }
+
+    // Retention, only users deleted before cutoff are purged
+    if err := store.Delete(ctx, user.Username); err != nil {
+        t.Fatalf("Failed to delete user: %v", err)
+    }
+    if purged, err := store.PurgeDeleted(ctx, time.Now().Add(-time.Hour)); purged != 0 || err != nil {
+        t.Errorf("Expected nothing purged before cutoff, got: %d (%v)", purged, err)
+    }
+    if purged, err := store.PurgeDeleted(ctx, time.Now().Add(time.Hour)); purged != 1 || err != nil {
+        t.Errorf("Expected 1 purged user, got: %d (%v)", purged, err)
+    }
+    if err := store.Restore(ctx, user.Username); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Purged user can't be restored, got: %v", err)
+    }
+}
+//}}} Soft delete
+
+
+//{{{ Rename
+func Test_MemUserStore_Rename(t *testing.T) {
+    store := NewMemUserStore()
+    for _, username := range []string{"test_rename_user1", "test_rename_taken"} {
+        if err := store.Insert(ctx, newTestUserFn(username)); err != nil {
+            t.Fatalf("Failed to create user that will be renamed: %v", err)
+        }
+    }
+    tests := []struct {
+        name                string
+        oldUsername         string
+        newUsername         string
+        expectedErr         error
+    }{
+        {"Invalid",     "test_rename_user1",    "bad name",             sdb.ErrInvalid},
+        {"NotFound",    "not_found",            "test_rename_user2",    sdb.ErrNotFound},
+        {"Taken",       "test_rename_user1",    "test_rename_taken",    sdb.ErrConflict},
+        {"Rename",      "test_rename_user1",    "test_rename_user2",    nil},
+        {"OldIsGone",   "test_rename_user1",    "test_rename_user3",    sdb.ErrNotFound},
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := store.Rename(ctx, tc.oldUsername, tc.newUsername)
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+    // Row moved with its data, version bumped
+    user, err := store.Get(ctx, "test_rename_user2", []string{"username", "hash", "version"})
+    if err != nil || user.Hash != testHash || user.Version != 2 {
+        t.Errorf("Wrong renamed user: %+v (%v)", user, err)
+    }
+}
+//}}} Rename
+
+
+//{{{ Rotate credentials
+func Test_MemUserStore_RotateCredentials(t *testing.T) {
+    store := NewMemUserStore()
+    if err := store.Insert(ctx, newTestUserFn("test_rotate_user1")); err != nil {
+        t.Fatalf("Failed to create user that will be rotated: %v", err)
+    }
+    // New salt/hash are swapped old ones, enough to tell them apart
+    creds := smodels.UserCredentials{Salt: testHash, Hash: testSalt, EncSymkey: testEncSymkey}
+    partial := creds
+    partial.Salt = ""
+    tests := []struct {
+        name                string
+        username            string
+        proof               string
+        creds               smodels.UserCredentials
+        expectedErr         error
+    }{
+        {
+            name:               "WrongProof",
+            username:           "test_rotate_user1",
+            proof:              testSalt,
+            creds:              creds,
+            expectedErr:        ErrProofMismatch,
+        }, {
+            name:               "MissingSalt",
+            username:           "test_rotate_user1",
+            proof:              testHash,
+            creds:              partial,
+            expectedErr:        sdb.ErrInvalid,
+        }, {
+            name:               "NotFound",
+            username:           "not_found",
+            proof:              testHash,
+            creds:              creds,
+            expectedErr:        sdb.ErrNotFound,
+        }, {
+            name:               "Success",
+            username:           "test_rotate_user1",
+            proof:              testHash,
+            creds:              creds,
+            expectedErr:        nil,
+        }, {
+            // Old hash is no longer valid proof
+            name:               "OldProofAfterRotation",
+            username:           "test_rotate_user1",
+            proof:              testHash,
+            creds:              creds,
+            expectedErr:        ErrProofMismatch,
+        },
+    }
+    // Iterate, cases build on each other
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            rotatedAt, err := store.RotateCredentials(ctx, tc.username, tc.proof, tc.creds)
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if (tc.expectedErr == nil) == rotatedAt.IsZero() {
+                t.Errorf("Wrong rotation time %v for error %v", rotatedAt, err)
+            }
+        })
+    }
+    // All three replaced together, rotation recorded and version bumped
+    user, _ := store.Get(ctx, "test_rotate_user1",
+        []string{"salt", "hash", "enc_symkey", "version", "credentials_rotated_at"})
+    if user.Salt != testHash || user.Hash != testSalt || user.Version != 2 || user.CredentialsRotatedAt == nil {
+        t.Errorf("Wrong rotated user: %+v", user)
+    }
+}
+//}}} Rotate credentials
+
+
+//{{{ Batch
+func Test_MemUserStore_Batch(t *testing.T) {
+    invalid := newTestUserFn("test_batch_bad")
+    invalid.Salt = "zz"
+    tests := []struct {
+        name                string
+        inputOps            []UserOp
+        inputAtomic         bool
+        expectedErrs        []error
+        expectedUsers       []string    // stored after batch, seeded with test_batch_seed
+    }{
+        {
+            name:               "AtomicSuccess",
+            inputOps:           []UserOp{
+                {Kind: UserOpCreate, User: newTestUserFn("test_batch_a")},
+                {Kind: UserOpUpdate, Username: "test_batch_seed", Data: map[string]interface{}{"hash": testSalt}},
+                {Kind: UserOpDelete, Username: "test_batch_a"},
+            },
+            inputAtomic:        true,
+            expectedErrs:       []error{nil, nil, nil},
+            expectedUsers:      []string{"test_batch_seed"},
+        }, {
+            name:               "AtomicRollback",
+            inputOps:           []UserOp{
+                {Kind: UserOpCreate, User: newTestUserFn("test_batch_a")},
+                {Kind: UserOpCreate, User: newTestUserFn("test_batch_seed")},
+                {Kind: UserOpDelete, Username: "test_batch_seed"},
+            },
+            inputAtomic:        true,
+            expectedErrs:       []error{sdb.ErrRolledBack, sdb.ErrConflict, sdb.ErrRolledBack},
+            expectedUsers:      []string{"test_batch_seed"},
+        }, {
+            name:               "Partial",
+            inputOps:           []UserOp{
+                {Kind: UserOpCreate, User: newTestUserFn("test_batch_a")},
+                {Kind: UserOpCreate, User: invalid},
+                {Kind: UserOpDelete, Username: "not_found"},
+                {Kind: UserOpCreate, User: newTestUserFn("test_batch_b")},
+            },
+            inputAtomic:        false,
+            expectedErrs:       []error{nil, sdb.ErrInvalid, sdb.ErrNotFound, nil},
+            expectedUsers:      []string{"test_batch_a", "test_batch_b", "test_batch_seed"},
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            store := NewMemUserStore()
+            if err := store.Insert(ctx, newTestUserFn("test_batch_seed")); err != nil {
+                t.Fatalf("Failed to create seed user: %v", err)
+            }
+            opErrs, err := store.Batch(ctx, tc.inputOps, tc.inputAtomic)
+            if err != nil || len(opErrs) != len(tc.expectedErrs) {
+                t.Fatalf("Fatal, expected %d op errors, got: %v (%v)", len(tc.expectedErrs), opErrs, err)
+            }
+            for i, expectedErr := range tc.expectedErrs {
+                if !errors.Is(opErrs[i], expectedErr) || (expectedErr == nil && opErrs[i] != nil) {
+                    t.Errorf("Wrong error of op %d:\nExpected:\t%v\nGot:\t\t%v", i, expectedErr, opErrs[i])
+                }
+            }
+            users, _ := store.List(ctx, "username", nil, 10)
+            var got []string
+            for _, user := range users {
+                got = append(got, user.Username)
+            }
+            if !reflect.DeepEqual(got, tc.expectedUsers) {
+                t.Errorf("Wrong stored users:\nExpected:\t%v\nGot:\t\t%v", tc.expectedUsers, got)
+            }
+        })
+    }
+}
+//}}} Batch
+
+
+//{{{ Done context
+func Test_MemUserStore_DoneContext(t *testing.T) {
+    store := NewMemUserStore()
+    // Canceled context
+    canceledCtx, cancel := context.WithCancel(ctx)
+    cancel()
+    if err := store.Insert(canceledCtx, newTestUserFn("test_user_ctx")); !errors.Is(err, sdb.ErrUnavailable) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrUnavailable, err)
+    }
+    // Expired deadline
+    expiredCtx, cancel := context.WithTimeout(ctx, -1)
+    defer cancel()
+    if _, err := store.Get(expiredCtx, "test_user_ctx", nil); !errors.Is(err, sdb.ErrTimeout) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrTimeout, err)
+    }
+    // Nothing was stored
+    if _, err := store.Get(ctx, "test_user_ctx", nil); !errors.Is(err, sdb.ErrNotFound) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", sdb.ErrNotFound, err)
+    }
+}
+//}}} Done context
@@ -0,0 +1,121 @@
+package cruduser
+import (
+    "time"
+    "context"
+    "database/sql"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+// Storage behind user endpoints, Postgres (PgUserStore) is default implementation.
+//  Errors are typed shareddb errors (ErrConflict, ErrNotFound, ...), check with errors.Is.
+//  Get fills only requested fields (empty = all but version), rest are left zero.
+//  Update with expectedVersion > 0 fails with ErrStaleVersion if row changed meanwhile.
+//  Delete is soft (restorable), Purge hard deletes soft deleted user, PurgeDeleted
+//  every user soft deleted before given time. Soft deleted users are invisible to
+//  other methods but keep their username reserved.
+//  Rename changes username, taken new one is ErrConflict.
+//  RotateCredentials replaces salt, hash and enc_symkey together, proof must match
+//  current hash (ErrProofMismatch otherwise).
+//  List is keyset paginated, order is one of UserListOrders.
+//  Batch applies ops in one transaction, see BatchUsers
+type UserStore interface {
+    Insert(ctx context.Context, user smodels.User) error
+    Get(ctx context.Context, username string, fields []string) (*smodels.User, error)
+    List(ctx context.Context, order string, after *smodels.UserListItem, limit int) ([]smodels.UserListItem, error)
+    Update(ctx context.Context, username string, data map[string]interface{}, expectedVersion int64) (int64, error)
+    Delete(ctx context.Context, username string) error
+    Restore(ctx context.Context, username string) error
+    Purge(ctx context.Context, username string) error
+    PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+    Rename(ctx context.Context, oldUsername string, newUsername string) error
+    RotateCredentials(ctx context.Context, username string, proof string, creds smodels.UserCredentials) (time.Time, error)
+    Batch(ctx context.Context, ops []UserOp, atomic bool) ([]error, error)
+}
+
+
+// Kinds of UserOp
+const (
+    UserOpCreate    = "create"
+    UserOpUpdate    = "update"
+    UserOpDelete    = "delete"
+)
+
+
+// Single write of batch, Kind picks which fields are used:
+//  create -> User, update -> Username + Data, delete -> Username
+type UserOp struct {
+    Kind        string
+    User        smodels.User
+    Username    string
+    Data        map[string]interface{}
+}
+
+
+//{{{ Postgres store
+type PgUserStore struct {
+    DB  *sql.DB
+}
+
+
+func NewPgUserStore(db *sql.DB) *PgUserStore {
+    return &PgUserStore{DB: db}
+}
+
+
+func (s *PgUserStore) Insert(ctx context.Context, user smodels.User) error {
+    return InsertUser(ctx, s.DB, user)
+}
+
+
+func (s *PgUserStore) Get(ctx context.Context, username string, fields []string) (*smodels.User, error) {
+    return SelectUser(ctx, s.DB, username, fields)
+}
+
+
+func (s *PgUserStore) List(ctx context.Context, order string, after *smodels.UserListItem, limit int) ([]smodels.UserListItem, error) {
+    return ListUsers(ctx, s.DB, order, after, limit)
+}
+
+
+func (s *PgUserStore) Update(ctx context.Context, username string, data map[string]interface{}, expectedVersion int64) (int64, error) {
+    return UpdateUser(ctx, s.DB, data, username, expectedVersion)
+}
+
+
+func (s *PgUserStore) Delete(ctx context.Context, username string) error {
+    return DeleteUser(ctx, s.DB, username)
+}
+
+
+func (s *PgUserStore) Restore(ctx context.Context, username string) error {
+    return RestoreUser(ctx, s.DB, username)
+}
+
+
+func (s *PgUserStore) Purge(ctx context.Context, username string) error {
+    return PurgeUser(ctx, s.DB, username)
+}
+
+
+func (s *PgUserStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    return PurgeDeletedUsers(ctx, s.DB, before)
+}
+
+
+func (s *PgUserStore) Rename(ctx context.Context, oldUsername string, newUsername string) error {
+    return RenameUser(ctx, s.DB, oldUsername, newUsername)
+}
+
+
+func (s *PgUserStore) RotateCredentials(ctx context.Context, username string, proof string, creds smodels.UserCredentials) (time.Time, error) {
+    return RotateUserCredentials(ctx, s.DB, username, proof, creds)
+}
+
+
+func (s *PgUserStore) Batch(ctx context.Context, ops []UserOp, atomic bool) ([]error, error) {
+    return BatchUsers(ctx, s.DB, ops, atomic)
+}
+//}}} Postgres store
@@ -0,0 +1,263 @@
+package sharedapi
+import (
+    "io"
+    "fmt"
+    "sort"
+    "slices"
+    "bytes"
+    "errors"
+    "strings"
+    "context"
+    "net/http"
+    "encoding/json"
+)
+
+
+// Body limit when BodyOptions.MaxBytes isn't set
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+
+var (
+    ErrBodyTooLarge = errors.New("request body too large")
+    ErrInvalidJSON  = errors.New("Invalid JSON")
+    ErrMissingKey   = errors.New("missing key")
+    ErrUnknownField = errors.New("unknown field")
+    ErrTrailingData = errors.New("trailing data after JSON value")
+)
+
+
+type BodyOptions struct {
+    MaxBytes            int64   // <= 0 means DefaultMaxBodyBytes, only first read of request applies it
+    DisallowUnknown     bool    // keys not in struct target / extracted keys are error
+    DisallowTrailing    bool    // anything but whitespace after first JSON value is error
+    UseNumber           bool    // numbers of interface{} targets are json.Number, not float64
+}
+
+
+//{{{ Body cache
+type bodyCacheKey struct{}
+
+
+type bodyCache struct {
+    raw []byte
+    err error
+}
+
+
+// Request copy holding body on its context with r.Body reset to it, r itself is
+//  never modified since server and outer handlers still own it
+func setBodyCacheFn(r *http.Request, raw []byte, err error) *http.Request {
+    ctx := context.WithValue(r.Context(), bodyCacheKey{}, &bodyCache{raw: raw, err: err})
+    cached := r.WithContext(ctx)
+    cached.Body = io.NopCloser(bytes.NewReader(raw))
+    cached.ContentLength = int64(len(raw))
+    return cached
+}
+
+
+// Request body read once (at most maxBytes) and cached on context of returned
+//  request, pass that one on (ex.: to next handler) so later reads get cached
+//  bytes/error. Its r.Body can still be read as usual, r is left as is
+func ReadBodyFn(r *http.Request, maxBytes int64) (*http.Request, []byte, error) {
+    if cache, ok := r.Context().Value(bodyCacheKey{}).(*bodyCache); ok {
+        // Fresh reader over cache, earlier reader may be drained
+        cached := r.WithContext(r.Context())
+        cached.Body = io.NopCloser(bytes.NewReader(cache.raw))
+        return cached, cache.raw, cache.err
+    }
+    if maxBytes <= 0 {
+        maxBytes = DefaultMaxBodyBytes
+    }
+    var raw []byte
+    var err error
+    if r.Body != nil {
+        // One byte over limit tells body of exactly maxBytes from bigger one
+        raw, err = io.ReadAll(io.LimitReader(r.Body, maxBytes + 1))
+        r.Body.Close()
+        if err == nil && int64(len(raw)) > maxBytes {
+            raw, err = nil, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxBytes)
+        }
+    }
+    return setBodyCacheFn(r, raw, err), raw, err
+}
+//}}} Body cache
+
+
+//{{{ BodyDecoder
+// Decodes cached request body, any number of Decode/Extract calls on same request
+type BodyDecoder struct {
+    req     *http.Request
+    raw     []byte
+    opts    BodyOptions
+}
+
+
+func NewBodyDecoder(r *http.Request, opts BodyOptions) (*BodyDecoder, error) {
+    cached, raw, err := ReadBodyFn(r, opts.MaxBytes)
+    if err != nil {
+        return nil, err
+    }
+    return &BodyDecoder{req: cached, raw: raw, opts: opts}, nil
+}
+
+
+// Request holding cached body, pass it on instead of drained r given to NewBodyDecoder
+func (d *BodyDecoder) Request() *http.Request {
+    return d.req
+}
+
+
+// Turns encoding/json errors into ErrInvalidJSON/ErrUnknownField with byte offset
+//  into body, so client can find the problem
+func jsonErrFn(err error, raw []byte) error {
+    var syntaxErr *json.SyntaxError
+    var typeErr *json.UnmarshalTypeError
+    switch {
+    case errors.As(err, &syntaxErr):
+        return fmt.Errorf("%w: %v (at byte %d)", ErrInvalidJSON, err, syntaxErr.Offset)
+    case errors.As(err, &typeErr):
+        return fmt.Errorf("%w: %s must be %s (at byte %d)", ErrInvalidJSON, typeErr.Field, typeErr.Type, typeErr.Offset)
+    case errors.Is(err, io.EOF):
+        return fmt.Errorf("%w: empty body", ErrInvalidJSON)
+    case errors.Is(err, io.ErrUnexpectedEOF):
+        return fmt.Errorf("%w: unexpected end of body (at byte %d)", ErrInvalidJSON, len(raw))
+    case strings.HasPrefix(err.Error(), "json: unknown field "):
+        // Decoder.DisallowUnknownFields has no typed error
+        return fmt.Errorf("%w %s", ErrUnknownField, strings.TrimPrefix(err.Error(), "json: unknown field "))
+    default:
+        return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+    }
+}
+
+
+// JSON into target with body options (MaxBytes is ignored), also for parts of
+//  body ex.: batch items. DisallowUnknown works for struct targets
+func DecodeJSONFn(raw []byte, target interface{}, opts BodyOptions) error {
+    dec := json.NewDecoder(bytes.NewReader(raw))
+    if opts.DisallowUnknown {
+        dec.DisallowUnknownFields()
+    }
+    if opts.UseNumber {
+        dec.UseNumber()
+    }
+    if err := dec.Decode(target); err != nil {
+        return jsonErrFn(err, raw)
+    }
+    if !opts.DisallowTrailing {
+        return nil
+    }
+    end := int(dec.InputOffset())
+    rest := bytes.TrimLeft(raw[end:], " \t\r\n")
+    if len(rest) > 0 {
+        return fmt.Errorf("%w (at byte %d)", ErrTrailingData, len(raw) - len(rest))
+    }
+    return nil
+}
+
+
+// Whole body into target, see DecodeJSONFn
+func (d *BodyDecoder) Decode(target interface{}) error {
+    return DecodeJSONFn(d.raw, target, d.opts)
+}
+
+
+// Values of keys into their typed targets ex.: {"username": &username, "fields": &fields}.
+//  Every key is required, with DisallowUnknown body may hold only these keys
+func (d *BodyDecoder) Extract(targets map[string]interface{}) error {
+    var raw map[string]json.RawMessage
+    if err := d.Decode(&raw); err != nil {
+        return err
+    }
+    if raw == nil {
+        return fmt.Errorf("%w: body must be JSON object", ErrInvalidJSON)
+    }
+    // Sorted so same body always reports same key
+    keys := make([]string, 0, len(targets))
+    for key := range targets {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        val, ok := raw[key]
+        if !ok {
+            return fmt.Errorf("%w: %s", ErrMissingKey, key)
+        }
+        if err := json.Unmarshal(val, targets[key]); err != nil {
+            return fmt.Errorf("failed to unmarshal value: %s of key: %s to target type: %T", val, key, targets[key])
+        }
+    }
+    if !d.opts.DisallowUnknown {
+        return nil
+    }
+    present := make([]string, 0, len(raw))
+    for key := range raw {
+        present = append(present, key)
+    }
+    return unknownKeyErrFn(present, keys)
+}
+//}}} BodyDecoder
+
+
+// First unknown key (sorted) as ErrUnknownField, nil when all keys are allowed
+func unknownKeyErrFn(keys []string, allowed []string) error {
+    unknown := make([]string, 0)
+    for _, key := range keys {
+        if !slices.Contains(allowed, key) {
+            unknown = append(unknown, key)
+        }
+    }
+    if len(unknown) == 0 {
+        return nil
+    }
+    sort.Strings(unknown)
+    return fmt.Errorf("%w %q", ErrUnknownField, unknown[0])
+}
+
+
+// Strict check for map decoded bodies (DisallowUnknown only works for structs)
+func CheckKnownKeysFn(input map[string]interface{}, allowed []string) error {
+    keys := make([]string, 0, len(input))
+    for key := range input {
+        keys = append(keys, key)
+    }
+    return unknownKeyErrFn(keys, allowed)
+}
+
+
+// Shortcut for NewBodyDecoder + Decode
+func DecodeBodyFn(r *http.Request, target interface{}, opts BodyOptions) error {
+    decoder, err := NewBodyDecoder(r, opts)
+    if err != nil {
+        return err
+    }
+    return decoder.Decode(target)
+}
+
+
+// Shortcut for NewBodyDecoder + Extract
+func ExtractBodyFn(r *http.Request, targets map[string]interface{}, opts BodyOptions) error {
+    decoder, err := NewBodyDecoder(r, opts)
+    if err != nil {
+        return err
+    }
+    return decoder.Extract(targets)
+}
+
+
+// Status code and client facing error of failed body decode:
+//  413 too large, 422 unknown field (strict), 400 rest ("Invalid JSON" + detail when known)
+func BodyErrResponseFn(err error) (int, string) {
+    msg := err.Error()
+    switch {
+    case errors.Is(err, ErrBodyTooLarge):
+        return 413, "Request body too large"
+    case errors.Is(err, ErrUnknownField):
+        return 422, fmt.Sprintf("Invalid input format: %s", msg[strings.Index(msg, ErrUnknownField.Error()):])
+    case errors.Is(err, ErrInvalidJSON):
+        return 400, msg[strings.Index(msg, ErrInvalidJSON.Error()):]
+    case errors.Is(err, ErrTrailingData):
+        return 400, fmt.Sprintf("%v: %s", ErrInvalidJSON, msg[strings.Index(msg, ErrTrailingData.Error()):])
+    default:
+        return 400, ErrInvalidJSON.Error()
+    }
+}
@@ -0,0 +1,275 @@
+package sharedapi
+import (
+    "io"
+    "fmt"
+    "errors"
+    "testing"
+    "strings"
+    "reflect"
+    "net/http"
+    "net/http/httptest"
+)
+
+
+//{{{ Test ReadBodyFn
+func Test_ReadBodyFn(t *testing.T) {
+    // Read twice, second comes from cache of returned request and its r.Body is still readable
+    req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"username":"test_user"}`))
+    original := *req
+    cached := req
+    for i := 0; i < 2; i++ {
+        next, raw, err := ReadBodyFn(cached, 0)
+        if err != nil || string(raw) != `{"username":"test_user"}` {
+            t.Fatalf("Wrong body (read %d):\nGot:\t%s (%v)", i, raw, err)
+        }
+        cached = next
+    }
+    rest, err := io.ReadAll(cached.Body)
+    if err != nil || string(rest) != `{"username":"test_user"}` {
+        t.Errorf("Wrong r.Body after cache:\nGot:\t%s (%v)", rest, err)
+    }
+    // Request of caller isn't modified, only its body is consumed
+    if req.Context() != original.Context() || req.Body != original.Body {
+        t.Errorf("ReadBodyFn modified request in place")
+    }
+
+    // Limit, exactly maxBytes is fine
+    req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+    if _, _, err := ReadBodyFn(req, 7); err != nil {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", nil, err)
+    }
+    req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":10}`))
+    for i := 0; i < 2; i++ {
+        next, _, err := ReadBodyFn(req, 7)
+        if !errors.Is(err, ErrBodyTooLarge) {
+            t.Errorf("Wrong error (read %d):\nExpected:\t%v\nGot:\t\t%v", i, ErrBodyTooLarge, err)
+        }
+        req = next
+    }
+}
+//}}} Test ReadBodyFn
+
+
+//{{{ Test BodyDecoder.Decode
+func Test_BodyDecoder_Decode(t *testing.T) {
+    type target struct {
+        Username    string  `json:"username"`
+        Limit       int     `json:"limit"`
+    }
+    tests := []struct {
+        name            string
+        jsonBody        string
+        opts            BodyOptions
+        expectedErr     error
+        expectedErrMsg  string
+        expectedTarget  target
+    }{
+        {
+            name:           "Success",
+            jsonBody:       `{"username":"test_user","limit":5}`,
+            expectedTarget: target{Username: "test_user", Limit: 5},
+        }, {
+            name:           "UnknownAllowed",
+            jsonBody:       `{"username":"test_user","extra":1}`,
+            expectedTarget: target{Username: "test_user"},
+        }, {
+            name:           "UnknownField",
+            jsonBody:       `{"username":"test_user","extra":1}`,
+            opts:           BodyOptions{DisallowUnknown: true},
+            expectedErr:    ErrUnknownField,
+            expectedErrMsg: `unknown field "extra"`,
+        }, {
+            name:           "TrailingAllowed",
+            jsonBody:       `{"username":"test_user"}{"username":"other"}`,
+            expectedTarget: target{Username: "test_user"},
+        }, {
+            name:           "TrailingData",
+            jsonBody:       `{"username":"test_user"}  {"username":"other"}`,
+            opts:           BodyOptions{DisallowTrailing: true},
+            expectedErr:    ErrTrailingData,
+            expectedErrMsg: "(at byte 26)",
+        }, {
+            name:           "TrailingWhitespace",
+            jsonBody:       "{\"username\":\"test_user\"}\n",
+            opts:           BodyOptions{DisallowTrailing: true},
+            expectedTarget: target{Username: "test_user"},
+        }, {
+            name:           "SyntaxOffset",
+            jsonBody:       `{"username":"test_user",}`,
+            expectedErr:    ErrInvalidJSON,
+            expectedErrMsg: "(at byte 25)",
+        }, {
+            name:           "TypeOffset",
+            jsonBody:       `{"limit":"5"}`,
+            expectedErr:    ErrInvalidJSON,
+            expectedErrMsg: "limit must be int (at byte 12)",
+        }, {
+            name:           "Truncated",
+            jsonBody:       `{"username":"`,
+            expectedErr:    ErrInvalidJSON,
+            expectedErrMsg: "unexpected end of body (at byte 13)",
+        }, {
+            name:           "Empty",
+            jsonBody:       ``,
+            expectedErr:    ErrInvalidJSON,
+            expectedErrMsg: "empty body",
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.jsonBody))
+            decoder, err := NewBodyDecoder(req, tc.opts)
+            if err != nil {
+                t.Fatalf("Failed to create decoder: %v", err)
+            }
+            var got target
+            err = decoder.Decode(&got)
+            if !errors.Is(err, tc.expectedErr) || (err != nil && !strings.Contains(err.Error(), tc.expectedErrMsg)) {
+                t.Fatalf("Wrong error:\nExpected:\t%v %q\nGot:\t\t%v", tc.expectedErr, tc.expectedErrMsg, err)
+            }
+            if err == nil && got != tc.expectedTarget {
+                t.Errorf("Wrong target:\nExpected:\t%+v\nGot:\t\t%+v", tc.expectedTarget, got)
+            }
+        })
+    }
+}
+//}}} Test BodyDecoder.Decode
+
+
+//{{{ Test BodyDecoder.Extract
+func Test_BodyDecoder_Extract(t *testing.T) {
+    tests := []struct {
+        name            string
+        jsonBody        string
+        opts            BodyOptions
+        expectedErr     string
+    }{
+        {
+            name:           "Success",
+            jsonBody:       `{"username":"test_user","fields":["salt"]}`,
+        }, {
+            name:           "MissingKey",
+            jsonBody:       `{"username":"test_user"}`,
+            expectedErr:    "missing key: fields",
+        }, {
+            name:           "WrongType",
+            jsonBody:       `{"username":"test_user","fields":"salt"}`,
+            expectedErr:    "failed to unmarshal value",
+        }, {
+            name:           "UnknownKey",
+            jsonBody:       `{"username":"test_user","fields":["salt"],"hash":"ab"}`,
+            opts:           BodyOptions{DisallowUnknown: true},
+            expectedErr:    `unknown field "hash"`,
+        }, {
+            name:           "NotObject",
+            jsonBody:       `null`,
+            expectedErr:    "body must be JSON object",
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.jsonBody))
+            decoder, err := NewBodyDecoder(req, tc.opts)
+            if err != nil {
+                t.Fatalf("Failed to create decoder: %v", err)
+            }
+            var username string
+            var fields []string
+            err = decoder.Extract(map[string]interface{}{"username": &username, "fields": &fields})
+            if tc.expectedErr == "" && err != nil {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", nil, err)
+            }
+            if tc.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if err == nil && (username != "test_user" || !reflect.DeepEqual(fields, []string{"salt"})) {
+                t.Errorf("Wrong targets:\nGot:\t%q %v", username, fields)
+            }
+        })
+    }
+
+    // Uncached request, other keys are extracted from returned one
+    req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"username":"test_user","limit":3}`))
+    var username string
+    var limit int
+    cached, err := ExtractJSONValueFn(req, "username", &username)
+    if err != nil || username != "test_user" {
+        t.Fatalf("Failed to extract username: %q (%v)", username, err)
+    }
+    if _, err := ExtractJSONValueFn(cached, "limit", &limit); err != nil || limit != 3 {
+        t.Errorf("Wrong second extract:\nExpected:\t3\nGot:\t\t%d (%v)", limit, err)
+    }
+    // Extracting again from returned request works any number of times
+    limit = 0
+    if _, err := ExtractJSONValueFn(cached, "limit", &limit); err != nil || limit != 3 {
+        t.Errorf("Wrong third extract:\nExpected:\t3\nGot:\t\t%d (%v)", limit, err)
+    }
+
+    // Decoder passes cached request on too
+    decoder, err := NewBodyDecoder(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"limit":4}`)), BodyOptions{})
+    if err != nil {
+        t.Fatalf("Failed to create decoder: %v", err)
+    }
+    if _, err := ExtractJSONValueFn(decoder.Request(), "limit", &limit); err != nil || limit != 4 {
+        t.Errorf("Wrong extract from decoder request:\nExpected:\t4\nGot:\t\t%d (%v)", limit, err)
+    }
+
+    // Merged request decodes merged body, not cached original
+    merged := MergeJSONBodyFn(cached, map[string]interface{}{"username": "other_user"})
+    if _, err := ExtractJSONValueFn(merged, "username", &username); err != nil || username != "other_user" {
+        t.Errorf("Wrong merged extract:\nExpected:\tother_user\nGot:\t\t%s (%v)", username, err)
+    }
+}
+//}}} Test BodyDecoder.Extract
+
+
+//{{{ Test BodyErrResponseFn
+func Test_BodyErrResponseFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        err                 error
+        expectedStatusCode  int
+        expectedErrMsg      string
+    }{
+        {
+            name:               "TooLarge",
+            err:                fmt.Errorf("%w: limit is 10 bytes", ErrBodyTooLarge),
+            expectedStatusCode: 413,
+            expectedErrMsg:     "Request body too large",
+        }, {
+            name:               "UnknownField",
+            err:                CheckKnownKeysFn(map[string]interface{}{"id": 1, "owner": "x"}, []string{"id"}),
+            expectedStatusCode: 422,
+            expectedErrMsg:     `Invalid input format: unknown field "owner"`,
+        }, {
+            name:               "WrappedUnknownField",
+            err:                fmt.Errorf("ExtractJSONValueFn: %w", fmt.Errorf("%w %q", ErrUnknownField, "force")),
+            expectedStatusCode: 422,
+            expectedErrMsg:     `Invalid input format: unknown field "force"`,
+        }, {
+            name:               "Syntax",
+            err:                DecodeJSONFn([]byte(`{"a":}`), &map[string]interface{}{}, BodyOptions{}),
+            expectedStatusCode: 400,
+            expectedErrMsg:     "Invalid JSON: invalid character '}' looking for beginning of value (at byte 6)",
+        }, {
+            name:               "Trailing",
+            err:                DecodeJSONFn([]byte(`{} {}`), &map[string]interface{}{}, BodyOptions{DisallowTrailing: true}),
+            expectedStatusCode: 400,
+            expectedErrMsg:     "Invalid JSON: trailing data after JSON value (at byte 3)",
+        }, {
+            name:               "MissingKey",
+            err:                fmt.Errorf("%w: id", ErrMissingKey),
+            expectedStatusCode: 400,
+            expectedErrMsg:     "Invalid JSON",
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, errMsg := BodyErrResponseFn(tc.err)
+            if code != tc.expectedStatusCode || errMsg != tc.expectedErrMsg {
+                t.Errorf("Wrong response:\nExpected:\t%d %q\nGot:\t\t%d %q", tc.expectedStatusCode, tc.expectedErrMsg, code, errMsg)
+            }
+        })
+    }
+}
+//}}} Test BodyErrResponseFn
@@ -0,0 +1,66 @@
+package sharedapi
+import (
+    "fmt"
+    "errors"
+    "strings"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+)
+
+
+// Length of key generated by NewCursorKeyFn, also minimal accepted key length
+const CursorKeyLen = 32
+
+
+var ErrInvalidCursor = errors.New("invalid or tampered cursor")
+
+
+// Random key for signing cursors, panics only if system randomness is broken
+func NewCursorKeyFn() []byte {
+    key := make([]byte, CursorKeyLen)
+    if _, err := rand.Read(key); err != nil {
+        panic(fmt.Sprintf("NewCursorKeyFn: crypto/rand failed: %v", err))
+    }
+    return key
+}
+
+
+func cursorMacFn(key []byte, payload []byte) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write(payload)
+    return mac.Sum(nil)
+}
+
+
+// Opaque page cursor: base64url(payload) + "." + base64url(HMAC-SHA256(payload)).
+//  Client can't forge or alter it without key, payload is not secret
+func SignCursorFn(key []byte, payload []byte) string {
+    enc := base64.RawURLEncoding
+    return enc.EncodeToString(payload) + "." + enc.EncodeToString(cursorMacFn(key, payload))
+}
+
+
+// Returns payload of cursor made by SignCursorFn with same key, ErrInvalidCursor otherwise
+func VerifyCursorFn(key []byte, cursor string) ([]byte, error) {
+    fn := "VerifyCursorFn"
+    enc := base64.RawURLEncoding
+    rawPayload, rawMac, ok := strings.Cut(cursor, ".")
+    if !ok {
+        return nil, fmt.Errorf("%s: %w", fn, ErrInvalidCursor)
+    }
+    payload, err := enc.DecodeString(rawPayload)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", fn, ErrInvalidCursor)
+    }
+    mac, err := enc.DecodeString(rawMac)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", fn, ErrInvalidCursor)
+    }
+    // Constant time compare
+    if !hmac.Equal(mac, cursorMacFn(key, payload)) {
+        return nil, fmt.Errorf("%s: %w", fn, ErrInvalidCursor)
+    }
+    return payload, nil
+}
@@ -0,0 +1,91 @@
+package sharedapi
+import (
+    "testing"
+    "errors"
+    "bytes"
+    "strings"
+)
+
+
+//{{{ Test Sign/VerifyCursorFn
+func Test_VerifyCursorFn(t *testing.T) {
+    key := bytes.Repeat([]byte{0x01}, CursorKeyLen)
+    payload := []byte(`{"order":"username","username":"user_b"}`)
+    cursor := SignCursorFn(key, payload)
+    // Flip last char of payload part, still valid base64
+    rawPayload, rawMac, _ := strings.Cut(cursor, ".")
+    flipped := []byte(rawPayload)
+    if flipped[len(flipped)-1] == 'A' {
+        flipped[len(flipped)-1] = 'B'
+    } else {
+        flipped[len(flipped)-1] = 'A'
+    }
+    tests := []struct {
+        name                string
+        inputKey            []byte
+        inputCursor         string
+        expectedPayload     []byte
+        expectedErr         error
+    }{
+        {
+            name:               "RoundTrip",
+            inputKey:           key,
+            inputCursor:        cursor,
+            expectedPayload:    payload,
+            expectedErr:        nil,
+        }, {
+            name:               "TamperedPayload",
+            inputKey:           key,
+            inputCursor:        string(flipped) + "." + rawMac,
+            expectedPayload:    nil,
+            expectedErr:        ErrInvalidCursor,
+        }, {
+            name:               "OtherKey",
+            inputKey:           bytes.Repeat([]byte{0x02}, CursorKeyLen),
+            inputCursor:        cursor,
+            expectedPayload:    nil,
+            expectedErr:        ErrInvalidCursor,
+        }, {
+            name:               "MissingSignature",
+            inputKey:           key,
+            inputCursor:        rawPayload,
+            expectedPayload:    nil,
+            expectedErr:        ErrInvalidCursor,
+        }, {
+            name:               "NotBase64",
+            inputKey:           key,
+            inputCursor:        "not base64!." + rawMac,
+            expectedPayload:    nil,
+            expectedErr:        ErrInvalidCursor,
+        }, {
+            name:               "Empty",
+            inputKey:           key,
+            inputCursor:        "",
+            expectedPayload:    nil,
+            expectedErr:        ErrInvalidCursor,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := VerifyCursorFn(tc.inputKey, tc.inputCursor)
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if !bytes.Equal(got, tc.expectedPayload) {
+                t.Errorf("Wrong payload:\nExpected:\t%s\nGot:\t\t%s", tc.expectedPayload, got)
+            }
+        })
+    }
+}
+//}}} Test Sign/VerifyCursorFn
+
+
+//{{{ Test NewCursorKeyFn
+func Test_NewCursorKeyFn(t *testing.T) {
+    a, b := NewCursorKeyFn(), NewCursorKeyFn()
+    if len(a) != CursorKeyLen || bytes.Equal(a, b) {
+        t.Errorf("Expected 2 distinct keys of %d bytes, got: %x, %x", CursorKeyLen, a, b)
+    }
+}
+//}}} Test NewCursorKeyFn
@@ -0,0 +1,146 @@
+package sharedapi
+import (
+    "fmt"
+    "errors"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+// Single place where typed DB errors are turned into http status codes,
+//  succCode is returned when err is nil
+func StatusCodeFromErrFn(err error, succCode int) int {
+    switch {
+    case err == nil:
+        return succCode
+    case errors.Is(err, sdb.ErrUnknownColumn):
+        return 400
+    case errors.Is(err, sdb.ErrNotFound):
+        return 404
+    case errors.Is(err, sdb.ErrStaleVersion):
+        return 412
+    case errors.Is(err, sdb.ErrConflict):
+        return 409
+    case errors.Is(err, sdb.ErrInvalid):
+        return 422
+    case errors.Is(err, sdb.ErrRolledBack):
+        return 424
+    case errors.Is(err, sdb.ErrUnavailable):
+        return 503
+    case errors.Is(err, sdb.ErrTimeout):
+        return 504
+    default:
+        return 500
+    }
+}
+
+
+//{{{ Error codes
+// Machine readable error code of response, "<scope>.<kind>" ex.: user.conflict,
+//  validation.hex_length. entity is used for codes tied to resource, "" below 400
+func ErrorCodeFn(statusCode int, entity string, err error) string {
+    var fieldErr *smodels.FieldError
+    switch statusCode {
+    case 400:
+        switch {
+        case errors.Is(err, sdb.ErrUnknownColumn):
+            return "request.unknown_column"
+        case errors.Is(err, ErrInvalidJSON), errors.Is(err, ErrTrailingData), errors.Is(err, ErrMissingKey):
+            return "request.invalid_json"
+        }
+        return "request.invalid"
+    case 401:
+        return "auth.invalid_credentials"
+    case 404:
+        return fmt.Sprintf("%s.not_found", entity)
+    case 405:
+        return "request.method_not_allowed"
+    case 409:
+        if errors.Is(err, sdb.ErrReferenced) {
+            return fmt.Sprintf("%s.referenced", entity)
+        }
+        return fmt.Sprintf("%s.conflict", entity)
+    case 412:
+        if errors.Is(err, ErrPreconditionFailed) {
+            return "request.precondition_failed"
+        }
+        return fmt.Sprintf("%s.stale_version", entity)
+    case 413:
+        return "request.too_large"
+    case 422:
+        switch {
+        case errors.As(err, &fieldErr):
+            return fmt.Sprintf("validation.%s", fieldErr.Code)
+        case errors.Is(err, ErrUnknownField):
+            return "validation.unknown_field"
+        case errors.Is(err, sdb.ErrMissingReference):
+            return "validation.missing_reference"
+        }
+        return "validation.invalid"
+    case 424:
+        return fmt.Sprintf("%s.not_applied", entity)
+    case 503:
+        return "server.unavailable"
+    case 504:
+        return "server.timeout"
+    }
+    if statusCode >= 500 {
+        return "server.internal"
+    }
+    if statusCode >= 400 {
+        return "request.invalid"
+    }
+    return ""
+}
+
+
+// Per field problem of request, listed in problem+json errors array and 422 data
+type Violation struct {
+    Field       string  `json:"field"`
+    Code        string  `json:"code"`
+    Constraint  string  `json:"constraint,omitempty"`
+    Detail      string  `json:"detail"`
+}
+
+
+// Data of 422 response when model reported every invalid field (smodels.ValidationErrors)
+type ValidationData struct {
+    Errors  []Violation `json:"errors"`
+}
+
+
+func violationFn(fieldErr *smodels.FieldError) Violation {
+    return Violation{Field: fieldErr.Field, Code: fieldErr.Code, Constraint: fieldErr.Constraint, Detail: fieldErr.Message}
+}
+
+
+// Field violations carried by err (model validation), all of them for
+//  smodels.ValidationErrors in same order, nil when there are none
+func ViolationsFromErrFn(err error) []Violation {
+    var errs smodels.ValidationErrors
+    if errors.As(err, &errs) {
+        violations := make([]Violation, len(errs))
+        for i, fieldErr := range errs {
+            violations[i] = violationFn(fieldErr)
+        }
+        return violations
+    }
+    var fieldErr *smodels.FieldError
+    if !errors.As(err, &fieldErr) {
+        return nil
+    }
+    return []Violation{violationFn(fieldErr)}
+}
+
+
+// Data of 422 response listing every invalid field, nil unless err is smodels.ValidationErrors
+func ValidationDataFn(err error) interface{} {
+    var errs smodels.ValidationErrors
+    if !errors.As(err, &errs) {
+        return nil
+    }
+    return ValidationData{Errors: ViolationsFromErrFn(errs)}
+}
+//}}} Error codes
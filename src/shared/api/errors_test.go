@@ -0,0 +1,166 @@
+package sharedapi
+import (
+    "testing"
+    "errors"
+    "fmt"
+    "reflect"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+    smodels "github.com/FAH2S/diar4/src/shared/models"
+)
+
+
+//{{{ Test StatusCodeFromErrFn
+func Test_StatusCodeFromErrFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        inputErr            error
+        inputSuccCode       int
+        expectedStatusCode  int
+    }{
+        {
+            name:               "NilCreated",
+            inputErr:           nil,
+            inputSuccCode:      201,
+            expectedStatusCode: 201,
+        }, {
+            name:               "NilOK",
+            inputErr:           nil,
+            inputSuccCode:      200,
+            expectedStatusCode: 200,
+        }, {
+            name:               "UnknownColumn",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrUnknownColumn},
+            inputSuccCode:      200,
+            expectedStatusCode: 400,
+        }, {
+            name:               "NotFoundWrapped",
+            inputErr:           fmt.Errorf("SelectUser: %w", &sdb.DBError{Kind: sdb.ErrNotFound}),
+            inputSuccCode:      200,
+            expectedStatusCode: 404,
+        }, {
+            name:               "Conflict",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrConflict, Table: "users", Column: "username"},
+            inputSuccCode:      201,
+            expectedStatusCode: 409,
+        }, {
+            name:               "Referenced",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrReferenced, Constraint: "reviews_author_fkey"},
+            inputSuccCode:      200,
+            expectedStatusCode: 409,
+        }, {
+            name:               "MissingReference",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrMissingReference, Column: "event_id"},
+            inputSuccCode:      201,
+            expectedStatusCode: 422,
+        }, {
+            name:               "Invalid",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrInvalid, Constraint: "users_salt_check"},
+            inputSuccCode:      201,
+            expectedStatusCode: 422,
+        }, {
+            name:               "StaleVersion",
+            inputErr:           fmt.Errorf("UpdateUser: %w", &sdb.DBError{Kind: sdb.ErrStaleVersion}),
+            inputSuccCode:      200,
+            expectedStatusCode: 412,
+        }, {
+            name:               "RolledBack",
+            inputErr:           fmt.Errorf("BatchUsers: %w", sdb.ErrRolledBack),
+            inputSuccCode:      200,
+            expectedStatusCode: 424,
+        }, {
+            name:               "Unavailable",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrUnavailable},
+            inputSuccCode:      200,
+            expectedStatusCode: 503,
+        }, {
+            name:               "Timeout",
+            inputErr:           &sdb.DBError{Kind: sdb.ErrTimeout},
+            inputSuccCode:      200,
+            expectedStatusCode: 504,
+        }, {
+            name:               "Untyped",
+            inputErr:           errors.New("boom"),
+            inputSuccCode:      200,
+            expectedStatusCode: 500,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            statusCode := StatusCodeFromErrFn(tc.inputErr, tc.inputSuccCode)
+            if statusCode != tc.expectedStatusCode {
+                t.Errorf("\nExpected:\t%d\nGot:\t\t%d", tc.expectedStatusCode, statusCode)
+            }
+        })
+    }
+}
+//}}} Test StatusCodeFromErrFn
+
+
+//{{{ Test ErrorCodeFn
+func Test_ErrorCodeFn(t *testing.T) {
+    tests := []struct {
+        name            string
+        statusCode      int
+        err             error
+        expectedCode    string
+    }{
+        {"Success",             200, nil,                                                           ""},
+        {"InvalidJSON",         400, DecodeJSONFn([]byte(`{`), &map[string]interface{}{}, BodyOptions{}), "request.invalid_json"},
+        {"UnknownColumn",       400, &sdb.DBError{Kind: sdb.ErrUnknownColumn},                      "request.unknown_column"},
+        {"BadRequest",          400, errors.New("username: missing required field"),                "request.invalid"},
+        {"Unauthorized",        401, errors.New("proof doesn't match"),                             "auth.invalid_credentials"},
+        {"NotFound",            404, &sdb.DBError{Kind: sdb.ErrNotFound},                           "user.not_found"},
+        {"Conflict",            409, &sdb.DBError{Kind: sdb.ErrConflict},                           "user.conflict"},
+        {"Referenced",          409, &sdb.DBError{Kind: sdb.ErrReferenced},                         "user.referenced"},
+        {"StaleVersion",        412, &sdb.DBError{Kind: sdb.ErrStaleVersion},                       "user.stale_version"},
+        {"WeakIfMatch",         412, ErrPreconditionFailed,                                         "request.precondition_failed"},
+        {"TooLarge",            413, ErrBodyTooLarge,                                               "request.too_large"},
+        {"FieldError",          422, fmt.Errorf("Validate: %w", smodels.IsValidUsernameFn("x")),    "validation.username_length"},
+        {"UnknownField",        422, CheckKnownKeysFn(map[string]interface{}{"x": 1}, nil),         "validation.unknown_field"},
+        {"MissingReference",    422, &sdb.DBError{Kind: sdb.ErrMissingReference},                   "validation.missing_reference"},
+        {"Invalid",             422, &sdb.DBError{Kind: sdb.ErrInvalid},                            "validation.invalid"},
+        {"NotApplied",          424, sdb.ErrRolledBack,                                             "user.not_applied"},
+        {"Internal",            500, errors.New("boom"),                                            "server.internal"},
+        {"Unavailable",         503, &sdb.DBError{Kind: sdb.ErrUnavailable},                        "server.unavailable"},
+        {"Timeout",             504, &sdb.DBError{Kind: sdb.ErrTimeout},                            "server.timeout"},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code := ErrorCodeFn(tc.statusCode, "user", tc.err)
+            if code != tc.expectedCode {
+                t.Errorf("\nExpected:\t%s\nGot:\t\t%s", tc.expectedCode, code)
+            }
+        })
+    }
+
+    // Violations only for field errors
+    violations := ViolationsFromErrFn(fmt.Errorf("Validate: %w", smodels.IsValidHexStringFn("ab", "salt", 64)))
+    expected := []Violation{{Field: "salt", Code: "hex_length", Constraint: "64 chars", Detail: "salt: length must be exactly 64 char long"}}
+    if !reflect.DeepEqual(violations, expected) {
+        t.Errorf("Wrong violations:\nExpected:\t%+v\nGot:\t\t%+v", expected, violations)
+    }
+    if violations := ViolationsFromErrFn(errors.New("boom")); violations != nil {
+        t.Errorf("Wrong violations:\nExpected:\t%v\nGot:\t\t%+v", nil, violations)
+    }
+
+    // Collection lists every field in order, only collection becomes 422 data
+    user := smodels.User{Username: "test_user", Salt: "ab", Hash: "cd", EncSymkey: ""}
+    err := fmt.Errorf("Validate: %w", user.Validate())
+    fields := make([]string, 0)
+    for _, violation := range ViolationsFromErrFn(err) {
+        fields = append(fields, violation.Field)
+    }
+    if !reflect.DeepEqual(fields, []string{"salt", "hash", "enc_symkey"}) {
+        t.Errorf("Wrong violation fields:\nExpected:\t%v\nGot:\t\t%v", []string{"salt", "hash", "enc_symkey"}, fields)
+    }
+    if data, ok := ValidationDataFn(err).(ValidationData); !ok || len(data.Errors) != 3 {
+        t.Errorf("Wrong validation data:\nGot:\t%+v", ValidationDataFn(err))
+    }
+    if data := ValidationDataFn(smodels.IsValidUsernameFn("x")); data != nil {
+        t.Errorf("Wrong validation data:\nExpected:\t%v\nGot:\t\t%+v", nil, data)
+    }
+}
+//}}} Test ErrorCodeFn
@@ -0,0 +1,52 @@
+package sharedapi
+import (
+    "fmt"
+    "errors"
+    "strconv"
+    "strings"
+)
+
+
+// Strong ETag of row version ex.: "3"
+func FormatETagFn(version int64) string {
+    return fmt.Sprintf("%q", strconv.FormatInt(version, 10))
+}
+
+
+// If-Match lists only weak tags, under strong comparison they never match so
+//  precondition fails (412) whatever the current version is
+var ErrPreconditionFailed = errors.New(`If-Match: weak entity tag never matches, send ETag as is ex.: "3"`)
+
+
+// Versions listed by If-Match header, nil when header is empty or "*" (any version).
+//  Header may list several tags ex.: "3", "4". Weak tags (W/"3") are skipped since
+//  If-Match uses strong comparison, when nothing else is left ErrPreconditionFailed.
+//  Other errors are malformed header, client facing
+func ParseIfMatchFn(header string) ([]int64, error) {
+    header = strings.TrimSpace(header)
+    if header == "" || header == "*" {
+        return nil, nil
+    }
+    malformed := errors.New(`If-Match: must be quoted version ex.: "3" or list of them`)
+    var versions []int64
+    for _, tag := range strings.Split(header, ",") {
+        tag = strings.TrimSpace(tag)
+        weak := strings.HasPrefix(tag, "W/")
+        tag = strings.TrimPrefix(tag, "W/")
+        unquoted, err := strconv.Unquote(tag)
+        if err != nil || !strings.HasPrefix(tag, `"`) {
+            return nil, malformed
+        }
+        version, err := strconv.ParseInt(unquoted, 10, 64)
+        if err != nil || version < 1 {
+            return nil, malformed
+        }
+        if !weak {
+            versions = append(versions, version)
+        }
+    }
+    if len(versions) == 0 {
+        return nil, ErrPreconditionFailed
+    }
+    return versions, nil
+}
@@ -0,0 +1,52 @@
+package sharedapi
+import (
+    "testing"
+    "strings"
+    "errors"
+    "slices"
+)
+
+
+//{{{ Test ParseIfMatchFn
+func Test_ParseIfMatchFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               string
+        expectedVersions    []int64
+        expectedErrSubStr   string
+    }{
+        {"Empty",           "",                 nil,            ""},
+        {"Any",             "*",                nil,            ""},
+        {"Version",         `"3"`,              []int64{3},     ""},
+        {"RoundTrip",       FormatETagFn(42),   []int64{42},    ""},
+        {"List",            `"3", "4"`,         []int64{3, 4},  ""},
+        {"WeakAndStrong",   `W/"3", "4"`,       []int64{4},     ""},
+        {"Weak",            `W/"3"`,            nil,            "weak entity tag"},
+        {"WeakList",        `W/"3",W/"4"`,      nil,            "weak entity tag"},
+        {"Unquoted",        "3",                nil,            "must be quoted version"},
+        {"NotNumber",       `"abc"`,            nil,            "must be quoted version"},
+        {"Zero",            `"0"`,              nil,            "must be quoted version"},
+        {"WeakNotNumber",   `W/"abc"`,          nil,            "must be quoted version"},
+        {"EmptyListItem",   `"3",`,             nil,            "must be quoted version"},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            versions, err := ParseIfMatchFn(tc.input)
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrSubStr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErrSubStr, err)
+            }
+            // Only weak tags is precondition failure, not malformed header
+            if errors.Is(err, ErrPreconditionFailed) != (tc.expectedErrSubStr == "weak entity tag") {
+                t.Errorf("Wrong error kind, ErrPreconditionFailed: %v", errors.Is(err, ErrPreconditionFailed))
+            }
+            if !slices.Equal(versions, tc.expectedVersions) {
+                t.Errorf("Wrong versions:\nExpected:\t%v\nGot:\t\t%v", tc.expectedVersions, versions)
+            }
+        })
+    }
+}
+//}}} Test ParseIfMatchFn
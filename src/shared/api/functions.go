@@ -1,32 +1,72 @@
 package sharedapi
 import (
+    "bytes"
     "encoding/json"
     "net/http"
     "fmt"
     "strings"
+    "time"
+    "context"
+    "errors"
+)
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
 )
 
-//TODO: rethink about this fn current problem is reading value will dump/erase
-//  r.Body either repouplate it or rethink if this is even needed
-func ExtractJSONValueFn(r *http.Request, key string, target interface{}) error {
+// Extracts one key from JSON body, returns request holding cached body. r is drained
+//  unless it was cached already, so extract other keys from returned request
+func ExtractJSONValueFn(r *http.Request, key string, target interface{}) (*http.Request, error) {
     fn := "ExtractJSONValueFn"
-    var raw map[string]json.RawMessage
-    if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
-        return fmt.Errorf("%s: Invalid JSON: %w", fn, err)
+    cached, raw, err := ReadBodyFn(r, 0)
+    if err != nil {
+        return cached, fmt.Errorf("%s: %w", fn, err)
     }
-
-    val, ok := raw[key]
-    if !ok {
-        return fmt.Errorf("%s: missing key: %s", fn, key)
+    decoder := &BodyDecoder{req: cached, raw: raw}
+    if err := decoder.Extract(map[string]interface{}{key: target}); err != nil {
+        return cached, fmt.Errorf("%s: %w", fn, err)
     }
+    return cached, nil
+}
 
-    if err := json.Unmarshal(val, target); err != nil {
-        return fmt.Errorf("%s: failed to unmarshal value: %v to target type: %T", fn, val, target)
+
+// Request clone whose JSON object body also has values (they override body keys),
+//  lets path/query params feed body based endpoints. Empty body counts as {},
+//  body that isn't JSON object is kept as is so endpoint reports it
+func MergeJSONBodyFn(r *http.Request, values map[string]interface{}) *http.Request {
+    _, raw, err := ReadBodyFn(r, 0)
+    // Own copy, merged body cached on it shadows cache of r
+    merged := setBodyCacheFn(r.Clone(r.Context()), raw, err)
+    if err != nil {
+        return merged
+    }
+    body := map[string]json.RawMessage{}
+    if len(bytes.TrimSpace(raw)) > 0 {
+        if err = json.Unmarshal(raw, &body); err != nil || body == nil {
+            return merged
+        }
     }
+    for key, val := range values {
+        encoded, err := json.Marshal(val)
+        if err != nil {
+            return merged
+        }
+        body[key] = encoded
+    }
+    encoded, _ := json.Marshal(body)
+    return setBodyCacheFn(merged, encoded, nil)
+}
 
-    return nil
+
+// Derive store operation context from request, client disconnect cancels it too.
+//  timeout <= 0 means only bound by request context
+func OpContextFn(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+    if timeout <= 0 {
+        return context.WithCancel(r.Context())
+    }
+    return context.WithTimeout(r.Context(), timeout)
 }
 
+
 //TODO: potentially not api-layer but something else
 func SanitizeKeysFn(inputMap map[string]interface{}, allowed []string) (map[string]interface{}) {
     allowedSet := make(map[string]struct{}, len(allowed))
@@ -53,6 +93,15 @@ func MapStatusCodeFn(code int, action, entity, name string, err error) (msg, err
     case 201:
         msg := fmt.Sprintf("Success: %s %s '%s'", action, entity, name)
         return msg, "", true
+    case 207:
+        // Batch committed but some items failed, details are per item
+        msg := fmt.Sprintf("Partial: %s %s '%s'", action, entity, name)
+        errMsg := "Some items failed, see data"
+        return msg, errMsg, true
+    case 401:
+        msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
+        errMsg := "Invalid credentials"
+        return msg, errMsg, false
     case 404:
         msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
         errMsg := fmt.Sprintf("%s not found, dosen't exist", strings.ToUpper(entity[:1]) + entity[1:])
@@ -60,15 +109,39 @@ func MapStatusCodeFn(code int, action, entity, name string, err error) (msg, err
     case 409:
         msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
         errMsg := fmt.Sprintf("%s already exist", strings.ToUpper(entity[:1]) + entity[1:])
+        if errors.Is(err, sdb.ErrReferenced) {
+            errMsg = fmt.Sprintf("%s is still referenced", strings.ToUpper(entity[:1]) + entity[1:])
+        }
+        return msg, errMsg, false
+    case 412:
+        msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
+        errMsg := fmt.Sprintf("%s was modified, read it again and retry", strings.ToUpper(entity[:1]) + entity[1:])
         return msg, errMsg, false
     case 422:
         msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
         errMsg := fmt.Sprintf("Invalid input format: %v", err)
+        // Don't leak driver error, name referencing column instead
+        var dbErr *sdb.DBError
+        if errors.Is(err, sdb.ErrMissingReference) && errors.As(err, &dbErr) {
+            errMsg = fmt.Sprintf("Invalid reference: '%s' doesn't exist", dbErr.Column)
+        }
+        return msg, errMsg, false
+    case 424:
+        msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
+        errMsg := "Not applied, other item in transaction failed"
         return msg, errMsg, false
     case 500:
         msg := fmt.Sprintf("Fail: %s %s", action, entity)
         errMsg := "Internal server error"
         return msg, errMsg, false
+    case 503:
+        msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
+        errMsg := "Service unavailable, try again later"
+        return msg, errMsg, false
+    case 504:
+        msg := fmt.Sprintf("Fail: %s %s '%s'", action, entity, name)
+        errMsg := "Operation timed out"
+        return msg, errMsg, false
     default:
         msg := fmt.Sprintf("Fail: %s %s", action, entity)
         errMsg := "Unknown error"
@@ -1,11 +1,16 @@
 package sharedapi
 import (
+    "io"
     "testing"
+    "time"
     "net/http"
     "net/http/httptest"
     "strings"
     "reflect"
 )
+import (
+    sdb "github.com/FAH2S/diar4/src/shared/db"
+)
 
 //{{{ Test MapStatusCodeFn
 func Test_MapStatusCodeFn(t *testing.T) {
@@ -30,6 +35,66 @@ func Test_MapStatusCodeFn(t *testing.T) {
             ExpectedMsg:        "Success: create user 'test_user'",
             ExpectedErrMsg:     "",
             ExpectedBool:       true,
+        }, {
+            name:               "401Fail",
+            inputStatusCode:    401,
+            inputAction:        "verify",
+            inputEntity:        "user",
+            inputName:          "test_user",
+            inputError:         nil,
+            ExpectedMsg:        "Fail: verify user 'test_user'",
+            ExpectedErrMsg:     "Invalid credentials",
+            ExpectedBool:       false,
+        }, {
+            name:               "409Referenced",
+            inputStatusCode:    409,
+            inputAction:        "delete",
+            inputEntity:        "event",
+            inputName:          "1",
+            inputError:         &sdb.DBError{Kind: sdb.ErrReferenced},
+            ExpectedMsg:        "Fail: delete event '1'",
+            ExpectedErrMsg:     "Event is still referenced",
+            ExpectedBool:       false,
+        }, {
+            name:               "422MissingReference",
+            inputStatusCode:    422,
+            inputAction:        "create",
+            inputEntity:        "review",
+            inputName:          "",
+            inputError:         &sdb.DBError{Kind: sdb.ErrMissingReference, Column: "event_id"},
+            ExpectedMsg:        "Fail: create review ''",
+            ExpectedErrMsg:     "Invalid reference: 'event_id' doesn't exist",
+            ExpectedBool:       false,
+        }, {
+            name:               "207Partial",
+            inputStatusCode:    207,
+            inputAction:        "batch create",
+            inputEntity:        "user",
+            inputName:          "3",
+            inputError:         nil,
+            ExpectedMsg:        "Partial: batch create user '3'",
+            ExpectedErrMsg:     "Some items failed, see data",
+            ExpectedBool:       true,
+        }, {
+            name:               "424RolledBack",
+            inputStatusCode:    424,
+            inputAction:        "create",
+            inputEntity:        "user",
+            inputName:          "test_user",
+            inputError:         &sdb.DBError{Kind: sdb.ErrRolledBack},
+            ExpectedMsg:        "Fail: create user 'test_user'",
+            ExpectedErrMsg:     "Not applied, other item in transaction failed",
+            ExpectedBool:       false,
+        }, {
+            name:               "412StaleVersion",
+            inputStatusCode:    412,
+            inputAction:        "update",
+            inputEntity:        "user",
+            inputName:          "test_user",
+            inputError:         &sdb.DBError{Kind: sdb.ErrStaleVersion},
+            ExpectedMsg:        "Fail: update user 'test_user'",
+            ExpectedErrMsg:     "User was modified, read it again and retry",
+            ExpectedBool:       false,
         }, {
             name:               "404Fail",
             inputStatusCode:    404,
@@ -60,6 +125,26 @@ func Test_MapStatusCodeFn(t *testing.T) {
             ExpectedMsg:        "Fail: create user",
             ExpectedErrMsg:     "Internal server error",
             ExpectedBool:       false,
+        }, {
+            name:               "503Unavailable",
+            inputStatusCode:    503,
+            inputAction:        "read",
+            inputEntity:        "user",
+            inputName:          "test_user",
+            inputError:         nil,
+            ExpectedMsg:        "Fail: read user 'test_user'",
+            ExpectedErrMsg:     "Service unavailable, try again later",
+            ExpectedBool:       false,
+        }, {
+            name:               "504Timeout",
+            inputStatusCode:    504,
+            inputAction:        "update",
+            inputEntity:        "user",
+            inputName:          "test_user",
+            inputError:         nil,
+            ExpectedMsg:        "Fail: update user 'test_user'",
+            ExpectedErrMsg:     "Operation timed out",
+            ExpectedBool:       false,
         },
     }
 
@@ -134,7 +219,7 @@ func Test_ExtractJSONValueFn(t *testing.T) {
         t.Run(tc.name, func(t *testing.T) {
             req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.jsonBody))
             req.Header.Set("Content-Type", "application/json")
-            err := ExtractJSONValueFn(req, tc.key, tc.target)
+            _, err := ExtractJSONValueFn(req, tc.key, tc.target)
 
             // Don't expect error but got one
             if tc.expectedErr == "" && err != nil {
@@ -159,6 +244,70 @@ func Test_ExtractJSONValueFn(t *testing.T) {
 //}}} Test ExtractJSONValueFn
 
 
+//{{{ Test MergeJSONBodyFn
+func Test_MergeJSONBodyFn(t *testing.T) {
+    tests := []struct {
+        name            string
+        jsonBody        string
+        values          map[string]interface{}
+        expectedBody    string
+    }{
+        {
+            name:           "EmptyBody",
+            jsonBody:       "",
+            values:         map[string]interface{}{"username": "test_user"},
+            expectedBody:   `{"username":"test_user"}`,
+        }, {
+            name:           "ValuesOverrideBody",
+            jsonBody:       `{"username": "other", "salt": "ab"}`,
+            values:         map[string]interface{}{"username": "test_user", "fields": []string{"salt"}},
+            expectedBody:   `{"fields":["salt"],"salt":"ab","username":"test_user"}`,
+        }, {
+            // Endpoint gets original body and answers "Invalid JSON" itself
+            name:           "InvalidJSONKept",
+            jsonBody:       `{"username": "`,
+            values:         map[string]interface{}{"username": "test_user"},
+            expectedBody:   `{"username": "`,
+        }, {
+            name:           "NotObjectKept",
+            jsonBody:       `null`,
+            values:         map[string]interface{}{"username": "test_user"},
+            expectedBody:   `null`,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodPatch, "/users/test_user", strings.NewReader(tc.jsonBody))
+            got, err := io.ReadAll(MergeJSONBodyFn(req, tc.values).Body)
+            if err != nil || string(got) != tc.expectedBody {
+                t.Errorf("Wrong body:\nExpected:\t%s\nGot:\t\t%s (%v)", tc.expectedBody, got, err)
+            }
+        })
+    }
+}
+//}}} Test MergeJSONBodyFn
+
+
+//{{{ Test OpContextFn
+func Test_OpContextFn(t *testing.T) {
+    req := httptest.NewRequest("POST", "/", nil)
+    // No timeout, no deadline
+    opCtx, cancel := OpContextFn(req, 0)
+    if _, ok := opCtx.Deadline(); ok {
+        t.Errorf("Expected no deadline for timeout 0")
+    }
+    cancel()
+    // Timeout sets deadline
+    opCtx, cancel = OpContextFn(req, time.Second)
+    defer cancel()
+    if _, ok := opCtx.Deadline(); !ok {
+        t.Errorf("Expected deadline for timeout 1s")
+    }
+}
+//}}} Test OpContextFn
+
+
 //{{{ Test SanitizeKeysFn
 func Test_SanitizeKeysFn(t *testing.T) {
     tests := []struct{
@@ -1,3 +1,14 @@
 module github.com/FAH2S/diar4/src/shared/api
 
 go 1.22.2
+
+require (
+	github.com/FAH2S/diar4/src/shared/db v0.0.0-20250831140142-fb209de09923
+	github.com/FAH2S/diar4/src/shared/models v0.0.0-20250828143826-8ca2d9ad4d84
+)
+
+require github.com/lib/pq v1.10.9 // indirect
+
+replace github.com/FAH2S/diar4/src/shared/db => ../db
+
+replace github.com/FAH2S/diar4/src/shared/models => ../models
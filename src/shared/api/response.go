@@ -1,5 +1,9 @@
 package sharedapi
 import (
+    "mime"
+    "reflect"
+    "strings"
+    "strconv"
     "net/http"
     "encoding/json"
 )
@@ -8,18 +12,126 @@ import (
 type APIResponse struct {
     Message string      `json:"message"`
     Error   string      `json:"error"`
+    Code    string      `json:"code,omitempty"`
     Data    interface{} `json:"data"`
 }
 
 
+// RFC 9457 error document, sent instead of APIResponse when client asked for it
+//  (see NegotiateProblemFn). Code, Errors and Data are extension members
+type Problem struct {
+    Type        string      `json:"type"`
+    Title       string      `json:"title"`
+    Status      int         `json:"status"`
+    Detail      string      `json:"detail,omitempty"`
+    Instance    string      `json:"instance,omitempty"`
+    Code        string      `json:"code,omitempty"`
+    Errors      []Violation `json:"errors,omitempty"`
+    Data        interface{} `json:"data,omitempty"`
+}
+
+
+const (
+    ProblemContentType  = "application/problem+json"
+    // Problem type is prefix + error code, ex.: urn:diar4:problem:user.conflict
+    ProblemTypePrefix   = "urn:diar4:problem:"
+)
+
+
 func WriteJSONResponseFn(w http.ResponseWriter, statusCode int, message string, errMsg string, data interface{}) {
+    WriteCodedResponseFn(w, statusCode, message, errMsg, "", nil, data)
+}
+
+
+// Same as WriteJSONResponseFn plus machine readable code (see ErrorCodeFn), err is
+//  source of per field violations. Error responses on problem writer are problem+json,
+//  otherwise 422 without data gets every invalid field as data (see ValidationDataFn)
+func WriteCodedResponseFn(w http.ResponseWriter, statusCode int, message string, errMsg string, code string, err error, data interface{}) {
+    if pw, ok := w.(*problemWriter); ok && statusCode >= 400 {
+        writeProblemFn(pw, statusCode, errMsg, code, err, data)
+        return
+    }
+    if statusCode == 422 && isNilDataFn(data) {
+        data = ValidationDataFn(err)
+    }
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(statusCode)
 
     response := APIResponse{
         Message:    message,
         Error:      errMsg,
+        Code:       code,
         Data:       data,
     }
     json.NewEncoder(w).Encode(response)
 }
+
+
+// Untyped or typed nil (ex.: nil map of handler), both are encoded as null
+func isNilDataFn(data interface{}) bool {
+    if data == nil {
+        return true
+    }
+    val := reflect.ValueOf(data)
+    switch val.Kind() {
+    case reflect.Map, reflect.Pointer, reflect.Slice, reflect.Interface:
+        return val.IsNil()
+    }
+    return false
+}
+
+
+//{{{ Problem
+// Marks response writer of client that accepts problem+json
+type problemWriter struct {
+    http.ResponseWriter
+    instance    string
+}
+
+
+// Client lists application/problem+json in Accept (q > 0)
+func AcceptsProblemFn(r *http.Request) bool {
+    for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+        mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+        if err != nil || mediaType != ProblemContentType {
+            continue
+        }
+        q, err := strconv.ParseFloat(params["q"], 64)
+        if params["q"] == "" || (err == nil && q > 0) {
+            return true
+        }
+    }
+    return false
+}
+
+
+// Writer to pass down when client accepts problem+json, otherwise w as is.
+//  Success responses are unchanged, only errors switch format
+func NegotiateProblemFn(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+    if _, ok := w.(*problemWriter); ok || !AcceptsProblemFn(r) {
+        return w
+    }
+    return &problemWriter{ResponseWriter: w, instance: r.URL.RequestURI()}
+}
+
+
+func writeProblemFn(pw *problemWriter, statusCode int, errMsg string, code string, err error, data interface{}) {
+    problemType := "about:blank"
+    if code != "" {
+        problemType = ProblemTypePrefix + code
+    }
+    problem := Problem{
+        Type:       problemType,
+        Title:      http.StatusText(statusCode),
+        Status:     statusCode,
+        Detail:     errMsg,
+        Instance:   pw.instance,
+        Code:       code,
+        Errors:     ViolationsFromErrFn(err),
+        Data:       data,
+    }
+    pw.Header().Set("Content-Type", ProblemContentType)
+    pw.WriteHeader(statusCode)
+    json.NewEncoder(pw).Encode(problem)
+}
+//}}} Problem
@@ -4,6 +4,10 @@ import (
     "net/http/httptest"
     "encoding/json"
     "reflect"
+    "strings"
+)
+import (
+    smodels "github.com/FAH2S/diar4/src/shared/models"
 )
 
 func Test_WriteJSONResponseFn(t *testing.T) {
@@ -74,3 +78,111 @@ func Test_WriteJSONResponseFn(t *testing.T) {
 }
 
 
+
+
+func Test_WriteCodedResponseFn(t *testing.T) {
+    violationErr := smodels.IsValidUsernameFn("x")
+    validationErr := (&smodels.User{Username: "x", Salt: "ab", Hash: strings.Repeat("a", 64), EncSymkey: strings.Repeat("b", 120)}).Validate()
+    tests := []struct {
+        name                string
+        accept              string
+        statusCode          int
+        code                string
+        err                 error
+        expectedCT          string
+        expectedBody        map[string]interface{}
+    }{
+        {
+            name:           "Envelope",
+            accept:         "application/json",
+            statusCode:     409,
+            code:           "user.conflict",
+            expectedCT:     "application/json",
+            expectedBody:   map[string]interface{}{"message": "Fail: create user 'x'", "error": "User already exist", "code": "user.conflict", "data": nil},
+        }, {
+            name:           "Problem",
+            accept:         "application/json, application/problem+json;q=0.9",
+            statusCode:     422,
+            code:           "validation.username_length",
+            err:            violationErr,
+            expectedCT:     "application/problem+json",
+            expectedBody:   map[string]interface{}{
+                "type":     "urn:diar4:problem:validation.username_length",
+                "title":    "Unprocessable Entity",
+                "status":   float64(422),
+                "detail":   "User already exist",
+                "instance": "/users/x?fields=salt",
+                "code":     "validation.username_length",
+                "errors":   []interface{}{map[string]interface{}{
+                    "field":        "username",
+                    "code":         "username_length",
+                    "constraint":   "3..30 chars",
+                    "detail":       "username: length must be between 3 and 30 char long",
+                }},
+            },
+        }, {
+            name:           "ValidationData",
+            statusCode:     422,
+            code:           "validation.username_length",
+            err:            validationErr,
+            expectedCT:     "application/json",
+            expectedBody:   map[string]interface{}{
+                "message":  "Fail: create user 'x'",
+                "error":    "User already exist",
+                "code":     "validation.username_length",
+                "data":     map[string]interface{}{"errors": []interface{}{
+                    map[string]interface{}{"field": "username", "code": "username_length", "constraint": "3..30 chars", "detail": "username: length must be between 3 and 30 char long"},
+                    map[string]interface{}{"field": "salt", "code": "hex_length", "constraint": "64 chars", "detail": "salt: length must be exactly 64 char long"},
+                }},
+            },
+        }, {
+            name:           "ProblemNoCode",
+            accept:         "application/problem+json",
+            statusCode:     500,
+            expectedCT:     "application/problem+json",
+            expectedBody:   map[string]interface{}{
+                "type":     "about:blank",
+                "title":    "Internal Server Error",
+                "status":   float64(500),
+                "detail":   "User already exist",
+                "instance": "/users/x?fields=salt",
+            },
+        }, {
+            name:           "ProblemRejected",
+            accept:         "application/problem+json;q=0",
+            statusCode:     500,
+            code:           "server.internal",
+            expectedCT:     "application/json",
+            expectedBody:   map[string]interface{}{"message": "Fail: create user 'x'", "error": "User already exist", "code": "server.internal", "data": nil},
+        }, {
+            name:           "ProblemSuccess",
+            accept:         "application/problem+json",
+            statusCode:     200,
+            expectedCT:     "application/json",
+            expectedBody:   map[string]interface{}{"message": "Fail: create user 'x'", "error": "User already exist", "data": nil},
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest("GET", "/users/x?fields=salt", nil)
+            req.Header.Set("Accept", tc.accept)
+            rec := httptest.NewRecorder()
+            w := NegotiateProblemFn(rec, req)
+            WriteCodedResponseFn(w, tc.statusCode, "Fail: create user 'x'", "User already exist", tc.code, tc.err, nil)
+
+            if rec.Code != tc.statusCode {
+                t.Errorf("\nExpected:\t%d\nGot:\t\t%d", tc.statusCode, rec.Code)
+            }
+            if ct := rec.Header().Get("Content-Type"); ct != tc.expectedCT {
+                t.Errorf("\nExpected:\t%s\nGot:\t\t%s", tc.expectedCT, ct)
+            }
+            var body map[string]interface{}
+            if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+                t.Fatalf("Failed to decode response body: %v", err)
+            }
+            if !reflect.DeepEqual(body, tc.expectedBody) {
+                t.Errorf("\nExpected:\t%v\nGot:\t\t%v", tc.expectedBody, body)
+            }
+        })
+    }
+}
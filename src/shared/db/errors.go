@@ -0,0 +1,54 @@
+package shareddb
+import (
+    "errors"
+    "fmt"
+)
+
+
+// Error kinds returned by DB layer, check with errors.Is
+var (
+    ErrConflict         = errors.New("conflict")
+    ErrNotFound         = errors.New("not found")
+    ErrInvalid          = errors.New("invalid data/format")
+    ErrUnknownColumn    = errors.New("unknown column")
+    ErrTimeout          = errors.New("operation timed out")
+    ErrUnavailable      = errors.New("database unavailable")
+    ErrRolledBack       = errors.New("not applied, transaction rolled back")
+    ErrStaleVersion     = errors.New("row version changed")
+)
+
+
+// Foreign key kinds, refine base kinds so errors.Is(err, ErrConflict/ErrInvalid) still holds
+var (
+    ErrReferenced       = fmt.Errorf("%w: still referenced", ErrConflict)
+    ErrMissingReference = fmt.Errorf("%w: referenced row doesn't exist", ErrInvalid)
+)
+
+
+// Serialization failure/deadlock, transaction can be retried, see WithTx
+var ErrSerialization = fmt.Errorf("%w: concurrent transaction conflict", ErrUnavailable)
+
+
+// Typed DB error, Kind is one of Err* above, rest is best effort context
+//  taken from postgres error (may be empty). Check with errors.As
+type DBError struct {
+    Kind        error
+    Table       string
+    Column      string
+    Constraint  string
+    Err         error
+}
+
+
+func (e *DBError) Error() string {
+    if e.Err == nil {
+        return e.Kind.Error()
+    }
+    return e.Err.Error()
+}
+
+
+// Both kind and underlying error are part of chain
+func (e *DBError) Unwrap() []error {
+    return []error{e.Kind, e.Err}
+}
@@ -0,0 +1,267 @@
+package shareddb
+import (
+    "fmt"
+    "sort"
+    "time"
+    "regexp"
+    "strconv"
+    "context"
+    "io/fs"
+    "database/sql"
+)
+
+
+// Arbitrary but fixed key, every migration runner locks on it so only one
+//  runner (ex.: several replicas starting at once) touches schema at a time
+const migrationLockKey int64 = 4242001
+
+
+// Migration file name ex.: `0002_add_updated_at.up.sql`
+var migrationFileMatch = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+
+type Migration struct {
+    Version int64
+    Name    string
+    Up      string
+    Down    string
+}
+
+
+type MigrationState struct {
+    Version     int64
+    Name        string
+    Applied     bool
+    AppliedAt   time.Time
+}
+
+
+//{{{ Load
+// Reads `*.up.sql`/`*.down.sql` pairs from root of fsys, sorted by version
+func LoadMigrationsFn(fsys fs.FS) ([]Migration, error) {
+    fn := "LoadMigrationsFn"
+    entries, err := fs.ReadDir(fsys, ".")
+    if err != nil {
+        return nil, fmt.Errorf("%s: failed to read migrations: %w", fn, err)
+    }
+
+    byVersion := make(map[int64]*Migration)
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+        match := migrationFileMatch.FindStringSubmatch(entry.Name())
+        if match == nil {
+            return nil, fmt.Errorf("%s: invalid migration file name: %q", fn, entry.Name())
+        }
+        version, err := strconv.ParseInt(match[1], 10, 64)
+        if err != nil || version <= 0 {
+            return nil, fmt.Errorf("%s: invalid migration version: %q", fn, entry.Name())
+        }
+        content, err := fs.ReadFile(fsys, entry.Name())
+        if err != nil {
+            return nil, fmt.Errorf("%s: failed to read %q: %w", fn, entry.Name(), err)
+        }
+
+        // Same version must share name
+        m, ok := byVersion[version]
+        if !ok {
+            m = &Migration{Version: version, Name: match[2]}
+            byVersion[version] = m
+        } else if m.Name != match[2] {
+            return nil, fmt.Errorf("%s: duplicate migration version %d: %q and %q", fn, version, m.Name, match[2])
+        }
+        if match[3] == "up" {
+            m.Up = string(content)
+        } else {
+            m.Down = string(content)
+        }
+    }
+
+    // Both directions are required
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, m := range byVersion {
+        if m.Up == "" || m.Down == "" {
+            return nil, fmt.Errorf("%s: migration %d_%s must have both up and down file", fn, m.Version, m.Name)
+        }
+        migrations = append(migrations, *m)
+    }
+    sort.Slice(migrations, func(i, j int) bool {
+        return migrations[i].Version < migrations[j].Version
+    })
+    return migrations, nil
+}
+//}}} Load
+
+
+//{{{ helper
+// Runs fn on single connection holding advisory lock, makes sure tracking table exists
+func withMigrationLockFn(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+    wrap := "withMigrationLockFn"
+    conn, err := db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("%s: failed to get connection: %w", wrap, err)
+    }
+    defer conn.Close()
+
+    // Session level lock, blocks until other runner is done
+    if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+        return fmt.Errorf("%s: failed to acquire lock: %w", wrap, err)
+    }
+    defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+    query := `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version     BIGINT      PRIMARY KEY,
+            name        TEXT        NOT NULL,
+            applied_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )
+    `
+    if _, err = conn.ExecContext(ctx, query); err != nil {
+        return fmt.Errorf("%s: failed to create schema_migrations: %w", wrap, err)
+    }
+    return fn(conn)
+}
+
+
+func appliedMigrationsFn(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+    fn := "appliedMigrationsFn"
+    rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+    if err != nil {
+        return nil, fmt.Errorf("%s: failed to execute query: %w", fn, err)
+    }
+    defer rows.Close()
+
+    applied := make(map[int64]time.Time)
+    for rows.Next() {
+        var (
+            version     int64
+            appliedAt   time.Time
+        )
+        if err := rows.Scan(&version, &appliedAt); err != nil {
+            return nil, fmt.Errorf("%s: failed to scan row: %w", fn, err)
+        }
+        applied[version] = appliedAt
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("%s: failed to read rows: %w", fn, err)
+    }
+    return applied, nil
+}
+
+
+// Executes migration SQL and updates tracking table in one transaction
+func runMigrationFn(ctx context.Context, conn *sql.Conn, m Migration, up bool) error {
+    fn := "runMigrationFn"
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("%s: failed to begin transaction: %w", fn, err)
+    }
+    defer tx.Rollback()
+
+    script, track := m.Down, `DELETE FROM schema_migrations WHERE version = $1`
+    if up {
+        script, track = m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+    }
+    if _, err = tx.ExecContext(ctx, script); err != nil {
+        return fmt.Errorf("%s: migration %d_%s failed: %w", fn, m.Version, m.Name, err)
+    }
+    args := []interface{}{m.Version}
+    if up {
+        args = append(args, m.Name)
+    }
+    if _, err = tx.ExecContext(ctx, track, args...); err != nil {
+        return fmt.Errorf("%s: failed to track migration %d: %w", fn, m.Version, err)
+    }
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("%s: failed to commit migration %d: %w", fn, m.Version, err)
+    }
+    return nil
+}
+//}}} helper
+
+
+//{{{ Up, Down, Status
+// Applies every pending migration in ascending order, returns applied versions
+func MigrateUp(ctx context.Context, db *sql.DB, migrations []Migration) ([]int64, error) {
+    wrap := "MigrateUp"
+    done := []int64{}
+    err := withMigrationLockFn(ctx, db, func(conn *sql.Conn) error {
+        applied, err := appliedMigrationsFn(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for _, m := range migrations {
+            if _, ok := applied[m.Version]; ok {
+                continue
+            }
+            if err := runMigrationFn(ctx, conn, m, true); err != nil {
+                return err
+            }
+            done = append(done, m.Version)
+        }
+        return nil
+    })
+    if err != nil {
+        return done, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return done, nil
+}
+
+
+// Reverts last `steps` applied migrations in descending order, returns reverted versions
+func MigrateDown(ctx context.Context, db *sql.DB, migrations []Migration, steps int) ([]int64, error) {
+    wrap := "MigrateDown"
+    if steps < 1 {
+        return nil, fmt.Errorf("%s: steps must be at least 1, got %d", wrap, steps)
+    }
+    done := []int64{}
+    err := withMigrationLockFn(ctx, db, func(conn *sql.Conn) error {
+        applied, err := appliedMigrationsFn(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+            m := migrations[i]
+            if _, ok := applied[m.Version]; !ok {
+                continue
+            }
+            if err := runMigrationFn(ctx, conn, m, false); err != nil {
+                return err
+            }
+            done = append(done, m.Version)
+        }
+        return nil
+    })
+    if err != nil {
+        return done, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return done, nil
+}
+
+
+func GetMigrationStatus(ctx context.Context, db *sql.DB, migrations []Migration) ([]MigrationState, error) {
+    wrap := "GetMigrationStatus"
+    states := make([]MigrationState, 0, len(migrations))
+    err := withMigrationLockFn(ctx, db, func(conn *sql.Conn) error {
+        applied, err := appliedMigrationsFn(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for _, m := range migrations {
+            appliedAt, ok := applied[m.Version]
+            states = append(states, MigrationState{
+                Version:    m.Version,
+                Name:       m.Name,
+                Applied:    ok,
+                AppliedAt:  appliedAt,
+            })
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", wrap, err)
+    }
+    return states, nil
+}
+//}}} Up, Down, Status
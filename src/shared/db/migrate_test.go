@@ -0,0 +1,80 @@
+package shareddb
+import (
+    "testing"
+    "strings"
+    "reflect"
+    "testing/fstest"
+)
+
+
+//{{{ LoadMigrationsFn
+func Test_LoadMigrationsFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        files               fstest.MapFS
+        expected            []Migration
+        expectedErrSubStr   string
+    }{
+        {
+            name:               "SortedPairs",
+            files:              fstest.MapFS{
+                "0002_add_column.up.sql":       {Data: []byte("ALTER TABLE t ADD c INT;")},
+                "0002_add_column.down.sql":     {Data: []byte("ALTER TABLE t DROP c;")},
+                "0001_create_table.up.sql":     {Data: []byte("CREATE TABLE t ();")},
+                "0001_create_table.down.sql":   {Data: []byte("DROP TABLE t;")},
+            },
+            expected:           []Migration{
+                {Version: 1, Name: "create_table", Up: "CREATE TABLE t ();", Down: "DROP TABLE t;"},
+                {Version: 2, Name: "add_column", Up: "ALTER TABLE t ADD c INT;", Down: "ALTER TABLE t DROP c;"},
+            },
+            expectedErrSubStr:  "",
+        }, {
+            name:               "MissingDown",
+            files:              fstest.MapFS{
+                "0001_create_table.up.sql":     {Data: []byte("CREATE TABLE t ();")},
+            },
+            expected:           nil,
+            expectedErrSubStr:  "must have both up and down file",
+        }, {
+            name:               "InvalidName",
+            files:              fstest.MapFS{
+                "create_table.sql":             {Data: []byte("CREATE TABLE t ();")},
+            },
+            expected:           nil,
+            expectedErrSubStr:  "invalid migration file name",
+        }, {
+            name:               "DuplicateVersion",
+            files:              fstest.MapFS{
+                "0001_create_table.up.sql":     {Data: []byte("CREATE TABLE t ();")},
+                "0001_create_other.up.sql":     {Data: []byte("CREATE TABLE o ();")},
+            },
+            expected:           nil,
+            expectedErrSubStr:  "duplicate migration version 1",
+        }, {
+            name:               "ZeroVersion",
+            files:              fstest.MapFS{
+                "0000_create_table.up.sql":     {Data: []byte("CREATE TABLE t ();")},
+            },
+            expected:           nil,
+            expectedErrSubStr:  "invalid migration version",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            migrations, err := LoadMigrationsFn(tc.files)
+            // Check error
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrSubStr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErrSubStr, err)
+            }
+            // Check migrations
+            if !reflect.DeepEqual(tc.expected, migrations) {
+                t.Errorf("Wrong migrations:\nExpected:\t%+v\nGot:\t\t%+v", tc.expected, migrations)
+            }
+        })
+    }
+}
+//}}} LoadMigrationsFn
@@ -0,0 +1,180 @@
+package shareddb
+import (
+    "os"
+    "fmt"
+    "log"
+    "time"
+    "errors"
+    "strconv"
+    "context"
+    "database/sql"
+)
+
+
+// Pool limits and startup wait, zero values keep database/sql defaults
+//  (unlimited open, 2 idle, no lifetime limits)
+type PoolConfig struct {
+    MaxOpenConns        int
+    MaxIdleConns        int
+    ConnMaxLifetime     time.Duration
+    ConnMaxIdleTime     time.Duration
+    StartupTimeout      time.Duration   // max time spent waiting for DB, 0 = single ping
+    StartupBackoff      time.Duration   // first retry delay, doubles every attempt
+    StartupMaxBackoff   time.Duration   // cap of retry delay
+}
+
+
+func defaultPoolConfigFn() PoolConfig {
+    return PoolConfig{
+        MaxIdleConns:       2,
+        StartupTimeout:     30 * time.Second,
+        StartupBackoff:     250 * time.Millisecond,
+        StartupMaxBackoff:  5 * time.Second,
+    }
+}
+
+
+// Reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME,
+//  DB_CONN_MAX_IDLE_TIME, DB_STARTUP_TIMEOUT, DB_STARTUP_BACKOFF, DB_STARTUP_MAX_BACKOFF
+//  all optional, every problem is reported
+func loadPoolConfigFromEnvFn() (PoolConfig, error) {
+    const fn = "loadPoolConfigFromEnvFn"
+    cfg := defaultPoolConfigFn()
+    problems := []error{}
+
+    ints := []struct {
+        key     string
+        target  *int
+    }{
+        {"DB_MAX_OPEN_CONNS",   &cfg.MaxOpenConns},
+        {"DB_MAX_IDLE_CONNS",   &cfg.MaxIdleConns},
+    }
+    for _, i := range ints {
+        val := os.Getenv(i.key)
+        if val == "" {
+            continue // Keep default
+        }
+        parsed, err := strconv.Atoi(val)
+        if err != nil || parsed < 0 {
+            problems = append(problems, fmt.Errorf("%s: must be non-negative number, got %q", i.key, val))
+            continue
+        }
+        *i.target = parsed
+    }
+
+    durations := []struct {
+        key     string
+        target  *time.Duration
+    }{
+        {"DB_CONN_MAX_LIFETIME",    &cfg.ConnMaxLifetime},
+        {"DB_CONN_MAX_IDLE_TIME",   &cfg.ConnMaxIdleTime},
+        {"DB_STARTUP_TIMEOUT",      &cfg.StartupTimeout},
+        {"DB_STARTUP_BACKOFF",      &cfg.StartupBackoff},
+        {"DB_STARTUP_MAX_BACKOFF",  &cfg.StartupMaxBackoff},
+    }
+    for _, d := range durations {
+        val := os.Getenv(d.key)
+        if val == "" {
+            continue // Keep default
+        }
+        parsed, err := time.ParseDuration(val)
+        if err != nil || parsed < 0 {
+            problems = append(problems, fmt.Errorf("%s: must be non-negative duration ex.: 30s, got %q", d.key, val))
+            continue
+        }
+        *d.target = parsed
+    }
+
+    if len(problems) > 0 {
+        return PoolConfig{}, fmt.Errorf("%s: %w", fn, errors.Join(problems...))
+    }
+    return cfg, nil
+}
+
+
+func applyPoolConfigFn(db *sql.DB, cfg PoolConfig) {
+    db.SetMaxOpenConns(cfg.MaxOpenConns)
+    db.SetMaxIdleConns(cfg.MaxIdleConns)
+    db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+    db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+
+// Next retry delay, doubled and capped
+func nextBackoffFn(current time.Duration, max time.Duration) time.Duration {
+    next := current * 2
+    if next > max || next <= 0 {
+        return max
+    }
+    return next
+}
+
+
+type pinger interface {
+    PingContext(ctx context.Context) error
+}
+
+
+// Pings until success, StartupTimeout runs out or ctx is done (ex.: SIGTERM while waiting)
+func waitForDBFn(ctx context.Context, db pinger, cfg PoolConfig) error {
+    fn := "waitForDBFn"
+    deadline := time.Now().Add(cfg.StartupTimeout)
+    // Every ping shares same deadline so hung ping can't outlive StartupTimeout,
+    //  0 = single ping bounded by ctx only
+    pingCtx := ctx
+    if cfg.StartupTimeout > 0 {
+        var cancel context.CancelFunc
+        pingCtx, cancel = context.WithDeadline(ctx, deadline)
+        defer cancel()
+    }
+    backoff := cfg.StartupBackoff
+    for attempt := 1; ; attempt++ {
+        err := db.PingContext(pingCtx)
+        if err == nil {
+            return nil
+        }
+        // Give up, out of time
+        if time.Now().Add(backoff).After(deadline) {
+            return fmt.Errorf("%s: DB not ready after %d attempt(s): %w", fn, attempt, err)
+        }
+        log.Printf("%s: DB not ready (attempt %d), retrying in %s: %v", fn, attempt, backoff, err)
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("%s: %w", fn, ctx.Err())
+        case <-time.After(backoff):
+        }
+        backoff = nextBackoffFn(backoff, cfg.StartupMaxBackoff)
+    }
+}
+
+
+//{{{ Stats
+// Snapshot of sql.DBStats, WaitCount/WaitDuration growing means pool is saturated
+type PoolStats struct {
+    MaxOpenConnections  int     `json:"max_open_connections"`
+    OpenConnections     int     `json:"open_connections"`
+    InUse               int     `json:"in_use"`
+    Idle                int     `json:"idle"`
+    WaitCount           int64   `json:"wait_count"`
+    WaitDurationMs      int64   `json:"wait_duration_ms"`
+    MaxIdleClosed       int64   `json:"max_idle_closed"`
+    MaxIdleTimeClosed   int64   `json:"max_idle_time_closed"`
+    MaxLifetimeClosed   int64   `json:"max_lifetime_closed"`
+}
+
+
+func PoolStatsFn(db *sql.DB) PoolStats {
+    stats := db.Stats()
+    return PoolStats{
+        MaxOpenConnections: stats.MaxOpenConnections,
+        OpenConnections:    stats.OpenConnections,
+        InUse:              stats.InUse,
+        Idle:               stats.Idle,
+        WaitCount:          stats.WaitCount,
+        WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+        MaxIdleClosed:      stats.MaxIdleClosed,
+        MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+        MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+    }
+}
+//}}} Stats
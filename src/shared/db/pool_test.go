@@ -0,0 +1,200 @@
+package shareddb
+import (
+    "os"
+    "testing"
+    "strings"
+    "errors"
+    "context"
+    "time"
+)
+
+
+//{{{ loadPoolConfigFromEnvFn
+func Test_LoadPoolConfigFromEnvFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        env                 map[string]string
+        expected            PoolConfig
+        expectedErrSubStrs  []string
+    }{
+        {
+            name:               "Defaults",
+            env:                map[string]string{},
+            expected:           defaultPoolConfigFn(),
+        }, {
+            name:               "Override",
+            env:                map[string]string{
+                "DB_MAX_OPEN_CONNS":        "20",
+                "DB_MAX_IDLE_CONNS":        "10",
+                "DB_CONN_MAX_LIFETIME":     "30m",
+                "DB_CONN_MAX_IDLE_TIME":    "5m",
+                "DB_STARTUP_TIMEOUT":       "1m",
+                "DB_STARTUP_BACKOFF":       "1s",
+                "DB_STARTUP_MAX_BACKOFF":   "10s",
+            },
+            expected:           PoolConfig{
+                MaxOpenConns:       20,
+                MaxIdleConns:       10,
+                ConnMaxLifetime:    30 * time.Minute,
+                ConnMaxIdleTime:    5 * time.Minute,
+                StartupTimeout:     time.Minute,
+                StartupBackoff:     time.Second,
+                StartupMaxBackoff:  10 * time.Second,
+            },
+        }, {
+            name:               "EveryProblemListed",
+            env:                map[string]string{
+                "DB_MAX_OPEN_CONNS":        "many",
+                "DB_MAX_IDLE_CONNS":        "-1",
+                "DB_STARTUP_TIMEOUT":       "forever",
+            },
+            expected:           PoolConfig{},
+            expectedErrSubStrs: []string{
+                "DB_MAX_OPEN_CONNS: must be non-negative number",
+                "DB_MAX_IDLE_CONNS: must be non-negative number",
+                "DB_STARTUP_TIMEOUT: must be non-negative duration",
+            },
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            os.Clearenv()
+            for k, v := range tc.env {
+                os.Setenv(k, v)
+            }
+            cfg, err := loadPoolConfigFromEnvFn()
+            if len(tc.expectedErrSubStrs) == 0 && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            for _, sub := range tc.expectedErrSubStrs {
+                if err == nil || !strings.Contains(err.Error(), sub) {
+                    t.Errorf("Missing problem:\nExpected:\t%q\nGot:\t\t%v", sub, err)
+                }
+            }
+            if cfg != tc.expected {
+                t.Errorf("Wrong config:\nExpected:\t%+v\nGot:\t\t%+v", tc.expected, cfg)
+            }
+        })
+    }
+}
+//}}} loadPoolConfigFromEnvFn
+
+
+//{{{ nextBackoffFn
+func Test_NextBackoffFn(t *testing.T) {
+    max := time.Second
+    current := 100 * time.Millisecond
+    expected := []time.Duration{200, 400, 800, 1000, 1000}
+    for i, exp := range expected {
+        current = nextBackoffFn(current, max)
+        if current != exp * time.Millisecond {
+            t.Errorf("Step %d:\nExpected:\t%s\nGot:\t\t%s", i, exp * time.Millisecond, current)
+        }
+    }
+}
+//}}} nextBackoffFn
+
+
+//{{{ waitForDBFn
+// Fails first `failures` pings
+type mockPinger struct {
+    failures    int
+    calls       int
+}
+func (m *mockPinger) PingContext(ctx context.Context) error {
+    m.calls++
+    if m.calls <= m.failures {
+        return errors.New("connection refused")
+    }
+    return nil
+}
+
+
+func Test_WaitForDBFn(t *testing.T) {
+    cfg := PoolConfig{
+        StartupTimeout:     200 * time.Millisecond,
+        StartupBackoff:     time.Millisecond,
+        StartupMaxBackoff:  4 * time.Millisecond,
+    }
+    tests := []struct {
+        name                string
+        pinger              *mockPinger
+        cfg                 PoolConfig
+        expectedCalls       int
+        expectedErrSubStr   string
+    }{
+        {
+            name:               "ReadyImmediately",
+            pinger:             &mockPinger{failures: 0},
+            cfg:                cfg,
+            expectedCalls:      1,
+        }, {
+            name:               "ReadyAfterRetries",
+            pinger:             &mockPinger{failures: 3},
+            cfg:                cfg,
+            expectedCalls:      4,
+        }, {
+            name:               "NoRetryWithoutTimeout",
+            pinger:             &mockPinger{failures: 1},
+            cfg:                PoolConfig{StartupBackoff: time.Millisecond},
+            expectedCalls:      1,
+            expectedErrSubStr:  "DB not ready after 1 attempt(s)",
+        }, {
+            name:               "GiveUp",
+            pinger:             &mockPinger{failures: 1000},
+            cfg:                cfg,
+            expectedCalls:      -1,
+            expectedErrSubStr:  "connection refused",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := waitForDBFn(context.Background(), tc.pinger, tc.cfg)
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Fatal, expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrSubStr)) {
+                t.Fatalf("Wrong error:\nExpected:\t%q\nGot:\t\t%v", tc.expectedErrSubStr, err)
+            }
+            if tc.expectedCalls > 0 && tc.pinger.calls != tc.expectedCalls {
+                t.Errorf("Wrong ping count:\nExpected:\t%d\nGot:\t\t%d", tc.expectedCalls, tc.pinger.calls)
+            }
+        })
+    }
+}
+
+
+func Test_WaitForDBFn_Canceled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    cfg := PoolConfig{StartupTimeout: time.Minute, StartupBackoff: time.Second, StartupMaxBackoff: time.Second}
+    err := waitForDBFn(ctx, &mockPinger{failures: 1000}, cfg)
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", context.Canceled, err)
+    }
+}
+
+
+// Blocks until ctx is done, ex.: DB accepted TCP but never answers
+type hangingPinger struct{}
+func (hangingPinger) PingContext(ctx context.Context) error {
+    <-ctx.Done()
+    return ctx.Err()
+}
+
+
+func Test_WaitForDBFn_HungPing(t *testing.T) {
+    cfg := PoolConfig{StartupTimeout: 50 * time.Millisecond, StartupBackoff: time.Millisecond, StartupMaxBackoff: time.Millisecond}
+    start := time.Now()
+    // Caller ctx has no deadline, StartupTimeout alone must bound wait
+    err := waitForDBFn(context.Background(), hangingPinger{}, cfg)
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", context.DeadlineExceeded, err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("Waited past StartupTimeout: %s", elapsed)
+    }
+}
+//}}} waitForDBFn
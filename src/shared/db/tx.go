@@ -0,0 +1,141 @@
+package shareddb
+import (
+    "fmt"
+    "log"
+    "time"
+    "errors"
+    "context"
+    "math/rand"
+    "database/sql"
+)
+import (
+    "github.com/lib/pq"
+)
+
+
+// Satisfied by *sql.DB and *sql.Tx, lets same query fn run inside or outside transaction
+type Querier interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+
+var (
+    _ Querier = (*sql.DB)(nil)
+    _ Querier = (*sql.Tx)(nil)
+)
+
+
+// Isolation + retry policy of WithTx, zero values use defaultTxOptionsFn
+type TxOptions struct {
+    Isolation   sql.IsolationLevel  // 0 = DB default (read committed)
+    ReadOnly    bool
+    MaxAttempts int                 // total attempts, first one included
+    Backoff     time.Duration       // first retry delay, doubles every attempt
+    MaxBackoff  time.Duration       // cap of retry delay
+}
+
+
+func defaultTxOptionsFn() TxOptions {
+    return TxOptions{
+        MaxAttempts:    3,
+        Backoff:        10 * time.Millisecond,
+        MaxBackoff:     200 * time.Millisecond,
+    }
+}
+
+
+// Fill zero values with defaults, nil = all defaults
+func resolveTxOptionsFn(opts *TxOptions) TxOptions {
+    resolved := defaultTxOptionsFn()
+    if opts == nil {
+        return resolved
+    }
+    resolved.Isolation, resolved.ReadOnly = opts.Isolation, opts.ReadOnly
+    if opts.MaxAttempts > 0 {
+        resolved.MaxAttempts = opts.MaxAttempts
+    }
+    if opts.Backoff > 0 {
+        resolved.Backoff = opts.Backoff
+    }
+    if opts.MaxBackoff > 0 {
+        resolved.MaxBackoff = opts.MaxBackoff
+    }
+    return resolved
+}
+
+
+// Serialization failure (40001) or deadlock (40P01), raw pq error or already mapped
+func IsRetryableTxErrFn(err error) bool {
+    if errors.Is(err, ErrSerialization) {
+        return true
+    }
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        return pqErr.Code == "40001" || pqErr.Code == "40P01"
+    }
+    return false
+}
+
+
+//{{{ WithTx
+// Runs fn in transaction, commits when fn returns nil, rolls back otherwise (also on panic).
+//  Whole transaction is retried on serialization failure/deadlock, so fn must be
+//  safe to run again (no side effects outside tx). Error of last attempt is returned
+func WithTx(ctx context.Context, db *sql.DB, opts *TxOptions, fn func(tx *sql.Tx) error) error {
+    resolved := resolveTxOptionsFn(opts)
+    return retryTxFn(ctx, resolved, func() error {
+        return runTxFn(ctx, db, resolved, fn)
+    })
+}
+
+
+// Single attempt, begin/commit errors are mapped, fn error is returned as is
+func runTxFn(ctx context.Context, db *sql.DB, opts TxOptions, txFn func(tx *sql.Tx) error) error {
+    fn := "runTxFn"
+    tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+    if err != nil {
+        return fmt.Errorf("%s: begin: %w", fn, HandlePgErrorFn("transaction", err))
+    }
+    defer func() {
+        if p := recover(); p != nil {
+            tx.Rollback()
+            panic(p)
+        }
+    }()
+    if err = txFn(tx); err != nil {
+        tx.Rollback()
+        return err
+    }
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("%s: commit: %w", fn, HandlePgErrorFn("transaction", err))
+    }
+    return nil
+}
+
+
+// Calls attempt until it succeeds, fails with non retryable error, attempts run out or ctx is done
+func retryTxFn(ctx context.Context, opts TxOptions, attempt func() error) error {
+    fn := "retryTxFn"
+    backoff := opts.Backoff
+    for i := 1; ; i++ {
+        err := attempt()
+        if err == nil || !IsRetryableTxErrFn(err) {
+            return err
+        }
+        if i >= opts.MaxAttempts {
+            return fmt.Errorf("%s: gave up after %d attempt(s): %w", fn, i, err)
+        }
+        // Jitter in [backoff/2, backoff] so conflicting clients don't retry in lockstep
+        delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+        log.Printf("%s: transaction conflict (attempt %d), retrying in %s: %v", fn, i, delay, err)
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("%s: %w", fn, HandlePgErrorFn("transaction", ctx.Err()))
+        case <-time.After(delay):
+        }
+        backoff = nextBackoffFn(backoff, opts.MaxBackoff)
+    }
+}
+//}}} WithTx
@@ -0,0 +1,142 @@
+package shareddb
+import (
+    "testing"
+    "errors"
+    "context"
+    "fmt"
+    "time"
+    "database/sql"
+)
+import (
+    "github.com/lib/pq"
+)
+
+
+//{{{ resolveTxOptionsFn
+func Test_ResolveTxOptionsFn(t *testing.T) {
+    tests := []struct {
+        name        string
+        input       *TxOptions
+        expected    TxOptions
+    }{
+        {
+            name:       "Nil",
+            input:      nil,
+            expected:   defaultTxOptionsFn(),
+        }, {
+            name:       "Partial",
+            input:      &TxOptions{Isolation: sql.LevelSerializable, MaxAttempts: 5},
+            expected:   TxOptions{
+                Isolation:      sql.LevelSerializable,
+                MaxAttempts:    5,
+                Backoff:        10 * time.Millisecond,
+                MaxBackoff:     200 * time.Millisecond,
+            },
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if got := resolveTxOptionsFn(tc.input); got != tc.expected {
+                t.Errorf("Wrong options:\nExpected:\t%+v\nGot:\t\t%+v", tc.expected, got)
+            }
+        })
+    }
+}
+//}}} resolveTxOptionsFn
+
+
+//{{{ IsRetryableTxErrFn
+func Test_IsRetryableTxErrFn(t *testing.T) {
+    tests := []struct {
+        name        string
+        input       error
+        expected    bool
+    }{
+        {"RawSerialization",    &pq.Error{Code: "40001"},                                   true},
+        {"RawDeadlock",         fmt.Errorf("wrapped: %w", &pq.Error{Code: "40P01"}),       true},
+        {"Mapped",              HandlePgErrorFn("user", &pq.Error{Code: "40001"}),          true},
+        {"Conflict",            HandlePgErrorFn("user", &pq.Error{Code: "23505"}),          false},
+        {"Timeout",             &DBError{Kind: ErrTimeout},                                 false},
+        {"Nil",                 nil,                                                        false},
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if got := IsRetryableTxErrFn(tc.input); got != tc.expected {
+                t.Errorf("\nExpected:\t%t\nGot:\t\t%t", tc.expected, got)
+            }
+        })
+    }
+}
+//}}} IsRetryableTxErrFn
+
+
+//{{{ retryTxFn
+func Test_RetryTxFn(t *testing.T) {
+    opts := TxOptions{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+    conflict := &pq.Error{Code: "40001"}
+    tests := []struct {
+        name            string
+        errs            []error     // returned by consecutive attempts, nil after list ends
+        expectedCalls   int
+        expectedErr     error
+    }{
+        {
+            name:           "FirstAttempt",
+            errs:           nil,
+            expectedCalls:  1,
+            expectedErr:    nil,
+        }, {
+            name:           "RetriedConflict",
+            errs:           []error{conflict, HandlePgErrorFn("user", conflict)},
+            expectedCalls:  3,
+            expectedErr:    nil,
+        }, {
+            name:           "NotRetryable",
+            errs:           []error{&DBError{Kind: ErrConflict}},
+            expectedCalls:  1,
+            expectedErr:    ErrConflict,
+        }, {
+            name:           "GiveUp",
+            errs:           []error{conflict, conflict, conflict, conflict},
+            expectedCalls:  3,
+            expectedErr:    conflict,
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            calls := 0
+            err := retryTxFn(context.Background(), opts, func() error {
+                calls++
+                if calls <= len(tc.errs) {
+                    return tc.errs[calls-1]
+                }
+                return nil
+            })
+            if !errors.Is(err, tc.expectedErr) || (tc.expectedErr == nil && err != nil) {
+                t.Errorf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            if calls != tc.expectedCalls {
+                t.Errorf("Wrong attempt count:\nExpected:\t%d\nGot:\t\t%d", tc.expectedCalls, calls)
+            }
+        })
+    }
+}
+
+
+func Test_RetryTxFn_Canceled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    opts := TxOptions{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Second}
+    calls := 0
+    err := retryTxFn(ctx, opts, func() error {
+        calls++
+        return &pq.Error{Code: "40P01"}
+    })
+    if !errors.Is(err, ErrUnavailable) || calls != 1 {
+        t.Errorf("Wrong result:\nExpected:\t%v after 1 attempt\nGot:\t\t%v after %d", ErrUnavailable, err, calls)
+    }
+}
+//}}} retryTxFn
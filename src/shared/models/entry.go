@@ -0,0 +1,84 @@
+package sharedmodels
+import (
+    "time"
+)
+
+
+// Entry size cap and AEAD parameter sizes in hex chars
+const (
+    EntryCiphertextMaxLen   = 65536 // 32 KiB of ciphertext
+    EntryNonceLen           = 24    // 12 byte nonce (AES-GCM/ChaCha20-Poly1305)
+    EntryTagLen             = 32    // 16 byte auth tag
+)
+
+
+// Client encrypted diary entry, service never sees plaintext. ID/CreatedAt/UpdatedAt are set by DB
+type Entry struct {
+    ID          int64       `json:"id"`
+    Owner       string      `json:"owner"`
+    Ciphertext  string      `json:"ciphertext"`
+    Nonce       string      `json:"nonce"`
+    Tag         string      `json:"tag"`
+    CreatedAt   time.Time   `json:"created_at"`
+    UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+
+// Validates only shape of opaque crypto fields, content is never inspected
+func (entry *Entry) Validate() error {
+    if err := IsValidUsernameFn(entry.Owner); err != nil {
+        return RenameFieldErrFn(err, "owner")
+    }
+    if err := IsValidHexStringRangeFn(entry.Ciphertext, "ciphertext", 2, EntryCiphertextMaxLen); err != nil {
+        return err
+    }
+    if err := IsValidHexStringFn(entry.Nonce, "nonce", EntryNonceLen); err != nil {
+        return err
+    }
+    if err := IsValidHexStringFn(entry.Tag, "tag", EntryTagLen); err != nil {
+        return err
+    }
+    return nil
+}
+
+
+// Validates update map, re-encryption changes ciphertext, nonce and tag so all
+//  three must be present together (reusing nonce with new ciphertext breaks AEAD)
+func ValidateEntryMap(input map[string]interface{}) error {
+    // Define validators
+    validators := map[string]func(string) error {
+        "ciphertext": func(val string) error {
+            return IsValidHexStringRangeFn(val, "ciphertext", 2, EntryCiphertextMaxLen)
+        },
+        "nonce": func(val string) error {
+            return IsValidHexStringFn(val, "nonce", EntryNonceLen)
+        },
+        "tag": func(val string) error {
+            return IsValidHexStringFn(val, "tag", EntryTagLen)
+        },
+    }
+
+    // Iterate in fixed order so error is deterministic
+    for _, field := range []string{"ciphertext", "nonce", "tag"} {
+        rawInputField, ok := input[field]
+        if !ok {
+            return newFieldErrFn(field, "required", "ciphertext+nonce+tag", "%s: required, ciphertext, nonce and tag are updated together", field)
+        }
+        // String check
+        strVal, ok := rawInputField.(string)
+        if !ok {
+            return newFieldErrFn(field, "type", "string", "field %q must be string", field)
+        }
+        // Validate
+        if err := validators[field](strVal); err != nil {
+            return err
+        }
+    }
+    // Nothing else can be updated
+    for field := range input {
+        if _, ok := validators[field]; !ok {
+            return newFieldErrFn(field, "read_only", "read only", "field %q can't be updated", field)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,96 @@
+package sharedmodels
+import (
+    "testing"
+    "strings"
+)
+
+
+const (
+    testNonce = "00112233445566778899aabb"
+    testTag =   "00112233445566778899aabbccddeeff"
+)
+
+
+//{{{ Test Entry Validate
+func Test_EntryModel_Validate(t *testing.T) {
+    valid := func() Entry {
+        return Entry{Owner: "valid_test_user1", Ciphertext: "abcd", Nonce: testNonce, Tag: testTag}
+    }
+    tests := []struct {
+        name        string
+        mutate      func(e *Entry)
+        expected    string
+    }{
+        {
+            name:       "Valid",
+            mutate:     func(e *Entry) {},
+            expected:   "",
+        }, {
+            name:       "CiphertextTooLarge",
+            mutate:     func(e *Entry) { e.Ciphertext = strings.Repeat("ab", EntryCiphertextMaxLen/2+1) },
+            expected:   "ciphertext: length must be between 2 and 65536 char long",
+        }, {
+            name:       "CiphertextEmpty",
+            mutate:     func(e *Entry) { e.Ciphertext = "" },
+            expected:   "ciphertext: length must be between 2 and 65536 char long",
+        }, {
+            name:       "NonceWrongLength",
+            mutate:     func(e *Entry) { e.Nonce = "0011" },
+            expected:   "nonce: length must be exactly 24 char long",
+        }, {
+            name:       "TagInvalidChars",
+            mutate:     func(e *Entry) { e.Tag = strings.Repeat("g", EntryTagLen) },
+            expected:   "tag: contains invalid characters",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            entry := valid()
+            tc.mutate(&entry)
+            err := entry.Validate()
+            if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expected, err)
+            }
+        })
+    }
+}
+//}}} Test Entry Validate
+
+
+//{{{ Test ValidateEntryMap
+func Test_ValidateEntryMap(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               map[string]interface{}
+        expectedErr         string
+    }{
+        {
+            name:               "Valid",
+            input:              map[string]interface{}{"ciphertext": "abcd", "nonce": testNonce, "tag": testTag},
+            expectedErr:        "",
+        }, {
+            name:               "OnlyCiphertext",
+            input:              map[string]interface{}{"ciphertext": "abcd"},
+            expectedErr:        "nonce: required, ciphertext, nonce and tag are updated together",
+        }, {
+            name:               "TagNotString",
+            input:              map[string]interface{}{"ciphertext": "abcd", "nonce": testNonce, "tag": 1},
+            expectedErr:        "field \"tag\" must be string",
+        }, {
+            name:               "OwnerNotUpdatable",
+            input:              map[string]interface{}{"ciphertext": "abcd", "nonce": testNonce, "tag": testTag, "owner": "x"},
+            expectedErr:        "field \"owner\" can't be updated",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := ValidateEntryMap(tc.input)
+            if (err == nil && tc.expectedErr != "") || (err != nil && err.Error() != tc.expectedErr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Test ValidateEntryMap
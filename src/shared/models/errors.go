@@ -0,0 +1,102 @@
+package sharedmodels
+import (
+    "fmt"
+    "sort"
+    "errors"
+    "strings"
+)
+
+
+// Invalid field of model, Code is machine readable kind (ex.: hex_length) and Constraint
+//  the rule that failed (ex.: 64 chars), so API layer can report it without parsing
+//  Message. Message reads same as plain error did
+type FieldError struct {
+    Field       string
+    Code        string
+    Constraint  string
+    Message     string
+}
+
+
+func (e *FieldError) Error() string {
+    return e.Message
+}
+
+
+func newFieldErrFn(field string, code string, constraint string, format string, args ...interface{}) error {
+    return &FieldError{Field: field, Code: code, Constraint: constraint, Message: fmt.Sprintf(format, args...)}
+}
+
+
+// Same error reported under other field, ex.: event owner is validated as username.
+//  Exported for key fields validated outside models (entry owner of read/update/delete)
+func RenameFieldErrFn(err error, field string) error {
+    var fieldErr *FieldError
+    if !errors.As(err, &fieldErr) {
+        return fmt.Errorf("%s: %w", field, err)
+    }
+    return &FieldError{
+        Field:      field,
+        Code:       fieldErr.Code,
+        Constraint: fieldErr.Constraint,
+        Message:    fmt.Sprintf("%s: %s", field, fieldErr.Message),
+    }
+}
+
+
+//{{{ ValidationErrors
+// Every invalid field of model in field order, errors.As(err, &fieldErr) finds first one
+type ValidationErrors []*FieldError
+
+
+// Messages joined with "; ", single error reads same as FieldError alone
+func (errs ValidationErrors) Error() string {
+    msgs := make([]string, len(errs))
+    for i, fieldErr := range errs {
+        msgs[i] = fieldErr.Message
+    }
+    return strings.Join(msgs, "; ")
+}
+
+
+func (errs ValidationErrors) Unwrap() []error {
+    out := make([]error, len(errs))
+    for i, fieldErr := range errs {
+        out[i] = fieldErr
+    }
+    return out
+}
+
+
+// Adds err of validator, nil is skipped, non FieldError is kept under field
+func (errs *ValidationErrors) add(field string, err error) {
+    if err == nil {
+        return
+    }
+    var fieldErr *FieldError
+    if !errors.As(err, &fieldErr) {
+        fieldErr = &FieldError{Field: field, Code: "invalid", Message: err.Error()}
+    }
+    *errs = append(*errs, fieldErr)
+}
+
+
+// nil when nothing failed, so result can be returned as error
+func (errs ValidationErrors) orNil() error {
+    if len(errs) == 0 {
+        return nil
+    }
+    return errs
+}
+//}}} ValidationErrors
+
+
+// Keys of input map sorted, map order is random so errors follow this instead
+func sortedKeysFn(input map[string]interface{}) []string {
+    keys := make([]string, 0, len(input))
+    for key := range input {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,161 @@
+package sharedmodels
+import (
+    "errors"
+    "testing"
+    "strings"
+    "time"
+    "reflect"
+)
+
+
+//{{{ Test FieldError
+// Validators return FieldError with field and code, message stays as before
+func Test_FieldError(t *testing.T) {
+    event := Event{Owner: "x", EncPayload: "ab", StartsAt: time.Now(), EndsAt: time.Now()}
+    tests := []struct {
+        name            string
+        err             error
+        expectedField   string
+        expectedCode    string
+        expectedMsg     string
+    }{
+        {
+            name:           "UsernameLength",
+            err:            IsValidUsernameFn("ab"),
+            expectedField:  "username",
+            expectedCode:   "username_length",
+            expectedMsg:    "username: length must be between 3 and 30 char long",
+        }, {
+            name:           "HexLength",
+            err:            IsValidHexStringFn("ab", "salt", 64),
+            expectedField:  "salt",
+            expectedCode:   "hex_length",
+            expectedMsg:    "salt: length must be exactly 64 char long",
+        }, {
+            name:           "HexOddLength",
+            err:            IsValidHexStringRangeFn("abc", "enc_payload", 2, 8),
+            expectedField:  "enc_payload",
+            expectedCode:   "hex_odd_length",
+            expectedMsg:    "enc_payload: length must be even",
+        }, {
+            name:           "MapType",
+            err:            ValidateUserMap(map[string]interface{}{"salt": 5}),
+            expectedField:  "salt",
+            expectedCode:   "type",
+            expectedMsg:    `field "salt" must be string`,
+        }, {
+            name:           "RenamedOwner",
+            err:            event.Validate(),
+            expectedField:  "owner",
+            expectedCode:   "username_length",
+            expectedMsg:    "owner: username: length must be between 3 and 30 char long",
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            var fieldErr *FieldError
+            if !errors.As(tc.err, &fieldErr) {
+                t.Fatalf("Wrong error type:\nExpected:\t*FieldError\nGot:\t\t%T (%v)", tc.err, tc.err)
+            }
+            if fieldErr.Field != tc.expectedField || fieldErr.Code != tc.expectedCode || tc.err.Error() != tc.expectedMsg {
+                t.Errorf("Wrong error:\nExpected:\t%s %s %q\nGot:\t\t%s %s %q",
+                    tc.expectedField, tc.expectedCode, tc.expectedMsg, fieldErr.Field, fieldErr.Code, tc.err.Error())
+            }
+        })
+    }
+
+    // Error that isn't FieldError keeps plain prefix
+    err := RenameFieldErrFn(errors.New("boom"), "owner")
+    if !strings.HasPrefix(err.Error(), "owner: boom") {
+        t.Errorf("Wrong error:\nExpected:\t%q\nGot:\t\t%q", "owner: boom", err.Error())
+    }
+}
+//}}} Test FieldError
+
+
+//{{{ Test ValidationErrors
+// Every invalid field is reported, always in UserFields order
+func Test_ValidationErrors(t *testing.T) {
+    type fieldResult struct {
+        Field       string
+        Code        string
+        Constraint  string
+    }
+    tests := []struct {
+        name            string
+        validateFn      func() error
+        expected        []fieldResult
+        expectedMsg     string
+    }{
+        {
+            name:           "UserAllFields",
+            validateFn:     func() error {
+                user := User{Username: "x", Salt: "zz", Hash: strings.Repeat("g", 64), EncSymkey: ""}
+                return user.Validate()
+            },
+            expected:       []fieldResult{
+                {"username", "username_length", "3..30 chars"},
+                {"salt", "hex_length", "64 chars"},
+                {"hash", "hex_chars", "^[0-9a-fA-F]+$"},
+                {"enc_symkey", "hex_length", "120 chars"},
+            },
+            expectedMsg:    "username: length must be between 3 and 30 char long; salt: length must be exactly 64 char long; " +
+                "hash: contains invalid characters; enc_symkey: length must be exactly 120 char long",
+        }, {
+            name:           "MapOrder",
+            validateFn:     func() error {
+                return ValidateUserMap(map[string]interface{}{"enc_symkey": 5, "hash": "ab", "salt": "cd", "username": "test_user"})
+            },
+            expected:       []fieldResult{
+                {"salt", "hex_length", "64 chars"},
+                {"hash", "hex_length", "64 chars"},
+                {"enc_symkey", "type", "string"},
+            },
+            expectedMsg:    `salt: length must be exactly 64 char long; hash: length must be exactly 64 char long; field "enc_symkey" must be string`,
+        }, {
+            name:           "CredentialsSingle",
+            validateFn:     func() error {
+                creds := UserCredentials{Salt: strings.Repeat("a", 64), Hash: strings.Repeat("b", 64), EncSymkey: "abc"}
+                return creds.Validate()
+            },
+            expected:       []fieldResult{{"enc_symkey", "hex_length", "120 chars"}},
+            expectedMsg:    "enc_symkey: length must be exactly 120 char long",
+        }, {
+            name:           "Valid",
+            validateFn:     func() error {
+                return ValidateUserMap(map[string]interface{}{"username": "test_user"})
+            },
+        },
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            // Map iteration must not change result
+            for i := 0; i < 10; i++ {
+                err := tc.validateFn()
+                if tc.expected == nil {
+                    if err != nil {
+                        t.Fatalf("Wrong error:\nExpected:\t%v\nGot:\t\t%v", nil, err)
+                    }
+                    return
+                }
+                var errs ValidationErrors
+                if !errors.As(err, &errs) {
+                    t.Fatalf("Wrong error type:\nExpected:\tValidationErrors\nGot:\t\t%T (%v)", err, err)
+                }
+                got := make([]fieldResult, len(errs))
+                for j, fieldErr := range errs {
+                    got[j] = fieldResult{fieldErr.Field, fieldErr.Code, fieldErr.Constraint}
+                }
+                if !reflect.DeepEqual(got, tc.expected) || err.Error() != tc.expectedMsg {
+                    t.Fatalf("Wrong errors (run %d):\nExpected:\t%v %q\nGot:\t\t%v %q", i, tc.expected, tc.expectedMsg, got, err.Error())
+                }
+                // First error is still reachable as FieldError
+                var fieldErr *FieldError
+                if !errors.As(err, &fieldErr) || fieldErr.Field != tc.expected[0].Field {
+                    t.Errorf("Wrong first error:\nExpected:\t%s\nGot:\t\t%v", tc.expected[0].Field, fieldErr)
+                }
+            }
+        })
+    }
+}
+//}}} Test ValidationErrors
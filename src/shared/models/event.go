@@ -0,0 +1,117 @@
+package sharedmodels
+import (
+    "time"
+    "slices"
+)
+
+
+// Max length of client encrypted event payload in hex chars (4 KiB of ciphertext)
+const EventPayloadMaxLen = 8192
+
+
+// Create event struct, ID/CreatedAt/UpdatedAt are set by DB
+type Event struct {
+    ID          int64       `json:"id"`
+    Owner       string      `json:"owner"`
+    EncPayload  string      `json:"enc_payload"`
+    StartsAt    time.Time   `json:"starts_at"`
+    EndsAt      time.Time   `json:"ends_at"`
+    CreatedAt   time.Time   `json:"created_at"`
+    UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+
+// Serial primary key (events, reviews, ...)
+func IsValidIDFn(id int64) error {
+    if id < 1 {
+        return newFieldErrFn("id", "positive_integer", "> 0", "id: must be positive integer")
+    }
+    return nil
+}
+
+
+func IsValidTimeRangeFn(startsAt time.Time, endsAt time.Time) error {
+    if startsAt.IsZero() {
+        return newFieldErrFn("starts_at", "required", "required", "starts_at: required")
+    }
+    if endsAt.IsZero() {
+        return newFieldErrFn("ends_at", "required", "required", "ends_at: required")
+    }
+    if endsAt.Before(startsAt) {
+        return newFieldErrFn("ends_at", "time_order", ">= starts_at", "ends_at: must not be before starts_at")
+    }
+    return nil
+}
+
+
+// Validates client supplied fields, ID and timestamps set by DB are ignored
+func (event *Event) Validate() error {
+    if err := IsValidUsernameFn(event.Owner); err != nil {
+        return RenameFieldErrFn(err, "owner")
+    }
+    if err := IsValidHexStringRangeFn(event.EncPayload, "enc_payload", 2, EventPayloadMaxLen); err != nil {
+        return err
+    }
+    if err := IsValidTimeRangeFn(event.StartsAt, event.EndsAt); err != nil {
+        return err
+    }
+    return nil
+}
+
+
+// Columns of events table update may set, in table order
+var EventUpdateFields = []string{"enc_payload", "starts_at", "ends_at"}
+
+
+// Validates (partial) update map, times must be RFC 3339 strings. Present fields are checked
+//  in EventUpdateFields order then other keys sorted, invalid ones are returned together as
+//  ValidationErrors so same input always gives same error. Returns map with times parsed
+//  to time.Time so it can be passed to DB as is
+func ValidateEventMap(input map[string]interface{}) (map[string]interface{}, error) {
+    out := make(map[string]interface{}, len(input))
+    var errs ValidationErrors
+    for _, field := range EventUpdateFields {
+        rawInputField, ok := input[field]
+        if !ok {
+            continue // Skip
+        }
+        // String check
+        strVal, ok := rawInputField.(string)
+        if !ok {
+            errs.add(field, newFieldErrFn(field, "type", "string", "field %q must be string", field))
+            continue
+        }
+        // Validate
+        switch field {
+        case "enc_payload":
+            if err := IsValidHexStringRangeFn(strVal, field, 2, EventPayloadMaxLen); err != nil {
+                errs.add(field, err)
+                continue
+            }
+            out[field] = strVal
+        case "starts_at", "ends_at":
+            t, err := time.Parse(time.RFC3339, strVal)
+            if err != nil {
+                errs.add(field, newFieldErrFn(field, "time_format", "RFC 3339", "%s: must be RFC 3339 timestamp", field))
+                continue
+            }
+            out[field] = t
+        }
+    }
+    // Both parsed, check order here, otherwise DB CHECK catches it
+    startsAt, okStart := out["starts_at"].(time.Time)
+    endsAt, okEnd := out["ends_at"].(time.Time)
+    if okStart && okEnd && endsAt.Before(startsAt) {
+        errs.add("ends_at", newFieldErrFn("ends_at", "time_order", ">= starts_at", "ends_at: must not be before starts_at"))
+    }
+    // Keys that can't be updated
+    for _, field := range sortedKeysFn(input) {
+        if !slices.Contains(EventUpdateFields, field) {
+            errs.add(field, newFieldErrFn(field, "read_only", "read only", "field %q can't be updated", field))
+        }
+    }
+    if err := errs.orNil(); err != nil {
+        return nil, err
+    }
+    return out, nil
+}
@@ -0,0 +1,134 @@
+package sharedmodels
+import (
+    "testing"
+    "strings"
+    "time"
+)
+
+
+//{{{ Test Event Validate
+func Test_EventModel_Validate(t *testing.T) {
+    startsAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+    valid := func() Event {
+        return Event{
+            Owner:      "valid_test_user1",
+            EncPayload: "0c8fd825308df79b",
+            StartsAt:   startsAt,
+            EndsAt:     startsAt.Add(time.Hour),
+        }
+    }
+    tests := []struct {
+        name        string
+        mutate      func(e *Event)
+        expected    string
+    }{
+        {
+            name:       "Valid",
+            mutate:     func(e *Event) {},
+            expected:   "",
+        }, {
+            name:       "InvalidOwner",
+            mutate:     func(e *Event) { e.Owner = "ab" },
+            expected:   "owner: username: length must be between 3 and 30 char long",
+        }, {
+            name:       "PayloadOddLength",
+            mutate:     func(e *Event) { e.EncPayload = "abc" },
+            expected:   "enc_payload: length must be even",
+        }, {
+            name:       "PayloadTooLong",
+            mutate:     func(e *Event) { e.EncPayload = strings.Repeat("ab", EventPayloadMaxLen/2+1) },
+            expected:   "enc_payload: length must be between 2 and 8192 char long",
+        }, {
+            name:       "PayloadInvalidChars",
+            mutate:     func(e *Event) { e.EncPayload = "zz" },
+            expected:   "enc_payload: contains invalid characters",
+        }, {
+            name:       "MissingStartsAt",
+            mutate:     func(e *Event) { e.StartsAt = time.Time{} },
+            expected:   "starts_at: required",
+        }, {
+            name:       "EndsBeforeStarts",
+            mutate:     func(e *Event) { e.EndsAt = startsAt.Add(-time.Minute) },
+            expected:   "ends_at: must not be before starts_at",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            event := valid()
+            tc.mutate(&event)
+            err := event.Validate()
+            if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expected, err)
+            }
+        })
+    }
+}
+//}}} Test Event Validate
+
+
+//{{{ Test ValidateEventMap
+func Test_ValidateEventMap(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               map[string]interface{}
+        expectedErr         string
+    }{
+        {
+            name:               "Valid",
+            input:              map[string]interface{}{
+                "enc_payload":  "abcd",
+                "starts_at":    "2025-01-01T10:00:00Z",
+                "ends_at":      "2025-01-01T11:00:00Z",
+            },
+            expectedErr:        "",
+        }, {
+            name:               "FieldNotString",
+            input:              map[string]interface{}{"starts_at": 1234},
+            expectedErr:        "field \"starts_at\" must be string",
+        }, {
+            name:               "NotRFC3339",
+            input:              map[string]interface{}{"ends_at": "tomorrow"},
+            expectedErr:        "ends_at: must be RFC 3339 timestamp",
+        }, {
+            name:               "OwnerNotUpdatable",
+            input:              map[string]interface{}{"owner": "other_user"},
+            expectedErr:        "field \"owner\" can't be updated",
+        }, {
+            name:               "EndsBeforeStarts",
+            input:              map[string]interface{}{
+                "starts_at":    "2025-01-01T10:00:00Z",
+                "ends_at":      "2025-01-01T09:00:00Z",
+            },
+            expectedErr:        "ends_at: must not be before starts_at",
+        }, {
+            // Every error in EventUpdateFields order, then read only keys sorted
+            name:               "ManyInvalid",
+            input:              map[string]interface{}{
+                "owner":        "other_user",
+                "ends_at":      "tomorrow",
+                "id":           1,
+                "starts_at":    1234,
+                "enc_payload":  "zz",
+            },
+            expectedErr:        "enc_payload: contains invalid characters; field \"starts_at\" must be string; " +
+                "ends_at: must be RFC 3339 timestamp; field \"id\" can't be updated; field \"owner\" can't be updated",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            out, err := ValidateEventMap(tc.input)
+            if (err == nil && tc.expectedErr != "") || (err != nil && err.Error() != tc.expectedErr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            // Times are parsed
+            if err == nil {
+                if _, ok := out["starts_at"].(time.Time); !ok {
+                    t.Errorf("starts_at not parsed to time.Time: %T", out["starts_at"])
+                }
+            }
+        })
+    }
+}
+//}}} Test ValidateEventMap
@@ -0,0 +1,124 @@
+package sharedmodels
+import (
+    "fmt"
+    "time"
+    "math"
+    "slices"
+    "encoding/json"
+)
+
+
+// Max length of client encrypted review body in hex chars (2 KiB of ciphertext)
+const ReviewBodyMaxLen = 4096
+
+// Rating bounds, same as reviews table CHECK
+const (
+    ReviewRatingMin = 1
+    ReviewRatingMax = 5
+)
+
+
+// Create review struct, one per author per event. ID/CreatedAt/UpdatedAt are set by DB
+type Review struct {
+    ID          int64       `json:"id"`
+    Author      string      `json:"author"`
+    EventID     int64       `json:"event_id"`
+    Rating      int         `json:"rating"`
+    EncBody     string      `json:"enc_body"`
+    CreatedAt   time.Time   `json:"created_at"`
+    UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+
+func IsValidRatingFn(rating int) error {
+    if rating < ReviewRatingMin || rating > ReviewRatingMax {
+        return newFieldErrFn("rating", "range", fmt.Sprintf("%d..%d", ReviewRatingMin, ReviewRatingMax), "rating: must be between %d and %d", ReviewRatingMin, ReviewRatingMax)
+    }
+    return nil
+}
+
+
+// Validates client supplied fields, ID and timestamps set by DB are ignored
+func (review *Review) Validate() error {
+    if err := IsValidUsernameFn(review.Author); err != nil {
+        return RenameFieldErrFn(err, "author")
+    }
+    if review.EventID < 1 {
+        return newFieldErrFn("event_id", "positive_integer", "> 0", "event_id: must be positive integer")
+    }
+    if err := IsValidRatingFn(review.Rating); err != nil {
+        return err
+    }
+    if err := IsValidHexStringRangeFn(review.EncBody, "enc_body", 2, ReviewBodyMaxLen); err != nil {
+        return err
+    }
+    return nil
+}
+
+
+// Columns of reviews table update may set, in table order
+var ReviewUpdateFields = []string{"rating", "enc_body"}
+
+
+// Validates (partial) update map of rating/enc_body. Rating may be float64 or
+//  json.Number (decoder.UseNumber), returned map holds it as int. Present fields are
+//  checked in ReviewUpdateFields order then other keys sorted, invalid ones are
+//  returned together as ValidationErrors so same input always gives same error
+func ValidateReviewMap(input map[string]interface{}) (map[string]interface{}, error) {
+    out := make(map[string]interface{}, len(input))
+    var errs ValidationErrors
+    if rawInputField, ok := input["rating"]; ok {
+        rating, err := reviewRatingFn(rawInputField)
+        if err != nil {
+            errs.add("rating", err)
+        } else {
+            out["rating"] = rating
+        }
+    }
+    if rawInputField, ok := input["enc_body"]; ok {
+        strVal, ok := rawInputField.(string)
+        if !ok {
+            errs.add("enc_body", newFieldErrFn("enc_body", "type", "string", "field %q must be string", "enc_body"))
+        } else if err := IsValidHexStringRangeFn(strVal, "enc_body", 2, ReviewBodyMaxLen); err != nil {
+            errs.add("enc_body", err)
+        } else {
+            out["enc_body"] = strVal
+        }
+    }
+    // Keys that can't be updated
+    for _, field := range sortedKeysFn(input) {
+        if !slices.Contains(ReviewUpdateFields, field) {
+            errs.add(field, newFieldErrFn(field, "read_only", "read only", "field %q can't be updated", field))
+        }
+    }
+    if err := errs.orNil(); err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
+
+// Whole number rating within bounds from float64 or json.Number
+func reviewRatingFn(rawInputField interface{}) (int, error) {
+    field := "rating"
+    var rating float64
+    switch val := rawInputField.(type) {
+    case float64:
+        rating = val
+    case json.Number:
+        parsed, err := val.Float64()
+        if err != nil {
+            return 0, newFieldErrFn(field, "type", "integer", "field %q must be integer", field)
+        }
+        rating = parsed
+    default:
+        return 0, newFieldErrFn(field, "type", "integer", "field %q must be integer", field)
+    }
+    if rating != math.Trunc(rating) {
+        return 0, newFieldErrFn(field, "type", "integer", "field %q must be integer", field)
+    }
+    if err := IsValidRatingFn(int(rating)); err != nil {
+        return 0, err
+    }
+    return int(rating), nil
+}
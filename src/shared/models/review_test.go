@@ -0,0 +1,119 @@
+package sharedmodels
+import (
+    "testing"
+    "encoding/json"
+)
+
+
+//{{{ Test Review Validate
+func Test_ReviewModel_Validate(t *testing.T) {
+    valid := func() Review {
+        return Review{Author: "valid_test_user1", EventID: 1, Rating: 5, EncBody: "abcd"}
+    }
+    tests := []struct {
+        name        string
+        mutate      func(r *Review)
+        expected    string
+    }{
+        {
+            name:       "Valid",
+            mutate:     func(r *Review) {},
+            expected:   "",
+        }, {
+            name:       "InvalidAuthor",
+            mutate:     func(r *Review) { r.Author = "a b c" },
+            expected:   "author: username: contains invalid characters",
+        }, {
+            name:       "InvalidEventID",
+            mutate:     func(r *Review) { r.EventID = 0 },
+            expected:   "event_id: must be positive integer",
+        }, {
+            name:       "RatingTooLow",
+            mutate:     func(r *Review) { r.Rating = 0 },
+            expected:   "rating: must be between 1 and 5",
+        }, {
+            name:       "RatingTooHigh",
+            mutate:     func(r *Review) { r.Rating = 6 },
+            expected:   "rating: must be between 1 and 5",
+        }, {
+            name:       "BodyOddLength",
+            mutate:     func(r *Review) { r.EncBody = "abc" },
+            expected:   "enc_body: length must be even",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            review := valid()
+            tc.mutate(&review)
+            err := review.Validate()
+            if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expected, err)
+            }
+        })
+    }
+}
+//}}} Test Review Validate
+
+
+//{{{ Test ValidateReviewMap
+func Test_ValidateReviewMap(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               map[string]interface{}
+        expectedErr         string
+    }{
+        {
+            name:               "ValidFloat",
+            input:              map[string]interface{}{"rating": float64(3), "enc_body": "abcd"},
+            expectedErr:        "",
+        }, {
+            name:               "ValidNumber",
+            input:              map[string]interface{}{"rating": json.Number("4")},
+            expectedErr:        "",
+        }, {
+            name:               "RatingNotInteger",
+            input:              map[string]interface{}{"rating": json.Number("4.5")},
+            expectedErr:        "field \"rating\" must be integer",
+        }, {
+            name:               "RatingString",
+            input:              map[string]interface{}{"rating": "5"},
+            expectedErr:        "field \"rating\" must be integer",
+        }, {
+            name:               "RatingOutOfBounds",
+            input:              map[string]interface{}{"rating": float64(10)},
+            expectedErr:        "rating: must be between 1 and 5",
+        }, {
+            name:               "AuthorNotUpdatable",
+            input:              map[string]interface{}{"author": "other_user"},
+            expectedErr:        "field \"author\" can't be updated",
+        }, {
+            // Every error in ReviewUpdateFields order, then read only keys sorted
+            name:               "ManyInvalid",
+            input:              map[string]interface{}{
+                "event_id":     2,
+                "enc_body":     "abc",
+                "author":       "other_user",
+                "rating":       float64(0),
+            },
+            expectedErr:        "rating: must be between 1 and 5; enc_body: length must be even; " +
+                "field \"author\" can't be updated; field \"event_id\" can't be updated",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            out, err := ValidateReviewMap(tc.input)
+            if (err == nil && tc.expectedErr != "") || (err != nil && err.Error() != tc.expectedErr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+            // Rating is int
+            if _, present := tc.input["rating"]; err == nil && present {
+                if _, ok := out["rating"].(int); !ok {
+                    t.Errorf("rating not converted to int: %T", out["rating"])
+                }
+            }
+        })
+    }
+}
+//}}} Test ValidateReviewMap
@@ -1,7 +1,10 @@
 package sharedmodels
 import (
     "fmt"
+    "time"
     "regexp"
+    "slices"
+    "strings"
 )
 
 
@@ -14,20 +17,52 @@ var (
 
 // Create user struct
 type User struct {
-    Username    string `json:"username"`
+    Username                string      `json:"username"`
+    Salt                    string      `json:"salt"`
+    Hash                    string      `json:"hash"`
+    EncSymkey               string      `json:"enc_symkey"`
+    Version                 int64       `json:"-"`  // bumped by DB on every update, travels as ETag
+    CredentialsRotatedAt    *time.Time  `json:"-"`  // last credential rotation, nil = never
+}
+
+
+// Salt, hash and enc_symkey are derived from same password, so they are only replaced together
+type UserCredentials struct {
     Salt        string `json:"salt"`
     Hash        string `json:"hash"`
     EncSymkey   string `json:"enc_symkey"`
 }
 
 
+// Every column of users table in table order
+var UserFields = []string{"username", "salt", "hash", "enc_symkey"}
+
+// Only fields read/projection may return. Hash works as password (see proof check) and
+//  enc_symkey is released only by successful verify, so neither is ever readable
+var ReadableUserFields = []string{"username", "salt"}
+
+// Returned by read when caller doesn't ask for fields
+var DefaultUserFields = []string{"username", "salt"}
+
+// Derived from same password, so update sets all of them or none. Rotation replaces
+//  them together too, checking proof of current password
+var UserCredentialFields = []string{"salt", "hash", "enc_symkey"}
+
+
+// Row of user listing, public columns only
+type UserListItem struct {
+    Username    string      `json:"username"`
+    CreatedAt   time.Time   `json:"created_at"`
+}
+
+
 func IsValidUsernameFn(username string) error {
     // check username, 2 > len > 31, letters + numbers + '_'
     if len(username) < 3 || len(username) > 30 {
-        return fmt.Errorf("username: length must be between 3 and 30 char long")
+        return newFieldErrFn("username", "username_length", "3..30 chars", "username: length must be between 3 and 30 char long")
     }
     if !usernameMatch.MatchString(username) {
-        return fmt.Errorf("username: contains invalid characters")
+        return newFieldErrFn("username", "username_chars", "^[a-zA-Z0-9_]+$", "username: contains invalid characters")
     }
     return nil
 }
@@ -35,33 +70,52 @@ func IsValidUsernameFn(username string) error {
 
 func IsValidHexStringFn(hexStr string, hexStrName string, length int) error {
     if len(hexStr) != length {
-        return fmt.Errorf("%s: length must be exactly %d char long", hexStrName, length)
+        return newFieldErrFn(hexStrName, "hex_length", fmt.Sprintf("%d chars", length), "%s: length must be exactly %d char long", hexStrName, length)
     }
     if !hexStrMatch.MatchString(hexStr) {
-        return fmt.Errorf("%s: contains invalid characters", hexStrName)
+        return newFieldErrFn(hexStrName, "hex_chars", "^[0-9a-fA-F]+$", "%s: contains invalid characters", hexStrName)
     }
     return nil
 }
 
 
-func (user *User) Validate() error {
-    if err := IsValidUsernameFn(user.Username); err != nil {
-        return err
-    }
-    if err := IsValidHexStringFn(user.Salt, "salt", 64); err != nil {
-        return err
+// Variable length hex (encrypted blobs), must be whole bytes aka even length
+func IsValidHexStringRangeFn(hexStr string, hexStrName string, minLen int, maxLen int) error {
+    if len(hexStr) < minLen || len(hexStr) > maxLen {
+        return newFieldErrFn(hexStrName, "hex_length", fmt.Sprintf("%d..%d chars", minLen, maxLen), "%s: length must be between %d and %d char long", hexStrName, minLen, maxLen)
     }
-    if err := IsValidHexStringFn(user.Hash, "hash", 64); err != nil {
-        return err
+    if len(hexStr) % 2 != 0 {
+        return newFieldErrFn(hexStrName, "hex_odd_length", "even length", "%s: length must be even", hexStrName)
     }
-    if err := IsValidHexStringFn(user.EncSymkey, "enc_symkey", 120); err != nil {
-        return err
+    if !hexStrMatch.MatchString(hexStr) {
+        return newFieldErrFn(hexStrName, "hex_chars", "^[0-9a-fA-F]+$", "%s: contains invalid characters", hexStrName)
     }
-
     return nil
 }
 
 
+// Every field is checked, invalid ones are returned together as ValidationErrors
+func (user *User) Validate() error {
+    var errs ValidationErrors
+    errs.add("username", IsValidUsernameFn(user.Username))
+    errs.add("salt", IsValidHexStringFn(user.Salt, "salt", 64))
+    errs.add("hash", IsValidHexStringFn(user.Hash, "hash", 64))
+    errs.add("enc_symkey", IsValidHexStringFn(user.EncSymkey, "enc_symkey", 120))
+    return errs.orNil()
+}
+
+
+func (creds *UserCredentials) Validate() error {
+    var errs ValidationErrors
+    errs.add("salt", IsValidHexStringFn(creds.Salt, "salt", 64))
+    errs.add("hash", IsValidHexStringFn(creds.Hash, "hash", 64))
+    errs.add("enc_symkey", IsValidHexStringFn(creds.EncSymkey, "enc_symkey", 120))
+    return errs.orNil()
+}
+
+
+// Present fields are checked in UserFields order, invalid ones are returned together
+//  as ValidationErrors so same input always gives same error
 func ValidateUserMap(input map[string]interface{}) error {
     // Define validators
     validators := map[string]func(string) error {
@@ -80,7 +134,8 @@ func ValidateUserMap(input map[string]interface{}) error {
     }
 
     // Iterate
-    for field, validateFn := range validators {
+    var errs ValidationErrors
+    for _, field := range UserFields {
         rawInputField, ok := input[field]
         if !ok {
             continue // Skip
@@ -88,15 +143,70 @@ func ValidateUserMap(input map[string]interface{}) error {
         // String check
         strVal, ok := rawInputField.(string)
         if !ok {
-            return fmt.Errorf("field %q must be string", field)
+            errs.add(field, newFieldErrFn(field, "type", "string", "field %q must be string", field))
+            continue
         }
         // Validate
-        if err := validateFn(strVal); err != nil {
-            return err
+        errs.add(field, validators[field](strVal))
+    }
+    return errs.orNil()
+}
+
+
+// Update input with some but not all credential keys, every missing one is required_together.
+//  nil when there is none or all of them
+func RejectPartialCredentialsFn(input map[string]interface{}) error {
+    var missing []string
+    for _, field := range UserCredentialFields {
+        if _, ok := input[field]; !ok {
+            missing = append(missing, field)
         }
     }
-    return nil
+    if len(missing) == 0 || len(missing) == len(UserCredentialFields) {
+        return nil
+    }
+    var errs ValidationErrors
+    constraint := strings.Join(UserCredentialFields, "+")
+    for _, field := range missing {
+        errs.add(field, newFieldErrFn(field, "required_together", constraint, "field %q is required, salt, hash and enc_symkey change together", field))
+    }
+    return errs.orNil()
 }
 
 
+// Check requested projection, every field must be readable and listed once,
+//  secret columns (hash, enc_symkey) are refused
+func ValidateUserFieldsFn(fields []string) error {
+    if len(fields) == 0 {
+        return newFieldErrFn("fields", "min_items", ">= 1 item", "fields: must contain at least 1 field")
+    }
+    seen := make(map[string]struct{}, len(fields))
+    for _, field := range fields {
+        if !slices.Contains(UserFields, field) {
+            return newFieldErrFn("fields", "enum", strings.Join(ReadableUserFields, "|"), "fields: unknown field %q", field)
+        }
+        if !slices.Contains(ReadableUserFields, field) {
+            return newFieldErrFn("fields", "secret", strings.Join(ReadableUserFields, "|"), "fields: field %q can't be read", field)
+        }
+        if _, dup := seen[field]; dup {
+            return newFieldErrFn("fields", "duplicate", "unique", "fields: duplicate field %q", field)
+        }
+        seen[field] = struct{}{}
+    }
+    return nil
+}
+
 
+// Only requested readable fields, fields must be validated via ValidateUserFieldsFn
+func (user *User) Project(fields []string) map[string]string {
+    out := make(map[string]string, len(fields))
+    for _, field := range fields {
+        switch field {
+        case "username":
+            out[field] = user.Username
+        case "salt":
+            out[field] = user.Salt
+        }
+    }
+    return out
+}
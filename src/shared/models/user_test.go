@@ -2,6 +2,7 @@ package sharedmodels
 import (
     "testing"
     "strings"
+    "reflect"
 )
 
 
@@ -186,3 +187,148 @@ func Test_ValidateUserMap(t *testing.T) {
 }
 //}}} Test ValidateUserMap
 
+
+//{{{ Test RejectPartialCredentialsFn
+func Test_RejectPartialCredentialsFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               map[string]interface{}
+        expectedErr         string
+    }{
+        {
+            name:               "NoCredentials",
+            input:              map[string]interface{}{"username": "test_user"},
+            expectedErr:        "",
+        }, {
+            name:               "AllCredentials",
+            input:              map[string]interface{}{"enc_symkey": "aa", "hash": "bb", "salt": "cc"},
+            expectedErr:        "",
+        }, {
+            name:               "HashOnly",
+            input:              map[string]interface{}{"username": "test_user", "hash": "aa"},
+            expectedErr:        "field \"salt\" is required, salt, hash and enc_symkey change together; " +
+                "field \"enc_symkey\" is required, salt, hash and enc_symkey change together",
+        }, {
+            name:               "MissingEncSymkey",
+            input:              map[string]interface{}{"salt": "aa", "hash": "bb"},
+            expectedErr:        "field \"enc_symkey\" is required, salt, hash and enc_symkey change together",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := RejectPartialCredentialsFn(tc.input)
+            if (err == nil && tc.expectedErr != "") || (err != nil && err.Error() != tc.expectedErr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Test RejectPartialCredentialsFn
+
+
+//{{{ Test UserCredentials.Validate
+func Test_UserCredentials_Validate(t *testing.T) {
+    valid := UserCredentials{
+        Salt:       "344feecf40d375380ed5f523b9029647bf7c9f2261e0341a87aa5df6d49c4e31",
+        Hash:       "0c8fd825308df79b313a71b90ee93f7d889207c2277c477b424f83162a5aa4de",
+        EncSymkey:  strings.Repeat("0123456789ab", 10),
+    }
+    missingHash := valid
+    missingHash.Hash = ""
+    badSymkey := valid
+    badSymkey.EncSymkey = strings.Repeat("z", 120)
+    tests := []struct {
+        name                string
+        input               UserCredentials
+        expectedErrSubStr   string
+    }{
+        {
+            name:               "Valid",
+            input:              valid,
+            expectedErrSubStr:  "",
+        }, {
+            // All three are required, partial rotation is not allowed
+            name:               "MissingHash",
+            input:              missingHash,
+            expectedErrSubStr:  "hash: length must be exactly 64 char long",
+        }, {
+            name:               "InvalidEncSymkey",
+            input:              badSymkey,
+            expectedErrSubStr:  "enc_symkey: contains invalid characters",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := tc.input.Validate()
+            if tc.expectedErrSubStr == "" && err != nil {
+                t.Fatalf("Expected no error, got: %v", err)
+            }
+            if tc.expectedErrSubStr != "" && (err == nil || err.Error() != tc.expectedErrSubStr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%q", tc.expectedErrSubStr, err)
+            }
+        })
+    }
+}
+//}}} Test UserCredentials.Validate
+
+
+
+//{{{ Test ValidateUserFieldsFn
+func Test_ValidateUserFieldsFn(t *testing.T) {
+    tests := []struct {
+        name                string
+        input               []string
+        expectedErr         string
+    }{
+        {
+            name:               "DefaultFields",
+            input:              DefaultUserFields,
+            expectedErr:        "",
+        }, {
+            name:               "Empty",
+            input:              []string{},
+            expectedErr:        "fields: must contain at least 1 field",
+        }, {
+            name:               "UnknownField",
+            input:              []string{"username", "password"},
+            expectedErr:        "fields: unknown field \"password\"",
+        }, {
+            name:               "SecretHash",
+            input:              []string{"username", "hash"},
+            expectedErr:        "fields: field \"hash\" can't be read",
+        }, {
+            name:               "SecretEncSymkey",
+            input:              []string{"enc_symkey"},
+            expectedErr:        "fields: field \"enc_symkey\" can't be read",
+        }, {
+            name:               "DuplicateField",
+            input:              []string{"salt", "salt"},
+            expectedErr:        "fields: duplicate field \"salt\"",
+        },
+    }
+    // Iterate
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            err := ValidateUserFieldsFn(tc.input)
+            if (err == nil && tc.expectedErr != "") || (err != nil && err.Error() != tc.expectedErr) {
+                t.Errorf("\nExpected:\t%q\nGot:\t\t%v", tc.expectedErr, err)
+            }
+        })
+    }
+}
+//}}} Test ValidateUserFieldsFn
+
+
+//{{{ Test Project
+func Test_UserModel_Project(t *testing.T) {
+    user := User{Username: "test_user", Salt: "aa", Hash: "bb", EncSymkey: "cc"}
+    expected := map[string]string{"username": "test_user", "salt": "aa"}
+    // Secret fields are never projected
+    got := user.Project([]string{"username", "salt", "hash", "enc_symkey"})
+    if !reflect.DeepEqual(got, expected) {
+        t.Errorf("\nExpected:\t%v\nGot:\t\t%v", expected, got)
+    }
+}
+//}}} Test Project